@@ -0,0 +1,263 @@
+/**********************************************************************
+ *
+ * file name:    archive.go
+ * description:  frames stored in a single archive file
+ *
+ * ArchiveStorage keeps a whole knowledge base in one file. The file
+ * starts with a header line naming the format and its version, followed
+ * by one line per element of every frame, holding the frame name, the
+ * element name and its values as Go quoted strings:
+ *
+ *	framesets2 archive 2
+ *	"car" "car,slots" "color"
+ *	"car" "color,facets" "value"
+ *	"car" "color,value" "red"
+ *
+ * The archive is read when first used and kept in memory. Every change
+ * rewrites the file the same way Fstoref writes a frame file, through
+ * a synced temporary file renamed over the archive, so a change is
+ * either all on disk or not at all.
+ *
+ *							Functions
+ *
+ * NewArchiveStorage		create a storage in an archive file
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// aheader - start of the header line of an archive
+const aheader = "framesets2 archive "
+
+// ArchiveStorage - frames stored in a single file
+type ArchiveStorage struct {
+	mu     sync.Mutex
+	path   string
+	frames map[string]Frame
+}
+
+// NewArchiveStorage - create a storage in an archive file
+// the file is created when the first frame is stored
+func NewArchiveStorage(path string) *ArchiveStorage {
+	return &ArchiveStorage{path: path}
+}
+
+// Path - the archive file
+func (a *ArchiveStorage) Path() string {
+	return a.path
+}
+
+// fencodea - write the frames of an archive
+func fencodea(w io.Writer, frames map[string]Frame) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString(aheader + strconv.Itoa(FormatVersion) + "\n")
+	fnames := make([]string, 0, len(frames))
+	for k := range frames {
+		fnames = append(fnames, k)
+	}
+	sort.Strings(fnames)
+	for _, fname := range fnames {
+		x := frames[fname]
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writer.WriteString(fencodel(fname, append([]string{k}, x[k]...)))
+		}
+	}
+	return writer.Flush()
+}
+
+// fdecodea - read the frames of an archive
+func fdecodea(r io.Reader) (map[string]Frame, error) {
+	frames := make(map[string]Frame)
+	reader := bufio.NewReader(r)
+	header := true
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, ioerror(err)
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if header {
+			if line == "" && err == io.EOF {
+				break
+			}
+			v, ok := strings.CutPrefix(line, aheader)
+			if n, verr := strconv.Atoi(v); !ok || verr != nil || n < 2 || n > FormatVersion {
+				return nil, fmt.Errorf("%w: not an archive of a known version", ErrFormat)
+			}
+			header = false
+		} else if line != "" {
+			fname, items, derr := fdecodel(line)
+			if derr != nil {
+				return nil, derr
+			}
+			if len(items) == 0 {
+				return nil, fmt.Errorf("%w: line without an element name", ErrFormat)
+			}
+			if frames[fname] == nil {
+				frames[fname] = Frame{fname + ",slots": {}}
+			}
+			frames[fname][items[0]] = items[1:]
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return frames, nil
+}
+
+// load - read the archive, once
+// requires that a.mu is held
+func (a *ArchiveStorage) load() error {
+	if a.frames != nil {
+		return nil
+	}
+	fh, err := os.Open(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		a.frames = make(map[string]Frame)
+		return nil
+	}
+	if err != nil {
+		return ioerror(err)
+	}
+	defer fh.Close()
+	frames, err := fdecodea(fh)
+	if err != nil {
+		return err
+	}
+	a.frames = frames
+	return nil
+}
+
+// change - apply fn to a copy of the frames and write them out
+// the frames in memory are only replaced once the file is written
+func (a *ArchiveStorage) change(fn func(frames map[string]Frame) error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.load(); err != nil {
+		return err
+	}
+	frames := make(map[string]Frame, len(a.frames))
+	for k, v := range a.frames {
+		frames[k] = v
+	}
+	if err := fn(frames); err != nil {
+		return err
+	}
+	tmp, err := ftemp(a.path, func(w io.Writer) error {
+		return fencodea(w, frames)
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		os.Remove(tmp)
+		return ioerror(err)
+	}
+	fsyncd(a.path)
+	a.frames = frames
+	return nil
+}
+
+// Load - read a frame
+func (a *ArchiveStorage) Load(fname string) (Frame, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	if x, ok := a.frames[fname]; ok {
+		return clonef(x), nil
+	}
+	return nil, ErrFrameNotFound
+}
+
+// LoadAll - read a list of frames, failing if any of them fails
+func (a *ArchiveStorage) LoadAll(fnames []string) ([]Frame, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	frames := []Frame{}
+	for _, i := range fnames {
+		x, ok := a.frames[i]
+		if !ok {
+			return nil, ferror("load", i, "", ErrFrameNotFound)
+		}
+		frames = append(frames, clonef(x))
+	}
+	return frames, nil
+}
+
+// Store - write a frame
+func (a *ArchiveStorage) Store(fname string, x Frame) error {
+	return a.StoreAll([]string{fname}, []Frame{x})
+}
+
+// StoreAll - write a list of frames, either all of them or none
+func (a *ArchiveStorage) StoreAll(fnames []string, frames []Frame) error {
+	return a.change(func(archive map[string]Frame) error {
+		for i, fname := range fnames {
+			archive[fname] = clonef(frames[i])
+		}
+		return nil
+	})
+}
+
+// Delete - remove a stored frame
+func (a *ArchiveStorage) Delete(fname string) error {
+	return a.change(func(archive map[string]Frame) error {
+		if _, ok := archive[fname]; !ok {
+			return ErrFrameNotFound
+		}
+		delete(archive, fname)
+		return nil
+	})
+}
+
+// DeleteAll - remove a list of stored frames, as many as can be
+func (a *ArchiveStorage) DeleteAll(fnames []string) error {
+	errs := []error{}
+	err := a.change(func(archive map[string]Frame) error {
+		for _, i := range fnames {
+			if _, ok := archive[i]; !ok {
+				errs = append(errs, ferror("delete", i, "", ErrFrameNotFound))
+			}
+			delete(archive, i)
+		}
+		return nil
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+// List - names of the stored frames, in sorted order
+func (a *ArchiveStorage) List() ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	frames := []string{}
+	for k := range a.frames {
+		frames = append(frames, k)
+	}
+	sort.Strings(frames)
+	return frames, nil
+}
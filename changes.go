@@ -0,0 +1,162 @@
+/**********************************************************************
+ *
+ * file name:    changes.go
+ * description:  changes to the elements of frames
+ *
+ * A frame is a map of elements, fframes[<fname>][<ename>]. When some
+ * part of the knowledge base wants to follow every change made to the
+ * frames in memory, each command which changes a frame in place is
+ * compared with the frame before it, and the elements it added, changed
+ * or removed are handed on as a list of Change values. This is done
+ * while the frame is still locked, so changes to a frame are always
+ * handed on in the order in which they were made.
+ *
+ * Creating, replacing or removing a whole frame, as Fcreatef, Fcopyf,
+ * Fimportf or Fremovef do, is handed on as a change with no element,
+ * which removes every element of the frame, followed by the elements of
+ * the new frame, if any, and the removal of those it no longer has. Each
+ * of them carries the old values, so the changes can be undone as any
+ * others. Loading a frame changes nothing, since the frame comes from
+ * where it is kept.
+ *
+ *							Functions
+ *
+ * fchanged					hand the changes made by a command on
+ * fdiff					compare a frame before and after a command
+ * finverse					get the changes undoing a list of changes
+ * fput						apply a list of changes to a frame
+ * fwhole					get the changes replacing or removing a whole frame
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"slices"
+	"sort"
+)
+
+// Change - a change to one element of a frame
+// Old is nil if the element was added, New is nil if it was removed
+// A change with an empty Key removes every element of the frame
+type Change struct {
+	Frame string
+	Key   string
+	Old   []string
+	New   []string
+}
+
+// Changer - a Storage which can keep its stored frames in step with
+// the frames in memory
+// Apply is called with the changes made by every command, while the
+// frames they change are locked, and must ignore changes to frames it
+// does not hold. A frame it holds stays held while it is replaced, but
+// not once it is removed. If it fails, the command is undone and fails
+// as well.
+type Changer interface {
+	Apply(changes []Change) error
+}
+
+// fdiff - compare a frame before and after a command
+// the changes are ordered by element name
+func fdiff(fname string, before, after Frame) []Change {
+	changes := []Change{}
+	for k, v := range after {
+		if old, ok := before[k]; !ok {
+			changes = append(changes, Change{Frame: fname, Key: k, New: append([]string{}, v...)})
+		} else if !slices.Equal(old, v) {
+			changes = append(changes, Change{Frame: fname, Key: k, Old: append([]string{}, old...), New: append([]string{}, v...)})
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, Change{Frame: fname, Key: k, Old: append([]string{}, v...)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// fput - apply a list of changes to a frame
+func fput(f Frame, changes []Change) {
+	for _, c := range changes {
+		if c.New == nil {
+			delete(f, c.Key)
+		} else {
+			f[c.Key] = append([]string{}, c.New...)
+		}
+	}
+}
+
+// fwhole - the changes replacing a whole frame old, which is nil if there
+// is none, by x, or removing it if x is nil
+func fwhole(fname string, old, x Frame) []Change {
+	changes := []Change{{Frame: fname}}
+	for _, c := range fdiff(fname, Frame{}, x) {
+		if v, ok := old[c.Key]; ok {
+			c.Old = append([]string{}, v...)
+		}
+		changes = append(changes, c)
+	}
+	for _, c := range fdiff(fname, old, Frame{}) {
+		if _, ok := x[c.Key]; !ok {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// sinks - the Changer and the Journal following the frames of a
+// knowledge base, if any
+func (kb *KnowledgeBase) sinks() (Changer, *Journal) {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	changer, _ := kb.storage.(Changer)
+	return changer, kb.journal
+}
+
+// fchanged - hand the changes made by a command to a Changer and a Journal
+// the journal is written first; if the Changer then fails, the journal
+// is told the changes were undone
+func fchanged(changer Changer, journal *Journal, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if journal != nil {
+		if err := journal.changes(changes); err != nil {
+			return err
+		}
+	}
+	if changer != nil {
+		if err := changer.Apply(changes); err != nil {
+			if journal != nil {
+				journal.changes(finverse(changes))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// finverse - the changes undoing a list of changes
+// a whole frame replaced or removed is cleared and put back as it was
+func finverse(changes []Change) []Change {
+	undo := []Change{}
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		if c.Key != "" {
+			undo = append(undo, Change{Frame: c.Frame, Key: c.Key, Old: c.New, New: c.Old})
+			continue
+		}
+		// the elements of the frame follow the change clearing it, so
+		// their inverses were the last added, and are made to follow it
+		n := 0
+		for j := i + 1; j < len(changes) && changes[j].Frame == c.Frame && changes[j].Key != ""; j++ {
+			n++
+		}
+		undo = slices.Insert(undo, len(undo)-n, Change{Frame: c.Frame})
+	}
+	return undo
+}
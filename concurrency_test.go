@@ -0,0 +1,73 @@
+package framesets2
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentCommands(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatex("d")
+	kb.Fputx("d", func(f string) { kb.Fgetv(f, "s"); kb.Flistf() })
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				f := fmt.Sprint("f", i%10)
+				kb.Fcreatef(f)
+				kb.Fcreates(f, "s")
+				kb.Fcreatev(f, "s")
+				kb.Fcreated(f, "s", "ifputv")
+				kb.Fputd(f, "s", "ifputv", "d")
+				kb.Fputv(f, "s", fmt.Sprint(i))
+				kb.Fgetv(f, "s")
+				kb.Ffindeq("s", "3")
+				if i%17 == 0 {
+					kb.Fremovef(f)
+				}
+				kb.Fcopyf(f, "copy")
+				kb.Fcreatefs("set")
+				kb.Fsincludef("set", f)
+				kb.Fsmemberf(f)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, f := range kb.Fslistf("set") {
+		if !kb.Fexistf(f) {
+			t.Errorf("member %q of set does not exist", f)
+		}
+	}
+}
+
+func TestReferencedSlots(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("a")
+	kb.Fcreatef("b")
+	kb.Fcreates("a", "s")
+	kb.Fcreates("b", "s")
+	kb.Fcreater("a", "s")
+	kb.Fputr("a", "s", "b")
+	kb.Fcreatev("a", "s")
+	kb.Fputv("a", "s", "v")
+	n := 0
+	kb.Fcreatex("m")
+	kb.Fputx("m", func(string) { n++ })
+	kb.Fcreated("b", "s", "ifgetv")
+	kb.Fputd("b", "s", "ifgetv", "m")
+	if !kb.Fexistv("b", "s") {
+		t.Error("value facet not created in the referenced frame")
+	}
+	if got := kb.Fgetv("b", "s"); got != "v" {
+		t.Errorf("value in the referenced frame: got %q, want v", got)
+	}
+	if got := kb.Fgetv("a", "s"); got != "v" {
+		t.Errorf("value through the reference: got %q, want v", got)
+	}
+	if n != 2 {
+		t.Errorf("ifgetv demon of the referenced frame called %d times, want 2", n)
+	}
+}
@@ -0,0 +1,351 @@
+/**********************************************************************
+ *
+ * file name:    constraints.go
+ * description:  constraints on the values of slots
+ *
+ * Besides its type, a slot may constrain its values with constraint
+ * facets, fframes[<fname>][<sname>,<ctype>]. Every put into the value
+ * facet of the slot, by Fputv or any other command putting values, is
+ * checked against them, and a put breaking one fails with an error
+ * wrapping ErrConstraint which says what is wrong, leaving the values
+ * as they were. Fputr checks the frame it puts against the enum and
+ * pattern constraints of the slot.
+ *
+ * A slot gets each constraint it does not have itself from the nearest
+ * of its ancestors which has it. The constraints of a slot holding a
+ * reference apply to the reference, and values put through it are
+ * checked against the constraints of the referenced slot. Fsputconstraint
+ * puts a constraint into a frameset and each of its members, returning
+ * the errors of the members it could not be put into, and
+ * Fsincludef refuses a frame breaking the constraints of the frameset.
+ * Values already in a slot must satisfy a constraint put into it,
+ * except required and mincard, so a slot can be required before it is
+ * filled in; Fcheckf checks a whole frame against every constraint.
+ *
+ *							Constraints
+ *
+ * min <number>				no value less than number
+ * max <number>				no value greater than number
+ * enum <value>...			every value is one of the values
+ * pattern <regexp>			every value matches the whole regexp
+ * mincard <n>				at least n values
+ * maxcard <n>				at most n values
+ * required					at least one value
+ *
+ *							Functions
+ *
+ * Fcheckf					check the constraints of a frame
+ * Fgetconstraint			get a constraint of a slot
+ * Fputconstraint			put a constraint into a slot
+ * Fremoveconstraint		remove a constraint from a slot
+ * Fsputconstraint			put a constraint into a frameset
+ * Fsremoveconstraint		remove a constraint from a frameset
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+const (
+	ConstraintMin      = "min"
+	ConstraintMax      = "max"
+	ConstraintEnum     = "enum"
+	ConstraintPattern  = "pattern"
+	ConstraintMinCard  = "mincard"
+	ConstraintMaxCard  = "maxcard"
+	ConstraintRequired = "required"
+)
+
+// fconstraints - list of known constraints, in the order they are checked
+var fconstraints = []string{ConstraintRequired, ConstraintMinCard, ConstraintMaxCard,
+	ConstraintEnum, ConstraintPattern, ConstraintMin, ConstraintMax}
+
+// fconsargs - check the arguments of a constraint and return its facet
+func fconsargs(ctype string, args []string) ([]string, error) {
+	switch ctype {
+	case ConstraintMin, ConstraintMax:
+		if len(args) == 1 {
+			if _, err := strconv.ParseFloat(args[0], 64); err == nil {
+				return args, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s takes a number", ErrConstraint, ctype)
+	case ConstraintMinCard, ConstraintMaxCard:
+		if len(args) == 1 {
+			if n, err := strconv.Atoi(args[0]); err == nil && n >= 0 {
+				return args, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s takes a count", ErrConstraint, ctype)
+	case ConstraintPattern:
+		if len(args) == 1 {
+			if _, err := regexp.Compile(args[0]); err == nil {
+				return args, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s takes a regular expression", ErrConstraint, ctype)
+	case ConstraintEnum:
+		return append([]string{}, args...), nil
+	case ConstraintRequired:
+		if len(args) == 0 {
+			return []string{"true"}, nil
+		}
+		return nil, fmt.Errorf("%w: %s takes no arguments", ErrConstraint, ctype)
+	}
+	return nil, fmt.Errorf("%w: unknown constraint %q", ErrConstraint, ctype)
+}
+
+// fcheckc - check a list of values against one constraint
+func fcheckc(ctype string, args []string, vtype string, value []string) error {
+	switch ctype {
+	case ConstraintRequired:
+		if len(value) == 0 {
+			return fmt.Errorf("%w: value is required", ErrConstraint)
+		}
+	case ConstraintMinCard, ConstraintMaxCard:
+		n, _ := strconv.Atoi(Getval(args))
+		if ctype == ConstraintMinCard && len(value) < n {
+			return fmt.Errorf("%w: %d values, at least %d required", ErrConstraint, len(value), n)
+		}
+		if ctype == ConstraintMaxCard && len(value) > n {
+			return fmt.Errorf("%w: %d values, at most %d allowed", ErrConstraint, len(value), n)
+		}
+	case ConstraintEnum:
+		for _, v := range value {
+			if !slices.ContainsFunc(args, func(i string) bool { return fequalt(vtype, i, v) }) {
+				return fmt.Errorf("%w: %q is not one of %q", ErrConstraint, v, args)
+			}
+		}
+	case ConstraintPattern:
+		re, err := regexp.Compile("^(?:" + Getval(args) + ")$")
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrConstraint, err)
+		}
+		for _, v := range value {
+			if !re.MatchString(v) {
+				return fmt.Errorf("%w: %q does not match %q", ErrConstraint, v, Getval(args))
+			}
+		}
+	case ConstraintMin, ConstraintMax:
+		limit, _ := strconv.ParseFloat(Getval(args), 64)
+		for _, v := range value {
+			x, err := strconv.ParseFloat(v, 64)
+			switch {
+			case err != nil:
+				return fmt.Errorf("%w: %q is not a number", ErrConstraint, v)
+			case ctype == ConstraintMin && x < limit:
+				return fmt.Errorf("%w: %q is less than the minimum %s", ErrConstraint, v, Getval(args))
+			case ctype == ConstraintMax && x > limit:
+				return fmt.Errorf("%w: %q is greater than the maximum %s", ErrConstraint, v, Getval(args))
+			}
+		}
+	}
+	return nil
+}
+
+// fcons - the constraints of a slot, each from the nearest frame having it
+type fcons map[string][]string
+
+// check - check a list of values against the constraints
+// only the constraints named in ctypes are checked, if any are
+func (c fcons) check(vtype string, value []string, ctypes ...string) error {
+	for _, ctype := range fconstraints {
+		args, ok := c[ctype]
+		if !ok || (len(ctypes) > 0 && !Fmember(ctypes, ctype)) {
+			continue
+		}
+		if err := fcheckc(ctype, args, vtype, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// constraintp - the constraints of a slot, from the frame and its
+// ancestors
+func (kb *KnowledgeBase) constraintp(fname, sname string) fcons {
+	c := fcons{}
+	plist, _ := kb.pathp(fname)
+	for _, i := range plist {
+		kb.readf(i, func(f Frame) {
+			if !Fmember(f[i+",slots"], sname) {
+				return
+			}
+			for _, ctype := range f[sname+",facets"] {
+				if _, ok := c[ctype]; !ok && Fmember(fconstraints, ctype) {
+					c[ctype] = append([]string{}, f[sname+","+ctype]...)
+				}
+			}
+		})
+	}
+	return c
+}
+
+// fputconstraint - put a constraint into a slot, replacing any of the
+// same kind
+// values already in the slot must satisfy the constraint, unless it
+// asks for more values, so a slot can be required before it is filled
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,ctype]
+func (kb *KnowledgeBase) Fputconstraint(fname, sname, ctype string, args ...string) bool {
+	return kb.FputconstraintE(fname, sname, ctype, args...) == nil
+}
+
+// fputconstrainte - put a constraint into a slot, returning an error
+func (kb *KnowledgeBase) FputconstraintE(fname, sname, ctype string, args ...string) error {
+	args, err := fconsargs(ctype, args)
+	if err != nil {
+		return ferror("fputconstraint", fname, sname, err)
+	}
+	s, err := kb.slote(fname, sname)
+	if err == nil {
+		err = kb.writef(fname, func(f Frame) error {
+			fown(f, fname, sname, s)
+			var err error
+			switch {
+			case ctype == ConstraintRequired || ctype == ConstraintMinCard:
+			case Fmember(f[sname+",facets"], "value"):
+				err = fcheckc(ctype, args, Getval(f[sname+",type"]), f[sname+",value"])
+			case Fmember(f[sname+",facets"], "ref") && (ctype == ConstraintEnum || ctype == ConstraintPattern):
+				err = fcheckc(ctype, args, TypeRef, f[sname+",ref"])
+			}
+			if err != nil {
+				return err
+			}
+			if !Fmember(f[sname+",facets"], ctype) {
+				if err := fcreatet(f, fname, sname, ctype, "method"); err != nil {
+					return err
+				}
+			}
+			f[sname+","+ctype] = args
+			return nil
+		})
+	}
+	return ferror("fputconstraint", fname, sname, err)
+}
+
+// fgetconstraint - get a constraint of a slot, nil if it has none
+// requires that fframes[fname][sname,facets] exists
+func (kb *KnowledgeBase) Fgetconstraint(fname, sname, ctype string) []string {
+	args, _ := kb.FgetconstraintE(fname, sname, ctype)
+	return args
+}
+
+// fgetconstrainte - get a constraint of a slot, returning an error
+func (kb *KnowledgeBase) FgetconstraintE(fname, sname, ctype string) ([]string, error) {
+	_, err := kb.slote(fname, sname)
+	if err == nil {
+		if args, ok := kb.constraintp(fname, sname)[ctype]; ok {
+			return args, nil
+		}
+		err = ErrFacetNotFound
+	}
+	return nil, ferror("fgetconstraint", fname, sname, err)
+}
+
+// fremoveconstraint - remove a constraint from a slot
+// requires that fframes[fname][sname,ctype] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,ctype]
+func (kb *KnowledgeBase) Fremoveconstraint(fname, sname, ctype string) bool {
+	return kb.FremoveconstraintE(fname, sname, ctype) == nil
+}
+
+// fremoveconstrainte - remove a constraint from a slot, returning an error
+func (kb *KnowledgeBase) FremoveconstraintE(fname, sname, ctype string) error {
+	if !Fmember(fconstraints, ctype) {
+		return ferror("fremoveconstraint", fname, sname, fmt.Errorf("%w: unknown constraint %q", ErrConstraint, ctype))
+	}
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[fname+",slots"], sname) {
+			return ErrSlotNotFound
+		}
+		return fremovet(f, sname, ctype)
+	})
+	return ferror("fremoveconstraint", fname, sname, err)
+}
+
+// fcheckf - check every slot of a frame against its constraints
+// requires that fframes[fname] exists
+func (kb *KnowledgeBase) Fcheckf(fname string) bool {
+	return kb.FcheckfE(fname) == nil
+}
+
+// fcheckfe - check every slot of a frame against its constraints,
+// returning every constraint broken
+func (kb *KnowledgeBase) FcheckfE(fname string) error {
+	slots, err := kb.FlistsE(fname)
+	if err != nil {
+		return ferror("fcheckf", fname, "", err)
+	}
+	return kb.checkf("fcheckf", fname, fname, slots)
+}
+
+// checkf - check the slots of a frame against the constraints of the
+// same slots in another frame, or in the frame itself
+func (kb *KnowledgeBase) checkf(op, fname, cname string, slots []string) error {
+	errs := []error{}
+	for _, sname := range slots {
+		c := kb.constraintp(cname, sname)
+		if len(c) == 0 {
+			continue
+		}
+		s := kb.slot(fname, sname)
+		var err error
+		switch {
+		case s.has("ref"):
+			err = c.check(TypeRef, s["ref"], ConstraintEnum, ConstraintPattern)
+		case s.has("method"):
+		default:
+			err = c.check(Getval(s["type"]), s["value"])
+		}
+		if err != nil {
+			errs = append(errs, ferror(op, fname, sname, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fsputconstraint - put a constraint into a frameset and its members
+// requires that fframes[name][sname,facets] exists
+// modifies fframes[name][sname,facets], fframes[name][sname,ctype],
+// associated frames
+func (kb *KnowledgeBase) Fsputconstraint(name, sname, ctype string, args ...string) bool {
+	return kb.FsputconstraintE(name, sname, ctype, args...) == nil
+}
+
+// fsputconstrainte - put a constraint into a frameset, returning an error
+// the errors of the members it could not be put into are joined
+func (kb *KnowledgeBase) FsputconstraintE(name, sname, ctype string, args ...string) error {
+	defer kb.group()()
+	if err := kb.FputconstraintE(name, sname, ctype, args...); err != nil {
+		return err
+	}
+	var errs []error
+	for _, i := range kb.Fslistf(name) {
+		errs = append(errs, kb.FputconstraintE(i, sname, ctype, args...))
+	}
+	return errors.Join(errs...)
+}
+
+// fsremoveconstraint - remove a constraint from a frameset and its members
+// requires that fframes[name][sname,ctype] exists
+// modifies fframes[name][sname,facets], fframes[name][sname,ctype],
+// associated frames
+func (kb *KnowledgeBase) Fsremoveconstraint(name, sname, ctype string) bool {
+	return kb.FsremoveconstraintE(name, sname, ctype) == nil
+}
+
+// fsremoveconstrainte - remove a constraint from a frameset, returning
+// an error
+func (kb *KnowledgeBase) FsremoveconstraintE(name, sname, ctype string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FremoveconstraintE(fname, sname, ctype)
+	})
+}
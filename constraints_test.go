@@ -0,0 +1,168 @@
+package framesets2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// ckb - a knowledge base with a frame p holding value slots age, color
+// and code, and a reference slot owner, and a frame c which is a p
+func ckb() *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("p")
+	for _, sname := range []string{"age", "color", "code"} {
+		kb.Fcreates("p", sname)
+		kb.Fcreatev("p", sname)
+	}
+	kb.Fcreates("p", "owner")
+	kb.Fcreater("p", "owner")
+	kb.Fcreatef("c")
+	kb.Fputp("c", "p")
+	return kb
+}
+
+func TestConstraints(t *testing.T) {
+	tests := []struct {
+		name  string
+		sname string
+		kind  string
+		args  []string
+		put   func(kb *KnowledgeBase) error
+		err   error
+	}{
+		{"min", "age", "min", []string{"0"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "age", "-1") }, ErrConstraint},
+		{"max", "age", "max", []string{"150"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "age", "200") }, ErrConstraint},
+		{"not a number", "age", "max", []string{"150"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "age", "banana") }, ErrConstraint},
+		{"in range", "age", "max", []string{"150"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "age", "42") }, nil},
+		{"enum", "color", "enum", []string{"red", "blue"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "color", "green") }, ErrConstraint},
+		{"in enum", "color", "enum", []string{"red", "blue"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "color", "red") }, nil},
+		{"maxcard", "color", "maxcard", []string{"1"},
+			func(kb *KnowledgeBase) error { return kb.FputvlE("p", "color", []string{"red", "blue"}) }, ErrConstraint},
+		{"pattern", "code", "pattern", []string{"[A-Z]{3}"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "code", "ABCD") }, ErrConstraint},
+		{"matching pattern", "code", "pattern", []string{"[A-Z]{3}"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("p", "code", "ABC") }, nil},
+		{"required empty", "code", "required", nil,
+			func(kb *KnowledgeBase) error { return kb.FputvlE("p", "code", []string{}) }, ErrConstraint},
+		{"reference", "owner", "enum", []string{"p"},
+			func(kb *KnowledgeBase) error { return kb.FputrE("p", "owner", "x") }, ErrConstraint},
+		{"allowed reference", "owner", "enum", []string{"p"},
+			func(kb *KnowledgeBase) error { return kb.FputrE("p", "owner", "p") }, nil},
+		{"inherited", "age", "min", []string{"0"},
+			func(kb *KnowledgeBase) error { return kb.FputvE("c", "age", "-1") }, ErrConstraint},
+		{"inherited with own", "age", "max", []string{"150"}, func(kb *KnowledgeBase) error {
+			kb.Fputv("c", "age", "5")
+			kb.Fputconstraint("c", "age", "min", "3")
+			return kb.FputvE("c", "age", "200")
+		}, ErrConstraint},
+	}
+	for _, tt := range tests {
+		kb := ckb()
+		if err := kb.FputconstraintE("p", tt.sname, tt.kind, tt.args...); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := tt.put(kb); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestConstraintErrors(t *testing.T) {
+	kb := ckb()
+	kb.Fputv("p", "age", "42")
+	kb.Fputv("p", "code", "ABC")
+	kb.Fputconstraint("p", "code", "required")
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"broken by existing value", func() error { return kb.FputconstraintE("p", "age", "max", "10") }},
+		{"unknown kind", func() error { return kb.FputconstraintE("p", "age", "bogus") }},
+		{"removing a required value", func() error { return kb.FremovevE("p", "code") }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, ErrConstraint)
+		}
+	}
+	if got := kb.Fgetconstraint("p", "age", "max"); got != nil {
+		t.Errorf("constraint put despite an error: %q", got)
+	}
+	if got := kb.Fgetv("p", "code"); got != "ABC" {
+		t.Errorf("code: got %s, want ABC", got)
+	}
+	if got := kb.Fgetv("p", "age"); got != "42" {
+		t.Errorf("age: got %s, want 42", got)
+	}
+	kb.Fputconstraint("p", "age", "max", "150")
+	if got := kb.Fgetconstraint("c", "age", "max"); !reflect.DeepEqual(got, []string{"150"}) {
+		t.Errorf("fgetconstraint inherited: got %v", got)
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	kb := ckb()
+	kb.Fputv("p", "code", "ABC")
+	kb.Fputconstraint("p", "code", "required")
+	if err := kb.FcheckfE("c"); err != nil {
+		t.Errorf("fcheckf: %v", err)
+	}
+	kb.Fremoveconstraint("p", "code", "required")
+	kb.Fremovev("p", "code")
+	kb.Fputconstraint("p", "code", "required")
+	if err := kb.FcheckfE("p"); !errors.Is(err, ErrConstraint) {
+		t.Errorf("fcheckf: got %v, want %v", err, ErrConstraint)
+	}
+}
+
+func TestConstraintFrameset(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatefs("people")
+	kb.Fscreates("people", "name")
+	kb.Fscreatev("people", "name")
+	if err := kb.FsputconstraintE("people", "name", "required"); err != nil {
+		t.Fatal(err)
+	}
+	kb.Fcreatef("q")
+	if err := kb.FsincludefE("people", "q"); !errors.Is(err, ErrConstraint) {
+		t.Errorf("without name: got %v, want %v", err, ErrConstraint)
+	}
+	kb.Fcreates("q", "name")
+	kb.Fcreatev("q", "name")
+	kb.Fputv("q", "name", "Q")
+	if err := kb.FsincludefE("people", "q"); err != nil {
+		t.Errorf("with name: %v", err)
+	}
+}
+
+func TestConstraintFramesetMembers(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatefs("s")
+	kb.Fscreates("s", "n")
+	kb.Fscreatev("s", "n")
+	for _, i := range []string{"a", "b"} {
+		kb.Fcreatef(i)
+		kb.Fcreates(i, "n")
+		kb.Fcreatev(i, "n")
+		kb.Fsincludef("s", i)
+	}
+	kb.Fputv("b", "n", "20")
+	err := kb.FsputconstraintE("s", "n", "max", "10")
+	var fe *FrameError
+	if !errors.Is(err, ErrConstraint) || !errors.As(err, &fe) || fe.Frame != "b" {
+		t.Fatalf("fsputconstraint: got %v, want %v for b", err, ErrConstraint)
+	}
+	if kb.Fgetconstraint("a", "n", "max") == nil {
+		t.Error("no constraint in a")
+	}
+	if kb.Fgetconstraint("b", "n", "max") != nil {
+		t.Error("constraint in b broken by its value")
+	}
+}
@@ -0,0 +1,1348 @@
+/**********************************************************************
+ *
+ * file name:    default.go
+ * description:  package level functions
+ *
+ * Every frame function is a method of KnowledgeBase. The functions in
+ * this file keep the original package level API working by delegating
+ * to a default knowledge base created when the package is loaded.
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"log"
+	"time"
+)
+
+var fdefault = NewKnowledgeBase()
+
+// Default - return the default knowledge base used by the package functions
+func Default() *KnowledgeBase {
+	return fdefault
+}
+
+// ffind - find all frames having a given value facet
+func Ffind(sname string) []string {
+	return fdefault.Ffind(sname)
+}
+
+// ffindeq - find all frames having a given value for a given value facet
+func Ffindeq(sname string, args string) []string {
+	return fdefault.Ffindeq(sname, args)
+}
+
+// ffindne - find all frames not having a given value for a given value facet
+func Ffindne(sname string, args string) []string {
+	return fdefault.Ffindne(sname, args)
+}
+
+// fexistf - determine if a frame exists
+func Fexistf(fname string) bool {
+	return fdefault.Fexistf(fname)
+}
+
+// fcreatef - create a frame
+func Fcreatef(fname string) bool {
+	return fdefault.Fcreatef(fname)
+}
+
+// fcreatefe - create a frame, returning an error
+func FcreatefE(fname string) error {
+	return fdefault.FcreatefE(fname)
+}
+
+// fremovef - remove a frame
+func Fremovef(fname string) bool {
+	return fdefault.Fremovef(fname)
+}
+
+// fremovefe - remove a frame, returning an error
+func FremovefE(fname string) error {
+	return fdefault.FremovefE(fname)
+}
+
+// flistf - return list of frames
+func Flistf() []string {
+	return fdefault.Flistf()
+}
+
+// fcopyf - create a new frame based on another frame
+func Fcopyf(fname1, fname2 string) bool {
+	return fdefault.Fcopyf(fname1, fname2)
+}
+
+// fcopyfe - create a new frame based on another frame, returning an error
+func FcopyfE(fname1, fname2 string) error {
+	return fdefault.FcopyfE(fname1, fname2)
+}
+
+// fcomparef - determine if two frames are equivalent
+func Fcomparef(fname1, fname2 string) bool {
+	return fdefault.Fcomparef(fname1, fname2)
+}
+
+// fmergef - merge slots of one frame into another frame
+func Fmergef(fname1, fname2 string) bool {
+	return fdefault.Fmergef(fname1, fname2)
+}
+
+// fmergefe - merge slots of one frame into another frame, returning an error
+func FmergefE(fname1, fname2 string) error {
+	return fdefault.FmergefE(fname1, fname2)
+}
+
+// floadf - load a frame into memory
+func Floadf(fname string) bool {
+	return fdefault.Floadf(fname)
+}
+
+// floadfe - load a frame into memory, returning an error
+func FloadfE(fname string) error {
+	return fdefault.FloadfE(fname)
+}
+
+// fstoref - store a frame on disk
+func Fstoref(fname string) bool {
+	return fdefault.Fstoref(fname)
+}
+
+// fstorefe - store a frame on disk, returning an error
+func FstorefE(fname string) error {
+	return fdefault.FstorefE(fname)
+}
+
+// fupdatef - update structure of a frame from another frame
+func Fupdatef(fname1, fname2 string) bool {
+	return fdefault.Fupdatef(fname1, fname2)
+}
+
+// fupdatefe - update structure of a frame from another frame, returning an error
+func FupdatefE(fname1, fname2 string) error {
+	return fdefault.FupdatefE(fname1, fname2)
+}
+
+// ffilterf - filter slots of a frame based on another frame
+func Ffilterf(fname1, fname2 string) bool {
+	return fdefault.Ffilterf(fname1, fname2)
+}
+
+// ffilterfe - filter slots of a frame based on another frame, returning an error
+func FfilterfE(fname1, fname2 string) error {
+	return fdefault.FfilterfE(fname1, fname2)
+}
+
+// fcreatex - create a method in fmethods
+func Fcreatex(mname string) bool {
+	return fdefault.Fcreatex(mname)
+}
+
+// fcreatexe - create a method in fmethods, returning an error
+func FcreatexE(mname string) error {
+	return fdefault.FcreatexE(mname)
+}
+
+// fremovex - remove a method from fmethods
+func Fremovex(mname string) bool {
+	return fdefault.Fremovex(mname)
+}
+
+// fremovexe - remove a method from fmethods, returning an error
+func FremovexE(mname string) error {
+	return fdefault.FremovexE(mname)
+}
+
+// fexistx - determine if a method exists in fmethods
+func Fexistx(mname string) bool {
+	return fdefault.Fexistx(mname)
+}
+
+// flistx - return list of methods in fmethods
+func Flistx() []string {
+	return fdefault.Flistx()
+}
+
+// fgetx - get a method from fmethods
+func Fgetx(mname string) (func(string), bool) {
+	return fdefault.Fgetx(mname)
+}
+
+// fputx - put a method in fmethods
+func Fputx(mname string, method func(string)) bool {
+	return fdefault.Fputx(mname, method)
+}
+
+// fputxe - put a method in fmethods, returning an error
+func FputxE(mname string, method func(string)) error {
+	return fdefault.FputxE(mname, method)
+}
+
+// fputxm - put a method taking a MethodContext in fmethods
+func Fputxm(mname string, method Method) bool {
+	return fdefault.Fputxm(mname, method)
+}
+
+// fputxme - put a method taking a MethodContext in fmethods, returning an error
+func FputxmE(mname string, method Method) error {
+	return fdefault.FputxmE(mname, method)
+}
+
+// fexists - determine if a slot exists
+func Fexists(fname, sname string) bool {
+	return fdefault.Fexists(fname, sname)
+}
+
+// fcreates - create a slot
+func Fcreates(fname, sname string) bool {
+	return fdefault.Fcreates(fname, sname)
+}
+
+// fcreatese - create a slot, returning an error
+func FcreatesE(fname, sname string) error {
+	return fdefault.FcreatesE(fname, sname)
+}
+
+// fremoves - remove a slot
+func Fremoves(fname, sname string) bool {
+	return fdefault.Fremoves(fname, sname)
+}
+
+// fremovese - remove a slot, returning an error
+func FremovesE(fname, sname string) error {
+	return fdefault.FremovesE(fname, sname)
+}
+
+// flists - list slots of a frame
+func Flists(fname string) []string {
+	return fdefault.Flists(fname)
+}
+
+// flistse - list slots of a frame, returning an error
+func FlistsE(fname string) ([]string, error) {
+	return fdefault.FlistsE(fname)
+}
+
+// fcopys - copy a slot into another frame
+func Fcopys(fname1, sname, fname2 string) bool {
+	return fdefault.Fcopys(fname1, sname, fname2)
+}
+
+// fcopyse - copy a slot into another frame, returning an error
+func FcopysE(fname1, sname, fname2 string) error {
+	return fdefault.FcopysE(fname1, sname, fname2)
+}
+
+// fcompares - compare a slot in two frames
+func Fcompares(fname1, sname, fname2 string) bool {
+	return fdefault.Fcompares(fname1, sname, fname2)
+}
+
+// flistt - list of facet types in a slot
+func Flistt(fname, sname string) []string {
+	return fdefault.Flistt(fname, sname)
+}
+
+// flistte - list of facet types in a slot, returning an error
+func FlisttE(fname, sname string) ([]string, error) {
+	return fdefault.FlisttE(fname, sname)
+}
+
+// fexistrx - determine if a reference facet exists (internal)
+func Fexistrx(fname, sname string) bool {
+	return fdefault.Fexistrx(fname, sname)
+}
+
+// fexistr - determine if a reference facet exists
+func Fexistr(fname, sname string) bool {
+	return fdefault.Fexistr(fname, sname)
+}
+
+// fcreater - create a reference facet
+func Fcreater(fname, sname string) bool {
+	return fdefault.Fcreater(fname, sname)
+}
+
+// fcreatere - create a reference facet, returning an error
+func FcreaterE(fname, sname string) error {
+	return fdefault.FcreaterE(fname, sname)
+}
+
+// fremover - remove a reference facet
+func Fremover(fname, sname string) bool {
+	return fdefault.Fremover(fname, sname)
+}
+
+// fremovere - remove a reference facet, returning an error
+func FremoverE(fname, sname string) error {
+	return fdefault.FremoverE(fname, sname)
+}
+
+// fgetr - get a value from a reference facet
+func Fgetr(fname, sname string) string {
+	return fdefault.Fgetr(fname, sname)
+}
+
+// fgetre - get a value from a reference facet, returning an error
+func FgetrE(fname, sname string) (string, error) {
+	return fdefault.FgetrE(fname, sname)
+}
+
+// fputr - put a value in a reference facet
+func Fputr(fname1, sname, fname2 string) bool {
+	return fdefault.Fputr(fname1, sname, fname2)
+}
+
+// fputre - put a value in a reference facet, returning an error
+func FputrE(fname1, sname, fname2 string) error {
+	return fdefault.FputrE(fname1, sname, fname2)
+}
+
+// flistr - list of references in a frame
+func Flistr(fname string) []string {
+	return fdefault.Flistr(fname)
+}
+
+// fpathr - return chain of references
+func Fpathr(fname, sname string) []string {
+	return fdefault.Fpathr(fname, sname)
+}
+
+// fpathre - return chain of references, returning an error
+func FpathrE(fname, sname string) ([]string, error) {
+	return fdefault.FpathrE(fname, sname)
+}
+
+// fexistm - determine if a method facet exists
+func Fexistm(fname, sname string) bool {
+	return fdefault.Fexistm(fname, sname)
+}
+
+// fcreatem - create a method facet
+func Fcreatem(fname, sname string) bool {
+	return fdefault.Fcreatem(fname, sname)
+}
+
+// fcreateme - create a method facet, returning an error
+func FcreatemE(fname, sname string) error {
+	return fdefault.FcreatemE(fname, sname)
+}
+
+// fremovem - remove a method facet
+func Fremovem(fname, sname string) bool {
+	return fdefault.Fremovem(fname, sname)
+}
+
+// fremoveme - remove a method facet, returning an error
+func FremovemE(fname, sname string) error {
+	return fdefault.FremovemE(fname, sname)
+}
+
+// fexecm - execute a method
+func Fexecm(fname, sname string) bool {
+	return fdefault.Fexecm(fname, sname)
+}
+
+// fexecme - execute a method, returning an error
+func FexecmE(fname, sname string) error {
+	return fdefault.FexecmE(fname, sname)
+}
+
+// fgetm - get a value from a method
+func Fgetm(fname string, sname string) string {
+	return fdefault.Fgetm(fname, sname)
+}
+
+// fgetme - get a value from a method, returning an error
+func FgetmE(fname string, sname string) (string, error) {
+	return fdefault.FgetmE(fname, sname)
+}
+
+// fputm - put a value in a method facet
+func Fputm(fname, sname, args string) bool {
+	return fdefault.Fputm(fname, sname, args)
+}
+
+// fputme - put a value in a method facet, returning an error
+func FputmE(fname, sname, args string) error {
+	return fdefault.FputmE(fname, sname, args)
+}
+
+// fexistv - determine if a value facet exists
+func Fexistv(fname, sname string) bool {
+	return fdefault.Fexistv(fname, sname)
+}
+
+// fcreatev - create a value facet
+func Fcreatev(fname, sname string) bool {
+	return fdefault.Fcreatev(fname, sname)
+}
+
+// fcreateve - create a value facet, returning an error
+func FcreatevE(fname, sname string) error {
+	return fdefault.FcreatevE(fname, sname)
+}
+
+// fremovev - remove a value facet
+func Fremovev(fname, sname string) bool {
+	return fdefault.Fremovev(fname, sname)
+}
+
+// fremoveve - remove a value facet, returning an error
+func FremovevE(fname, sname string) error {
+	return fdefault.FremovevE(fname, sname)
+}
+
+// fgetv - get a value from a value facet
+func Fgetv(fname string, sname string) string {
+	return fdefault.Fgetv(fname, sname)
+}
+
+// fgetve - get a value from a value facet, returning an error
+func FgetvE(fname string, sname string) (string, error) {
+	return fdefault.FgetvE(fname, sname)
+}
+
+// fputv - put a value in a value facet
+func Fputv(fname, sname, args string) bool {
+	return fdefault.Fputv(fname, sname, args)
+}
+
+// fputve - put a value in a value facet, returning an error
+func FputvE(fname, sname, args string) error {
+	return fdefault.FputvE(fname, sname, args)
+}
+
+// fexistd - determine if a demon facet exists
+func Fexistd(fname, sname, dname string) bool {
+	return fdefault.Fexistd(fname, sname, dname)
+}
+
+// fcreated - create a demon facet
+func Fcreated(fname, sname, dname string) bool {
+	return fdefault.Fcreated(fname, sname, dname)
+}
+
+// fcreatede - create a demon facet, returning an error
+func FcreatedE(fname, sname, dname string) error {
+	return fdefault.FcreatedE(fname, sname, dname)
+}
+
+// fremoved - remove a demon facet
+func Fremoved(fname, sname, dname string) bool {
+	return fdefault.Fremoved(fname, sname, dname)
+}
+
+// fremovede - remove a demon facet, returning an error
+func FremovedE(fname, sname, dname string) error {
+	return fdefault.FremovedE(fname, sname, dname)
+}
+
+// fgetd - get a value from a demon facet
+func Fgetd(fname, sname, dname string) string {
+	return fdefault.Fgetd(fname, sname, dname)
+}
+
+// fgetde - get a value from a demon facet, returning an error
+func FgetdE(fname, sname, dname string) (string, error) {
+	return fdefault.FgetdE(fname, sname, dname)
+}
+
+// fputd - put a value in a demon facet
+func Fputd(fname, sname, dname, args string) bool {
+	return fdefault.Fputd(fname, sname, dname, args)
+}
+
+// fputde - put a value in a demon facet, returning an error
+func FputdE(fname, sname, dname, args string) error {
+	return fdefault.FputdE(fname, sname, dname, args)
+}
+
+// fexecd - directly execute a demon
+func Fexecd(fname, sname, dname string) bool {
+	return fdefault.Fexecd(fname, sname, dname)
+}
+
+// fexecde - directly execute a demon, returning an error
+func FexecdE(fname, sname, dname string) error {
+	return fdefault.FexecdE(fname, sname, dname)
+}
+
+// fcreatefs - create a frameset
+func Fcreatefs(name string) bool {
+	return fdefault.Fcreatefs(name)
+}
+
+// fcreatefse - create a frameset, returning an error
+func FcreatefsE(name string) error {
+	return fdefault.FcreatefsE(name)
+}
+
+// fremovefs - remove a frameset
+func Fremovefs(name string) bool {
+	return fdefault.Fremovefs(name)
+}
+
+// fremovefse - remove a frameset, returning an error
+func FremovefsE(name string) error {
+	return fdefault.FremovefsE(name)
+}
+
+// fslistf - return a list of frames in a frameset
+func Fslistf(name string) []string {
+	return fdefault.Fslistf(name)
+}
+
+// fslistfe - return a list of frames in a frameset, returning an error
+func FslistfE(name string) ([]string, error) {
+	return fdefault.FslistfE(name)
+}
+
+// floadfs - load a frameset into memory
+func Floadfs(name string) bool {
+	return fdefault.Floadfs(name)
+}
+
+// floadfse - load a frameset into memory, returning an error
+func FloadfsE(name string) error {
+	return fdefault.FloadfsE(name)
+}
+
+// fstorefs - store a frameset on disk
+func Fstorefs(name string) bool {
+	return fdefault.Fstorefs(name)
+}
+
+// fstorefse - store a frameset on disk, returning an error
+func FstorefsE(name string) error {
+	return fdefault.FstorefsE(name)
+}
+
+// fsincludef - include a frame in a frameset
+func Fsincludef(name, fname string) bool {
+	return fdefault.Fsincludef(name, fname)
+}
+
+// fsincludefe - include a frame in a frameset, returning an error
+func FsincludefE(name, fname string) error {
+	return fdefault.FsincludefE(name, fname)
+}
+
+// fsexcludef - exclude a frame from a frameset
+func Fsexcludef(name, fname string) bool {
+	return fdefault.Fsexcludef(name, fname)
+}
+
+// fsexcludefe - exclude a frame from a frameset, returning an error
+func FsexcludefE(name, fname string) error {
+	return fdefault.FsexcludefE(name, fname)
+}
+
+// fscreates - create a slot in a frameset
+func Fscreates(name, sname string) bool {
+	return fdefault.Fscreates(name, sname)
+}
+
+// fscreatese - create a slot in a frameset, returning an error
+func FscreatesE(name, sname string) error {
+	return fdefault.FscreatesE(name, sname)
+}
+
+// fsremoves - remove a slot from a frameset
+func Fsremoves(name, sname string) bool {
+	return fdefault.Fsremoves(name, sname)
+}
+
+// fsremovese - remove a slot from a frameset, returning an error
+func FsremovesE(name, sname string) error {
+	return fdefault.FsremovesE(name, sname)
+}
+
+// fscreated - create a demon facet in a frameset
+func Fscreated(name, sname, dname string) bool {
+	return fdefault.Fscreated(name, sname, dname)
+}
+
+// fscreatede - create a demon facet in a frameset, returning an error
+func FscreatedE(name, sname, dname string) error {
+	return fdefault.FscreatedE(name, sname, dname)
+}
+
+// fsremoved - remove a demon facet from a frameset
+func Fsremoved(name, sname, dname string) bool {
+	return fdefault.Fsremoved(name, sname, dname)
+}
+
+// fsremovede - remove a demon facet from a frameset, returning an error
+func FsremovedE(name, sname, dname string) error {
+	return fdefault.FsremovedE(name, sname, dname)
+}
+
+// fscreatem - create a method facet in a frameset
+func Fscreatem(name, sname string) bool {
+	return fdefault.Fscreatem(name, sname)
+}
+
+// fscreateme - create a method facet in a frameset, returning an error
+func FscreatemE(name, sname string) error {
+	return fdefault.FscreatemE(name, sname)
+}
+
+// fsremovem - remove a method facet from a frameset
+func Fsremovem(name, sname string) bool {
+	return fdefault.Fsremovem(name, sname)
+}
+
+// fsremoveme - remove a method facet from a frameset, returning an error
+func FsremovemE(name, sname string) error {
+	return fdefault.FsremovemE(name, sname)
+}
+
+// fscreater - create a reference facet in a frameset
+func Fscreater(name, sname string) bool {
+	return fdefault.Fscreater(name, sname)
+}
+
+// fscreatere - create a reference facet in a frameset, returning an error
+func FscreaterE(name, sname string) error {
+	return fdefault.FscreaterE(name, sname)
+}
+
+// fsremover - remove a reference facet from a frameset
+func Fsremover(name, sname string) bool {
+	return fdefault.Fsremover(name, sname)
+}
+
+// fsremovere - remove a reference facet from a frameset, returning an error
+func FsremoverE(name, sname string) error {
+	return fdefault.FsremoverE(name, sname)
+}
+
+// fscreatev - create a value facet in a frameset
+func Fscreatev(name, sname string) bool {
+	return fdefault.Fscreatev(name, sname)
+}
+
+// fscreateve - create a value facet in a frameset, returning an error
+func FscreatevE(name, sname string) error {
+	return fdefault.FscreatevE(name, sname)
+}
+
+// fsremovev - remove a value facet from of a frameset
+func Fsremovev(name, sname string) bool {
+	return fdefault.Fsremovev(name, sname)
+}
+
+// fsremoveve - remove a value facet from a frameset, returning an error
+func FsremovevE(name, sname string) error {
+	return fdefault.FsremovevE(name, sname)
+}
+
+// fsputr - put a value in reference facet in a frameset
+func Fsputr(name, sname, fname string) bool {
+	return fdefault.Fsputr(name, sname, fname)
+}
+
+// fsputre - put a value in reference facet in a frameset, returning an error
+func FsputrE(name, sname, fname string) error {
+	return fdefault.FsputrE(name, sname, fname)
+}
+
+// fsgetr - get a value from a reference facet in a frameset
+func Fsgetr(name, sname string) string {
+	return fdefault.Fsgetr(name, sname)
+}
+
+// fsgetre - get a value from a reference facet in a frameset, returning an error
+func FsgetrE(name, sname string) (string, error) {
+	return fdefault.FsgetrE(name, sname)
+}
+
+// fsmemberf - get list of framesets in which a frame is a member
+func Fsmemberf(name string) []string {
+	return fdefault.Fsmemberf(name)
+}
+
+// fgetvl - get all values of a value facet
+func Fgetvl(fname, sname string) []string {
+	return fdefault.Fgetvl(fname, sname)
+}
+
+// fgetvle - get all values of a value facet, returning an error
+func FgetvlE(fname, sname string) ([]string, error) {
+	return fdefault.FgetvlE(fname, sname)
+}
+
+// fputvl - put a list of values into a value facet
+func Fputvl(fname, sname string, lista []string) bool {
+	return fdefault.Fputvl(fname, sname, lista)
+}
+
+// fputvle - put a list of values into a value facet, returning an error
+func FputvlE(fname, sname string, lista []string) error {
+	return fdefault.FputvlE(fname, sname, lista)
+}
+
+// fcountv - get the number of values in a value facet
+func Fcountv(fname, sname string) int {
+	return fdefault.Fcountv(fname, sname)
+}
+
+// fcountve - get the number of values in a value facet, returning an error
+func FcountvE(fname, sname string) (int, error) {
+	return fdefault.FcountvE(fname, sname)
+}
+
+// fmemberv - determine if a value is in a value facet
+func Fmemberv(fname, sname, args string) bool {
+	return fdefault.Fmemberv(fname, sname, args)
+}
+
+// fappendv - append a value to a value facet
+func Fappendv(fname, sname, args string) bool {
+	return fdefault.Fappendv(fname, sname, args)
+}
+
+// fappendve - append a value to a value facet, returning an error
+func FappendvE(fname, sname, args string) error {
+	return fdefault.FappendvE(fname, sname, args)
+}
+
+// finsertv - insert a value into a value facet at position i
+func Finsertv(fname, sname string, i int, args string) bool {
+	return fdefault.Finsertv(fname, sname, i, args)
+}
+
+// finsertve - insert a value into a value facet, returning an error
+func FinsertvE(fname, sname string, i int, args string) error {
+	return fdefault.FinsertvE(fname, sname, i, args)
+}
+
+// fdeletev - delete all occurances of a value from a value facet
+func Fdeletev(fname, sname, args string) bool {
+	return fdefault.Fdeletev(fname, sname, args)
+}
+
+// fdeleteve - delete a value from a value facet, returning an error
+func FdeletevE(fname, sname, args string) error {
+	return fdefault.FdeletevE(fname, sname, args)
+}
+
+// funionv - add a list of values to a value facet
+func Funionv(fname, sname string, lista []string) bool {
+	return fdefault.Funionv(fname, sname, lista)
+}
+
+// funionve - add a list of values to a value facet, returning an error
+func FunionvE(fname, sname string, lista []string) error {
+	return fdefault.FunionvE(fname, sname, lista)
+}
+
+// fdifferencev - remove a list of values from a value facet
+func Fdifferencev(fname, sname string, lista []string) bool {
+	return fdefault.Fdifferencev(fname, sname, lista)
+}
+
+// fdifferenceve - remove a list of values from a value facet, returning an error
+func FdifferencevE(fname, sname string, lista []string) error {
+	return fdefault.FdifferencevE(fname, sname, lista)
+}
+
+// ffindin - find all frames having a given value among the values of a value facet
+func Ffindin(sname string, args string) []string {
+	return fdefault.Ffindin(sname, args)
+}
+
+// fputtype - declare the type of a slot
+func Fputtype(fname, sname, vtype string) bool {
+	return fdefault.Fputtype(fname, sname, vtype)
+}
+
+// fputtypee - declare the type of a slot, returning an error
+func FputtypeE(fname, sname, vtype string) error {
+	return fdefault.FputtypeE(fname, sname, vtype)
+}
+
+// fgettype - get the declared type of a slot, "" if it has none
+func Fgettype(fname, sname string) string {
+	return fdefault.Fgettype(fname, sname)
+}
+
+// fremovetype - remove the type of a slot
+func Fremovetype(fname, sname string) bool {
+	return fdefault.Fremovetype(fname, sname)
+}
+
+// fremovetypee - remove the type of a slot, returning an error
+func FremovetypeE(fname, sname string) error {
+	return fdefault.FremovetypeE(fname, sname)
+}
+
+// ftypev - get the type of a value facet, declared or else inferred
+func Ftypev(fname, sname string) string {
+	return fdefault.Ftypev(fname, sname)
+}
+
+// fgetvi - get a value facet as an int
+func Fgetvi(fname, sname string) int64 {
+	return fdefault.Fgetvi(fname, sname)
+}
+
+// fgetvie - get a value facet as an int, returning an error
+func FgetviE(fname, sname string) (int64, error) {
+	return fdefault.FgetviE(fname, sname)
+}
+
+// fgetvf - get a value facet as a float
+func Fgetvf(fname, sname string) float64 {
+	return fdefault.Fgetvf(fname, sname)
+}
+
+// fgetvfe - get a value facet as a float, returning an error
+func FgetvfE(fname, sname string) (float64, error) {
+	return fdefault.FgetvfE(fname, sname)
+}
+
+// fgetvb - get a value facet as a bool
+func Fgetvb(fname, sname string) bool {
+	return fdefault.Fgetvb(fname, sname)
+}
+
+// fgetvbe - get a value facet as a bool, returning an error
+func FgetvbE(fname, sname string) (bool, error) {
+	return fdefault.FgetvbE(fname, sname)
+}
+
+// fgetvt - get a value facet as a time
+func Fgetvt(fname, sname string) time.Time {
+	return fdefault.Fgetvt(fname, sname)
+}
+
+// fgetvte - get a value facet as a time, returning an error
+func FgetvtE(fname, sname string) (time.Time, error) {
+	return fdefault.FgetvtE(fname, sname)
+}
+
+// fgetvr - get a value facet as the name of an existing frame
+func Fgetvr(fname, sname string) string {
+	return fdefault.Fgetvr(fname, sname)
+}
+
+// fgetvre - get a value facet as the name of an existing frame,
+// returning an error
+func FgetvrE(fname, sname string) (string, error) {
+	return fdefault.FgetvrE(fname, sname)
+}
+
+// fputvi - put an int in a value facet
+func Fputvi(fname, sname string, i int64) bool {
+	return fdefault.Fputvi(fname, sname, i)
+}
+
+// fputvie - put an int in a value facet, returning an error
+func FputviE(fname, sname string, i int64) error {
+	return fdefault.FputviE(fname, sname, i)
+}
+
+// fputvf - put a float in a value facet
+func Fputvf(fname, sname string, x float64) bool {
+	return fdefault.Fputvf(fname, sname, x)
+}
+
+// fputvfe - put a float in a value facet, returning an error
+func FputvfE(fname, sname string, x float64) error {
+	return fdefault.FputvfE(fname, sname, x)
+}
+
+// fputvb - put a bool in a value facet
+func Fputvb(fname, sname string, b bool) bool {
+	return fdefault.Fputvb(fname, sname, b)
+}
+
+// fputvbe - put a bool in a value facet, returning an error
+func FputvbE(fname, sname string, b bool) error {
+	return fdefault.FputvbE(fname, sname, b)
+}
+
+// fputvt - put a time in a value facet
+func Fputvt(fname, sname string, t time.Time) bool {
+	return fdefault.Fputvt(fname, sname, t)
+}
+
+// fputvte - put a time in a value facet, returning an error
+func FputvtE(fname, sname string, t time.Time) error {
+	return fdefault.FputvtE(fname, sname, t)
+}
+
+// fputvr - put the name of an existing frame in a value facet
+func Fputvr(fname, sname, fname2 string) bool {
+	return fdefault.Fputvr(fname, sname, fname2)
+}
+
+// fputvre - put the name of an existing frame in a value facet,
+// returning an error
+func FputvrE(fname, sname, fname2 string) error {
+	return fdefault.FputvrE(fname, sname, fname2)
+}
+
+// fgetxm - get a Method from fmethods
+func Fgetxm(mname string) (Method, bool) {
+	return fdefault.Fgetxm(mname)
+}
+
+// fexecma - execute a method with arguments and return its result
+func Fexecma(fname, sname string, args ...any) (any, error) {
+	return fdefault.Fexecma(fname, sname, args...)
+}
+
+// fexecda - directly execute a demon with arguments and return its result
+func Fexecda(fname, sname, dname string, args ...any) (any, error) {
+	return fdefault.Fexecda(fname, sname, dname, args...)
+}
+
+// fexportf - export a frame as JSON
+func Fexportf(fname string) ([]byte, error) {
+	return fdefault.Fexportf(fname)
+}
+
+// fexportfs - export a frameset and its members as JSON
+func Fexportfs(name string) ([]byte, error) {
+	return fdefault.Fexportfs(name)
+}
+
+// fexport - export every frame as JSON, ordered by name
+func Fexport() ([]byte, error) {
+	return fdefault.Fexport()
+}
+
+// fimportf - import a frame from JSON
+func Fimportf(data []byte) error {
+	return fdefault.Fimportf(data)
+}
+
+// fimport - import a list of frames from JSON, as written by Fexport or Fexportfs
+func Fimport(data []byte) error {
+	return fdefault.Fimport(data)
+}
+
+// SetStorage - set the storage of a knowledge base
+func SetStorage(s Storage) {
+	fdefault.SetStorage(s)
+}
+
+// GetStorage - get the storage of a knowledge base
+func GetStorage() Storage {
+	return fdefault.GetStorage()
+}
+
+// fstored - get a list of stored frames
+func Fstored() ([]string, error) {
+	return fdefault.Fstored()
+}
+
+// funstoref - remove a stored frame
+func Funstoref(fname string) bool {
+	return fdefault.Funstoref(fname)
+}
+
+// funstorefe - remove a stored frame, returning an error
+func FunstorefE(fname string) error {
+	return fdefault.FunstorefE(fname)
+}
+
+// OpenJournal - replay the journal in a directory and keep it from now on
+func OpenJournal(dir string, every int) error {
+	return fdefault.OpenJournal(dir, every)
+}
+
+// Checkpoint - write every frame and method to a checkpoint and drop the journal before it
+func Checkpoint() error {
+	return fdefault.Checkpoint()
+}
+
+// CloseJournal - stop keeping a journal
+func CloseJournal() error {
+	return fdefault.CloseJournal()
+}
+
+// GetJournal - get the journal of a knowledge base, nil if it has none
+func GetJournal() *Journal {
+	return fdefault.GetJournal()
+}
+
+// Begin - begin a transaction on the default knowledge base
+func Begin() *KnowledgeBase {
+	return fdefault.Begin()
+}
+
+// SetHistory - keep a history of at most n steps, or none if n is 0
+func SetHistory(n int) {
+	fdefault.SetHistory(n)
+}
+
+// Group - call fn, making a single step of the changes it makes
+func Group(fn func() error) error {
+	return fdefault.Group(fn)
+}
+
+// Mark - put a named mark in the history after the last step
+func Mark(name string) error {
+	return fdefault.Mark(name)
+}
+
+// Undo - undo the last step
+func Undo() error {
+	return fdefault.Undo()
+}
+
+// Redo - make the last undone step again
+func Redo() error {
+	return fdefault.Redo()
+}
+
+// UndoTo - undo every step made after the last mark of a name
+func UndoTo(name string) error {
+	return fdefault.UndoTo(name)
+}
+
+// Snapshot - take a snapshot of the default knowledge base
+func Snapshot() *KnowledgeBase {
+	return fdefault.Snapshot()
+}
+
+// Fork - begin a transaction on a snapshot of the default knowledge base
+func Fork() *KnowledgeBase {
+	return fdefault.Fork()
+}
+
+// SetIsa - set the name of the slot naming the parent of a frame
+func SetIsa(sname string) {
+	fdefault.SetIsa(sname)
+}
+
+// GetIsa - get the name of the slot naming the parent of a frame
+func GetIsa() string {
+	return fdefault.GetIsa()
+}
+
+// fputp - put parents into a frame, replacing any it has
+func Fputp(fname string, pnames ...string) bool {
+	return fdefault.Fputp(fname, pnames...)
+}
+
+// fputpe - put parents into a frame, returning an error
+func FputpE(fname string, pnames ...string) error {
+	return fdefault.FputpE(fname, pnames...)
+}
+
+// fgetp - get the first parent of a frame, "" if it has none
+func Fgetp(fname string) string {
+	return fdefault.Fgetp(fname)
+}
+
+// fgetpe - get the first parent of a frame, returning an error
+func FgetpE(fname string) (string, error) {
+	return fdefault.FgetpE(fname)
+}
+
+// flistp - get the parents of a frame
+func Flistp(fname string) []string {
+	return fdefault.Flistp(fname)
+}
+
+// flistpe - get the parents of a frame, returning an error
+func FlistpE(fname string) ([]string, error) {
+	return fdefault.FlistpE(fname)
+}
+
+// fremovep - remove the parents of a frame
+func Fremovep(fname string) bool {
+	return fdefault.Fremovep(fname)
+}
+
+// fremovepe - remove the parents of a frame, returning an error
+func FremovepE(fname string) error {
+	return fdefault.FremovepE(fname)
+}
+
+// fpathp - get a frame and its ancestors in lookup order
+func Fpathp(fname string) []string {
+	return fdefault.Fpathp(fname)
+}
+
+// fpathpe - get a frame and its ancestors in lookup order, returning an error
+func FpathpE(fname string) ([]string, error) {
+	return fdefault.FpathpE(fname)
+}
+
+// finherits - get the frame a slot is inherited from
+func Finherits(fname, sname string) string {
+	return fdefault.Finherits(fname, sname)
+}
+
+// finheritse - get the frame a slot is inherited from, returning an error
+func FinheritsE(fname, sname string) (string, error) {
+	return fdefault.FinheritsE(fname, sname)
+}
+
+// fconflicts - get the slots a frame inherits conflicting definitions of
+func Fconflicts(fname string) []Conflict {
+	return fdefault.Fconflicts(fname)
+}
+
+// fconflictse - get the slots a frame inherits conflicting definitions
+// of, returning an error
+func FconflictsE(fname string) ([]Conflict, error) {
+	return fdefault.FconflictsE(fname)
+}
+
+// fputdefault - put a default into a slot
+func Fputdefault(fname, sname, value string) bool {
+	return fdefault.Fputdefault(fname, sname, value)
+}
+
+// fputdefaulte - put a default into a slot, returning an error
+func FputdefaultE(fname, sname, value string) error {
+	return fdefault.FputdefaultE(fname, sname, value)
+}
+
+// fgetdefault - get the default of a slot, "" if it has none
+func Fgetdefault(fname, sname string) string {
+	return fdefault.Fgetdefault(fname, sname)
+}
+
+// fgetdefaulte - get the default of a slot, returning an error
+func FgetdefaultE(fname, sname string) (string, error) {
+	return fdefault.FgetdefaultE(fname, sname)
+}
+
+// fremovedefault - remove the default of a slot
+func Fremovedefault(fname, sname string) bool {
+	return fdefault.Fremovedefault(fname, sname)
+}
+
+// fremovedefaulte - remove the default of a slot, returning an error
+func FremovedefaultE(fname, sname string) error {
+	return fdefault.FremovedefaultE(fname, sname)
+}
+
+// fputneeded - put an ifneeded demon into a slot, which keeps the values
+// it computes if cache is set
+func Fputneeded(fname, sname, mname string, cache bool) bool {
+	return fdefault.Fputneeded(fname, sname, mname, cache)
+}
+
+// fputneedede - put an ifneeded demon into a slot, returning an error
+func FputneededE(fname, sname, mname string, cache bool) error {
+	return fdefault.FputneededE(fname, sname, mname, cache)
+}
+
+// fputconstraint - put a constraint into a slot, replacing any of the
+// same kind
+func Fputconstraint(fname, sname, ctype string, args ...string) bool {
+	return fdefault.Fputconstraint(fname, sname, ctype, args...)
+}
+
+// fputconstrainte - put a constraint into a slot, returning an error
+func FputconstraintE(fname, sname, ctype string, args ...string) error {
+	return fdefault.FputconstraintE(fname, sname, ctype, args...)
+}
+
+// fgetconstraint - get a constraint of a slot, nil if it has none
+func Fgetconstraint(fname, sname, ctype string) []string {
+	return fdefault.Fgetconstraint(fname, sname, ctype)
+}
+
+// fgetconstrainte - get a constraint of a slot, returning an error
+func FgetconstraintE(fname, sname, ctype string) ([]string, error) {
+	return fdefault.FgetconstraintE(fname, sname, ctype)
+}
+
+// fremoveconstraint - remove a constraint from a slot
+func Fremoveconstraint(fname, sname, ctype string) bool {
+	return fdefault.Fremoveconstraint(fname, sname, ctype)
+}
+
+// fremoveconstrainte - remove a constraint from a slot, returning an error
+func FremoveconstraintE(fname, sname, ctype string) error {
+	return fdefault.FremoveconstraintE(fname, sname, ctype)
+}
+
+// fcheckf - check every slot of a frame against its constraints
+func Fcheckf(fname string) bool {
+	return fdefault.Fcheckf(fname)
+}
+
+// fcheckfe - check every slot of a frame against its constraints,
+// returning every constraint broken
+func FcheckfE(fname string) error {
+	return fdefault.FcheckfE(fname)
+}
+
+// fsputconstraint - put a constraint into a frameset and its members
+func Fsputconstraint(name, sname, ctype string, args ...string) bool {
+	return fdefault.Fsputconstraint(name, sname, ctype, args...)
+}
+
+// fsputconstrainte - put a constraint into a frameset, returning an error
+func FsputconstraintE(name, sname, ctype string, args ...string) error {
+	return fdefault.FsputconstraintE(name, sname, ctype, args...)
+}
+
+// fsremoveconstraint - remove a constraint from a frameset and its members
+func Fsremoveconstraint(name, sname, ctype string) bool {
+	return fdefault.Fsremoveconstraint(name, sname, ctype)
+}
+
+// fsremoveconstrainte - remove a constraint from a frameset, returning
+// an error
+func FsremoveconstraintE(name, sname, ctype string) error {
+	return fdefault.FsremoveconstraintE(name, sname, ctype)
+}
+
+// fputdf - put a demon into a frame
+func Fputdf(fname, dname, mname string) bool {
+	return fdefault.Fputdf(fname, dname, mname)
+}
+
+// fputdfe - put a demon into a frame, returning an error
+func FputdfE(fname, dname, mname string) error {
+	return fdefault.FputdfE(fname, dname, mname)
+}
+
+// fgetdf - get a demon of a frame, "" if it has none
+func Fgetdf(fname, dname string) string {
+	return fdefault.Fgetdf(fname, dname)
+}
+
+// fgetdfe - get a demon of a frame, returning an error
+func FgetdfE(fname, dname string) (string, error) {
+	return fdefault.FgetdfE(fname, dname)
+}
+
+// fremovedf - remove a demon from a frame
+func Fremovedf(fname, dname string) bool {
+	return fdefault.Fremovedf(fname, dname)
+}
+
+// fremovedfe - remove a demon from a frame, returning an error
+func FremovedfE(fname, dname string) error {
+	return fdefault.FremovedfE(fname, dname)
+}
+
+// faddd - add a demon to a slot, creating the demon facet if needed
+func Faddd(fname, sname, dname, mname string, priority int) bool {
+	return fdefault.Faddd(fname, sname, dname, mname, priority)
+}
+
+// faddde - add a demon to a slot, returning an error
+func FadddE(fname, sname, dname, mname string, priority int) error {
+	return fdefault.FadddE(fname, sname, dname, mname, priority)
+}
+
+// fdeld - take a demon away from a slot, removing the demon facet if it
+// was the last
+func Fdeld(fname, sname, dname, mname string) bool {
+	return fdefault.Fdeld(fname, sname, dname, mname)
+}
+
+// fdelde - take a demon away from a slot, returning an error
+func FdeldE(fname, sname, dname, mname string) error {
+	return fdefault.FdeldE(fname, sname, dname, mname)
+}
+
+// flistd - list the demons of a slot
+func Flistd(fname, sname string) []Demon {
+	return fdefault.Flistd(fname, sname)
+}
+
+// flistde - list the demons of a slot, returning an error
+func FlistdE(fname, sname string) ([]Demon, error) {
+	return fdefault.FlistdE(fname, sname)
+}
+
+// fadddf - add a demon to a frame
+func Fadddf(fname, dname, mname string, priority int) bool {
+	return fdefault.Fadddf(fname, dname, mname, priority)
+}
+
+// fadddfe - add a demon to a frame, returning an error
+func FadddfE(fname, dname, mname string, priority int) error {
+	return fdefault.FadddfE(fname, dname, mname, priority)
+}
+
+// fdeldf - take a demon away from a frame
+func Fdeldf(fname, dname, mname string) bool {
+	return fdefault.Fdeldf(fname, dname, mname)
+}
+
+// fdeldfe - take a demon away from a frame, returning an error
+func FdeldfE(fname, dname, mname string) error {
+	return fdefault.FdeldfE(fname, dname, mname)
+}
+
+// flistdf - list the demons of a frame and then those of its slots
+func Flistdf(fname string) []Demon {
+	return fdefault.Flistdf(fname)
+}
+
+// flistdfe - list the demons of a frame and its slots, returning an error
+func FlistdfE(fname string) ([]Demon, error) {
+	return fdefault.FlistdfE(fname)
+}
+
+// fslistd - list the demons of a frameset and then those of its members
+func Fslistd(name string) []Demon {
+	return fdefault.Fslistd(name)
+}
+
+// fslistde - list the demons of a frameset and its members, returning an
+// error
+func FslistdE(name string) ([]Demon, error) {
+	return fdefault.FslistdE(name)
+}
+
+// SetDemonDepth - set the limit on demons and methods nested
+func SetDemonDepth(n int) {
+	fdefault.SetDemonDepth(n)
+}
+
+// GetDemonDepth - get the limit on demons and methods nested
+func GetDemonDepth() int {
+	return fdefault.GetDemonDepth()
+}
+
+// SetRefDepth - set the limit on references followed from a slot
+func SetRefDepth(n int) {
+	fdefault.SetRefDepth(n)
+}
+
+// GetRefDepth - get the limit on references followed from a slot
+func GetRefDepth() int {
+	return fdefault.GetRefDepth()
+}
+
+// SetPolicy - set the policy for methods and demons failing
+func SetPolicy(p Policy) {
+	fdefault.SetPolicy(p)
+}
+
+// GetPolicy - get the policy for methods and demons failing
+func GetPolicy() Policy {
+	return fdefault.GetPolicy()
+}
+
+// SetLogger - set the logger used by PolicyLog, log.Default() if nil
+func SetLogger(l *log.Logger) {
+	fdefault.SetLogger(l)
+}
+
+// GetErrors - get the errors kept by PolicyCollect, oldest first
+func GetErrors() []error {
+	return fdefault.GetErrors()
+}
+
+// ClearErrors - drop the errors kept by PolicyCollect
+func ClearErrors() {
+	fdefault.ClearErrors()
+}
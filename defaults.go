@@ -0,0 +1,215 @@
+/**********************************************************************
+ *
+ * file name:    defaults.go
+ * description:  default values and if-needed demons
+ *
+ * A slot may hold a default in a default facet, and name a method in
+ * an ifneeded demon facet:
+ *
+ *	fframes[<fname>][<sname>,default]		default values
+ *	fframes[<fname>][<sname>,ifneeded]		method computing the values
+ *
+ * Fgetv, Fgetvl and the other commands reading a value facet fall back
+ * on these when the slot has no values: the ifneeded demon is called
+ * first, and its result, a string, a list of strings or anything else
+ * which is then printed, is taken as the values. A demon returning nil,
+ * or none at all, leaves it to the default. A slot need not have a
+ * value facet to have a default or an ifneeded demon, but one holding a
+ * method facet has neither.
+ *
+ * Both are looked up in the frame and then in its ancestors, so a
+ * frame whose own slot only overrides some facets still gets the
+ * default of its parent, and both follow references like the value
+ * facet. The ifneeded demon is called for the frame asked, not the one
+ * holding the demon. Values computed by a demon put with caching are
+ * put into the value facet of that frame, creating it if needed, so the
+ * demon is called only once; Fremovev or putting no values calls it
+ * again. Defaults and computed values must match the type of the slot.
+ *
+ *							Functions
+ *
+ * Fgetdefault				get the default of a slot
+ * Fputdefault				put a default into a slot
+ * Fputneeded				put an ifneeded demon into a slot
+ * Fremovedefault			remove the default of a slot
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// fcache - marks an ifneeded demon whose values are kept
+const fcache = "cache"
+
+// facetp - copy of the nearest slot holding a facet, in the frame or
+// its ancestors, nil if there is none
+func (kb *KnowledgeBase) facetp(fname, sname, ftype string) slot {
+	plist, _ := kb.pathp(fname)
+	for _, i := range plist {
+		var s slot
+		kb.readf(i, func(f Frame) {
+			s = fslot(f, i, sname)
+		})
+		if s.has(ftype) {
+			return s
+		}
+	}
+	return nil
+}
+
+// fvalues - the values a method returned, nil if none
+func fvalues(result any) []string {
+	switch x := result.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{x}
+	case []string:
+		return append([]string{}, x...)
+	default:
+		return []string{fmt.Sprint(x)}
+	}
+}
+
+// needed - values of a slot which has none, from its ifneeded demon or
+// its default
+// s is a copy of the slot as looked up; found is false if there is
+// neither
+func (kb *KnowledgeBase) needed(fname, sname string, s slot) (value []string, found bool, err error) {
+	vtype := Getval(s["type"])
+	if d := kb.facetp(fname, sname, "ifneeded"); d.demon("ifneeded") != "" {
+		mname := d.demon("ifneeded")
+		result, err := kb.invoke(mname, ErrDemonMissing, &MethodContext{KB: kb, Frame: fname, Slot: sname, Op: "ifneeded"})
+		if err != nil {
+			return nil, true, err
+		}
+		if value = fvalues(result); value != nil {
+			if value, err = fnormv(vtype, value); err != nil {
+				return nil, true, err
+			}
+			if Fmember(d["ifneeded"][1:], fcache) {
+				err = kb.writef(fname, func(f Frame) error {
+					fown(f, fname, sname, s)
+					if !Fmember(f[sname+",facets"], "value") {
+						if err := fcreatet(f, fname, sname, "value", "method", "ref"); err != nil {
+							return err
+						}
+					}
+					f[sname+",value"] = value
+					return nil
+				})
+				// a snapshot computes the values every time
+				if err != nil && !errors.Is(err, ErrReadOnly) {
+					return nil, true, err
+				}
+			}
+			return value, true, nil
+		}
+	}
+	if d := kb.facetp(fname, sname, "default"); d != nil {
+		value, err = fnormv(vtype, d["default"])
+		return value, true, err
+	}
+	return nil, false, nil
+}
+
+// fputdefault - put a default into a slot
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,default]
+// where fname is the original or referenced frame
+func (kb *KnowledgeBase) Fputdefault(fname, sname, value string) bool {
+	return kb.FputdefaultE(fname, sname, value) == nil
+}
+
+// fputdefaulte - put a default into a slot, returning an error
+func (kb *KnowledgeBase) FputdefaultE(fname, sname, value string) error {
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		err = kb.writef(fname, func(f Frame) error {
+			fown(f, fname, sname, s)
+			x, err := fnormt(Getval(f[sname+",type"]), value)
+			if err != nil {
+				return err
+			}
+			if !Fmember(f[sname+",facets"], "default") {
+				if err := fcreatet(f, fname, sname, "default", "method"); err != nil {
+					return err
+				}
+			}
+			return fputt(f, sname, "default", x)
+		})
+	}
+	return ferror("fputdefault", fname, sname, err)
+}
+
+// fgetdefault - get the default of a slot, "" if it has none
+// requires that fframes[fname][sname,facets] exists
+func (kb *KnowledgeBase) Fgetdefault(fname, sname string) string {
+	value, _ := kb.FgetdefaultE(fname, sname)
+	return value
+}
+
+// fgetdefaulte - get the default of a slot, returning an error
+func (kb *KnowledgeBase) FgetdefaultE(fname, sname string) (string, error) {
+	fname, _, err := kb.follow(fname, sname)
+	if err == nil {
+		if d := kb.facetp(fname, sname, "default"); d != nil {
+			return Getval(d["default"]), nil
+		}
+		err = ErrFacetNotFound
+	}
+	return "", ferror("fgetdefault", fname, sname, err)
+}
+
+// fremovedefault - remove the default of a slot
+// requires that fframes[fname][sname,default] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,default]
+// where fname is the original or referenced frame
+func (kb *KnowledgeBase) Fremovedefault(fname, sname string) bool {
+	return kb.FremovedefaultE(fname, sname) == nil
+}
+
+// fremovedefaulte - remove the default of a slot, returning an error
+func (kb *KnowledgeBase) FremovedefaultE(fname, sname string) error {
+	fname, _, err := kb.follow(fname, sname)
+	if err == nil {
+		err = kb.writef(fname, func(f Frame) error {
+			return fremovet(f, sname, "default")
+		})
+	}
+	return ferror("fremovedefault", fname, sname, err)
+}
+
+// fputneeded - put an ifneeded demon into a slot, which keeps the values
+// it computes if cache is set
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,ifneeded]
+// where fname is the original or referenced frame
+func (kb *KnowledgeBase) Fputneeded(fname, sname, mname string, cache bool) bool {
+	return kb.FputneededE(fname, sname, mname, cache) == nil
+}
+
+// fputneedede - put an ifneeded demon into a slot, returning an error
+func (kb *KnowledgeBase) FputneededE(fname, sname, mname string, cache bool) error {
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		err = kb.writef(fname, func(f Frame) error {
+			fown(f, fname, sname, s)
+			if !Fmember(f[sname+",facets"], "ifneeded") {
+				if err := fcreatet(f, fname, sname, "ifneeded", "method"); err != nil {
+					return err
+				}
+			}
+			f[sname+",ifneeded"] = []string{mname}
+			if cache {
+				f[sname+",ifneeded"] = append(f[sname+",ifneeded"], fcache)
+			}
+			return nil
+		})
+	}
+	return ferror("fputneeded", fname, sname, err)
+}
@@ -0,0 +1,163 @@
+package framesets2
+
+import (
+	"errors"
+	"testing"
+)
+
+// dkb - a knowledge base in which animal has a value slot legs with a
+// default of 4, and dog is an animal
+func dkb() *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("animal")
+	kb.Fcreates("animal", "legs")
+	kb.Fcreatev("animal", "legs")
+	kb.Fputdefault("animal", "legs", "4")
+	kb.Fcreatef("dog")
+	kb.Fputp("dog", "animal")
+	return kb
+}
+
+func TestDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(kb *KnowledgeBase)
+		fname  string
+		want   string
+	}{
+		{"default", func(kb *KnowledgeBase) {}, "animal", "4"},
+		{"value", func(kb *KnowledgeBase) { kb.Fputv("animal", "legs", "5") }, "animal", "5"},
+		{"no value facet", func(kb *KnowledgeBase) { kb.Fremovev("animal", "legs") }, "animal", "4"},
+		{"inherited", func(kb *KnowledgeBase) {
+			kb.Fcreates("dog", "legs")
+			kb.Fcreatev("dog", "legs")
+		}, "dog", "4"},
+		{"referenced", func(kb *KnowledgeBase) {
+			kb.Fcreatef("ref")
+			kb.Fcreates("ref", "legs")
+			kb.Fcreater("ref", "legs")
+			kb.Fputr("ref", "legs", "animal")
+		}, "ref", "4"},
+	}
+	for _, tt := range tests {
+		kb := dkb()
+		tt.change(kb)
+		if got := kb.Fgetv(tt.fname, "legs"); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if got := dkb().Fgetdefault("animal", "legs"); got != "4" {
+		t.Errorf("fgetdefault: got %s, want 4", got)
+	}
+}
+
+func TestDefaultErrors(t *testing.T) {
+	kb := dkb()
+	kb.Fputtype("animal", "legs", "int")
+	kb.Fcreates("animal", "m")
+	kb.Fcreatem("animal", "m")
+	tests := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{"typed", func() error { return kb.FputdefaultE("animal", "legs", "x") }, ErrType},
+		{"method slot", func() error { return kb.FputdefaultE("animal", "m", "1") }, ErrFacetConflict},
+		{"missing slot", func() error { return errv(kb.FgetvE("animal", "nothing")) }, ErrSlotNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if got := kb.Fgetdefault("animal", "legs"); got != "4" {
+		t.Errorf("default after errors: got %q, want 4", got)
+	}
+}
+
+// nkb - a knowledge base in which car has a slot age with a default of
+// 1, and methods count, counting its calls in n, and none, returning nil
+func nkb(n *int) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatex("count")
+	kb.Fputxm("count", func(c *MethodContext) (any, error) {
+		*n++
+		return *n * 10, nil
+	})
+	kb.Fcreatex("none")
+	kb.Fputxm("none", func(c *MethodContext) (any, error) {
+		return nil, nil
+	})
+	kb.Fcreatef("car")
+	kb.Fcreates("car", "age")
+	kb.Fputdefault("car", "age", "1")
+	return kb
+}
+
+func TestIfNeeded(t *testing.T) {
+	tests := []struct {
+		name   string
+		mname  string
+		cache  bool
+		want   []string
+		calls  int
+		cached bool
+	}{
+		{"computed each time", "count", false, []string{"10", "20"}, 2, false},
+		{"cached", "count", true, []string{"10", "10"}, 1, true},
+		{"nothing computed", "none", false, []string{"1", "1"}, 0, false},
+	}
+	for _, tt := range tests {
+		n := 0
+		kb := nkb(&n)
+		if err := kb.FputneededE("car", "age", tt.mname, tt.cache); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for i, want := range tt.want {
+			if got := kb.Fgetv("car", "age"); got != want {
+				t.Errorf("%s: call %d: got %s, want %s", tt.name, i, got, want)
+			}
+		}
+		if n != tt.calls || kb.Fexistv("car", "age") != tt.cached {
+			t.Errorf("%s: %d calls, value kept %v", tt.name, n, kb.Fexistv("car", "age"))
+		}
+	}
+}
+
+func TestIfNeededMissing(t *testing.T) {
+	n := 0
+	kb := nkb(&n)
+	kb.Fputneeded("car", "age", "missing", false)
+	if _, err := kb.FgetvE("car", "age"); !errors.Is(err, ErrDemonMissing) {
+		t.Errorf("got %v, want %v", err, ErrDemonMissing)
+	}
+}
+
+func TestIfNeededSnapshot(t *testing.T) {
+	n := 0
+	kb := nkb(&n)
+	kb.Fputneeded("car", "age", "count", true)
+	sn := kb.Snapshot()
+	defer sn.Release()
+	if got := sn.Fgetv("car", "age"); got != "10" {
+		t.Errorf("got %s, want 10", got)
+	}
+	// a snapshot cannot keep the value, nor can the knowledge base
+	if kb.Fexistv("car", "age") || sn.Fexistv("car", "age") {
+		t.Error("value kept")
+	}
+}
+
+func TestDefaultJSON(t *testing.T) {
+	n := 0
+	kb := nkb(&n)
+	kb.Fputneeded("car", "age", "count", true)
+	data, _ := kb.Fexportf("car")
+	kb2 := NewKnowledgeBase()
+	if err := kb2.Fimportf(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := kb2.Fgetdefault("car", "age"); got != "1" {
+		t.Errorf("got %q, want 1 from %s", got, data)
+	}
+}
@@ -0,0 +1,344 @@
+/**********************************************************************
+ *
+ * file name:    demons.go
+ * description:  several demons for an event, ordered by priority
+ *
+ * A demon facet of a slot, or a demon of a frame, holds a list of
+ * methods rather than one, so several demons can watch the same event
+ * without replacing each other. Each method is followed by its priority
+ * as an entry of its own, so any method name can be used:
+ *
+ *	fframes[<fname>][<sname>,ifputv]		{"audit", "0", "check", "-10", "log", "5"}
+ *
+ * A method put alone by Fputd or Fputdf, with no priority after it, has
+ * priority 0.
+ *
+ * The demons of an event are called by ascending priority, those of the
+ * same priority in the order they were added, and the first failing
+ * stops the rest. Before demons are called in the same order, each
+ * getting the values as rewritten by those before it. Fputd and Fputdf
+ * put a demon of priority 0 in place of the first one, as before, while
+ * Faddd and Fadddf add one to those already there. Adding a method again
+ * changes its priority. Taking away the last demon of a slot removes the
+ * demon facet. An ifneeded demon computes the values of a slot, so a
+ * slot has only one.
+ *
+ *							Functions
+ *
+ * Faddd					add a demon to a slot
+ * Fadddf					add a demon to a frame
+ * Fdeld					take a demon away from a slot
+ * Fdeldf					take a demon away from a frame
+ * Flistd					list the demons of a slot
+ * Flistdf					list the demons of a frame and its slots
+ * Fslistd					list the demons of a frameset and its members
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Demon - a demon held by a slot or, if Slot is "", a frame
+type Demon struct {
+	Frame    string
+	Slot     string
+	Type     string
+	Method   string
+	Priority int
+}
+
+// fdemonss - list of slot demon types, besides their before and after
+// demons and ifneeded
+var fdemonss = []string{
+	"ifcreatem", "ifcreater", "ifcreatev", "ifexecm", "ifexistm", "ifexistr", "ifexistv",
+	"ifgetm", "ifgetr", "ifgetv", "ifputm", "ifputr", "ifputv", "ifref",
+	"ifremovem", "ifremover", "ifremovev",
+}
+
+// fdemont - determine if a facet type is a slot demon type
+func fdemont(dname string) bool {
+	for _, i := range []string{"before", "after"} {
+		if x, ok := strings.CutPrefix(dname, i); ok {
+			return Fmember(fdemonss, "if"+x)
+		}
+	}
+	return dname == "ifneeded" || Fmember(fdemonss, dname)
+}
+
+// fdemonp - demons in a demon facet, in the order they were added
+func fdemonp(lista []string) []Demon {
+	demons := []Demon{}
+	for i := 0; i < len(lista); i += 2 {
+		d := Demon{Method: lista[i]}
+		if i+1 < len(lista) {
+			d.Priority, _ = strconv.Atoi(lista[i+1])
+		}
+		if d.Method != "" {
+			demons = append(demons, d)
+		}
+	}
+	return demons
+}
+
+// fdemonx - entries of a demon facet for a list of demons
+func fdemonx(demons []Demon) []string {
+	lista := []string{}
+	for _, i := range demons {
+		lista = append(lista, i.Method, strconv.Itoa(i.Priority))
+	}
+	return lista
+}
+
+// fdemonl - demons in a demon facet, in the order they are called
+func fdemonl(lista []string) []Demon {
+	demons := fdemonp(lista)
+	sort.SliceStable(demons, func(i, j int) bool {
+		return demons[i].Priority < demons[j].Priority
+	})
+	return demons
+}
+
+// fmethodl - methods in a demon facet, in the order they are called
+func fmethodl(lista []string) []string {
+	mnames := []string{}
+	for _, i := range fdemonl(lista) {
+		mnames = append(mnames, i.Method)
+	}
+	return mnames
+}
+
+// fadddl - add a demon to the entries of a demon facet
+// a method already there gets the new priority
+func fadddl(lista []string, mname string, priority int) []string {
+	demons := fdemonp(fdeldl(lista, mname))
+	return fdemonx(append(demons, Demon{Method: mname, Priority: priority}))
+}
+
+// fdeldl - take a demon away from the entries of a demon facet
+func fdeldl(lista []string, mname string) []string {
+	demons := []Demon{}
+	for _, i := range fdemonp(lista) {
+		if i.Method != mname {
+			demons = append(demons, i)
+		}
+	}
+	return fdemonx(demons)
+}
+
+// fhasdl - determine if the entries of a demon facet hold a method
+func fhasdl(lista []string, mname string) bool {
+	for _, i := range fdemonp(lista) {
+		if i.Method == mname {
+			return true
+		}
+	}
+	return false
+}
+
+// demons - methods of the demons of a slot for an event, in the order
+// they are called
+func (s slot) demons(dname string) []string {
+	if !s.has(dname) {
+		return nil
+	}
+	return fmethodl(s[dname])
+}
+
+// faddd - add a demon to a slot, creating the demon facet if needed
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,dname]
+func (kb *KnowledgeBase) Faddd(fname, sname, dname, mname string, priority int) bool {
+	return kb.FadddE(fname, sname, dname, mname, priority) == nil
+}
+
+// faddde - add a demon to a slot, returning an error
+func (kb *KnowledgeBase) FadddE(fname, sname, dname, mname string, priority int) error {
+	if !fdemont(dname) || dname == "ifneeded" {
+		return ferror("faddd", fname, sname, fmt.Errorf("%w: no demon %q", ErrFacetNotFound, dname))
+	}
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[sname+",facets"], dname) {
+			if err := fcreatet(f, fname, sname, dname); err != nil {
+				return err
+			}
+		}
+		f[sname+","+dname] = fadddl(f[sname+","+dname], mname, priority)
+		return nil
+	})
+	return ferror("faddd", fname, sname, err)
+}
+
+// fdeld - take a demon away from a slot, removing the demon facet if it
+// was the last
+// requires that fframes[fname][sname,dname] holds mname
+// modifies fframes[fname][sname,facets], fframes[fname][sname,dname]
+func (kb *KnowledgeBase) Fdeld(fname, sname, dname, mname string) bool {
+	return kb.FdeldE(fname, sname, dname, mname) == nil
+}
+
+// fdelde - take a demon away from a slot, returning an error
+func (kb *KnowledgeBase) FdeldE(fname, sname, dname, mname string) error {
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[fname+",slots"], sname) {
+			return ErrSlotNotFound
+		}
+		lista := f[sname+","+dname]
+		if !Fmember(f[sname+",facets"], dname) || !fhasdl(lista, mname) {
+			return ErrFacetNotFound
+		}
+		listx := fdeldl(lista, mname)
+		if len(fmethodl(listx)) == 0 {
+			return fremovet(f, sname, dname)
+		}
+		f[sname+","+dname] = listx
+		return nil
+	})
+	return ferror("fdeld", fname, sname, err)
+}
+
+// flistd - list the demons of a slot
+// requires that fframes[fname][sname,facets] exists
+func (kb *KnowledgeBase) Flistd(fname, sname string) []Demon {
+	demons, _ := kb.FlistdE(fname, sname)
+	return demons
+}
+
+// flistde - list the demons of a slot, returning an error
+func (kb *KnowledgeBase) FlistdE(fname, sname string) ([]Demon, error) {
+	demons := []Demon{}
+	found := false
+	if !kb.readf(fname, func(f Frame) {
+		if found = Fmember(f[fname+",slots"], sname); found {
+			demons = flistds(f, fname, sname)
+		}
+	}) {
+		return demons, ferror("flistd", fname, sname, ErrFrameNotFound)
+	}
+	if !found {
+		return demons, ferror("flistd", fname, sname, ErrSlotNotFound)
+	}
+	return demons, nil
+}
+
+// flistds - demons of a slot of a frame map, by demon facet
+func flistds(f Frame, fname, sname string) []Demon {
+	demons := []Demon{}
+	for _, i := range f[sname+",facets"] {
+		if fdemont(i) {
+			lista := f[sname+","+i]
+			// the rest of an ifneeded demon says whether to cache
+			if i == "ifneeded" && len(lista) > 1 {
+				lista = lista[:1]
+			}
+			for _, j := range fdemonl(lista) {
+				demons = append(demons, Demon{fname, sname, i, j.Method, j.Priority})
+			}
+		}
+	}
+	return demons
+}
+
+// fadddf - add a demon to a frame
+// requires that fframes[fname] exists
+// modifies fframes[fname][fname,dname]
+func (kb *KnowledgeBase) Fadddf(fname, dname, mname string, priority int) bool {
+	return kb.FadddfE(fname, dname, mname, priority) == nil
+}
+
+// fadddfe - add a demon to a frame, returning an error
+func (kb *KnowledgeBase) FadddfE(fname, dname, mname string, priority int) error {
+	if !Fmember(fdemonsf, dname) {
+		return ferror("fadddf", fname, "", fmt.Errorf("%w: no frame demon %q", ErrFacetNotFound, dname))
+	}
+	kb.fdemons.Store(true)
+	err := kb.writef(fname, func(f Frame) error {
+		f[fdemonk(fname, dname)] = fadddl(f[fdemonk(fname, dname)], mname, priority)
+		return nil
+	})
+	return ferror("fadddf", fname, "", err)
+}
+
+// fdeldf - take a demon away from a frame
+// requires that fframes[fname][fname,dname] holds mname
+// modifies fframes[fname][fname,dname]
+func (kb *KnowledgeBase) Fdeldf(fname, dname, mname string) bool {
+	return kb.FdeldfE(fname, dname, mname) == nil
+}
+
+// fdeldfe - take a demon away from a frame, returning an error
+func (kb *KnowledgeBase) FdeldfE(fname, dname, mname string) error {
+	err := kb.writef(fname, func(f Frame) error {
+		lista, ok := f[fdemonk(fname, dname)]
+		if !ok || !Fmember(fdemonsf, dname) || !fhasdl(lista, mname) {
+			return ErrFacetNotFound
+		}
+		listx := fdeldl(lista, mname)
+		if len(fmethodl(listx)) == 0 {
+			delete(f, fdemonk(fname, dname))
+		} else {
+			f[fdemonk(fname, dname)] = listx
+		}
+		return nil
+	})
+	return ferror("fdeldf", fname, "", err)
+}
+
+// flistdf - list the demons of a frame and then those of its slots
+// requires that fframes[fname] exists
+func (kb *KnowledgeBase) Flistdf(fname string) []Demon {
+	demons, _ := kb.FlistdfE(fname)
+	return demons
+}
+
+// flistdfe - list the demons of a frame and its slots, returning an error
+func (kb *KnowledgeBase) FlistdfE(fname string) ([]Demon, error) {
+	demons := []Demon{}
+	if !kb.readf(fname, func(f Frame) {
+		demons = flistdf(f, fname)
+	}) {
+		return demons, ferror("flistdf", fname, "", ErrFrameNotFound)
+	}
+	return demons, nil
+}
+
+// flistdf - demons of a frame map and its slots
+func flistdf(f Frame, fname string) []Demon {
+	demons := []Demon{}
+	for _, i := range fdemonsf {
+		for _, j := range fdemonl(f[fdemonk(fname, i)]) {
+			demons = append(demons, Demon{fname, "", i, j.Method, j.Priority})
+		}
+	}
+	for _, i := range f[fname+",slots"] {
+		demons = append(demons, flistds(f, fname, i)...)
+	}
+	return demons
+}
+
+// fslistd - list the demons of a frameset and then those of its members
+// requires that fframes[name] exists
+func (kb *KnowledgeBase) Fslistd(name string) []Demon {
+	demons, _ := kb.FslistdE(name)
+	return demons
+}
+
+// fslistde - list the demons of a frameset and its members, returning an
+// error
+func (kb *KnowledgeBase) FslistdE(name string) ([]Demon, error) {
+	set, err := kb.FslistfE(name)
+	if err != nil {
+		return []Demon{}, ferror("fslistd", name, "", err)
+	}
+	demons := []Demon{}
+	for _, i := range append([]string{name}, set...) {
+		x, _ := kb.FlistdfE(i)
+		demons = append(demons, x...)
+	}
+	return demons, nil
+}
@@ -0,0 +1,179 @@
+package framesets2
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mdkb - a knowledge base with methods a, b, c and a@5, each noting its
+// name and the demon it is called for in log, veto, failing, up,
+// putting the new value in upper case, and ex, adding an exclamation
+// mark; frame f has a value slot x
+func mdkb(log *[]string) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	for _, mname := range []string{"a", "b", "c", "a@5"} {
+		mname := mname
+		kb.Fcreatex(mname)
+		kb.Fputxm(mname, func(c *MethodContext) (any, error) {
+			*log = append(*log, mname+":"+c.Op)
+			return nil, nil
+		})
+	}
+	kb.Fcreatex("veto")
+	kb.Fputxm("veto", func(c *MethodContext) (any, error) {
+		return nil, errors.New("veto")
+	})
+	kb.Fcreatex("up")
+	kb.Fputxm("up", func(c *MethodContext) (any, error) {
+		return strings.ToUpper(Getval(c.New)), nil
+	})
+	kb.Fcreatex("ex")
+	kb.Fputxm("ex", func(c *MethodContext) (any, error) {
+		return Getval(c.New) + "!", nil
+	})
+	kb.Fcreatef("f")
+	kb.Fcreates("f", "x")
+	kb.Fcreatev("f", "x")
+	return kb
+}
+
+// fdemons - a list of demons, each a method and a priority
+type fdemons []struct {
+	mname    string
+	priority int
+}
+
+func TestMultiDemons(t *testing.T) {
+	tests := []struct {
+		name   string
+		add    fdemons
+		change func(kb *KnowledgeBase)
+		want   []string
+		facet  []string
+	}{
+		{"by priority", fdemons{{"a", 5}, {"b", -1}, {"c", 5}}, func(kb *KnowledgeBase) {},
+			[]string{"b:ifputv", "a:ifputv", "c:ifputv"}, []string{"a", "5", "b", "-1", "c", "5"}},
+		{"added again", fdemons{{"a", 5}, {"b", -1}, {"c", 5}, {"c", -5}}, func(kb *KnowledgeBase) {},
+			[]string{"c:ifputv", "b:ifputv", "a:ifputv"}, []string{"a", "5", "b", "-1", "c", "-5"}},
+		{"failing", fdemons{{"a", 5}, {"veto", 1}, {"b", -1}}, func(kb *KnowledgeBase) {},
+			[]string{"b:ifputv"}, []string{"a", "5", "veto", "1", "b", "-1"}},
+		{"method names with @", fdemons{{"a@5", 1}, {"a", 0}}, func(kb *KnowledgeBase) {},
+			[]string{"a:ifputv", "a@5:ifputv"}, []string{"a@5", "1", "a", "0"}},
+		{"fputd", fdemons{{"a", 5}, {"b", -1}}, func(kb *KnowledgeBase) { kb.Fputd("f", "x", "ifputv", "c") },
+			[]string{"b:ifputv", "c:ifputv"}, []string{"c", "0", "b", "-1"}},
+		{"fdeld", fdemons{{"a", 5}, {"b", -1}}, func(kb *KnowledgeBase) { kb.Fdeld("f", "x", "ifputv", "b") },
+			[]string{"a:ifputv"}, []string{"a", "5"}},
+	}
+	for _, tt := range tests {
+		log := []string{}
+		kb := mdkb(&log)
+		for _, d := range tt.add {
+			if err := kb.FadddE("f", "x", "ifputv", d.mname, d.priority); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		tt.change(kb)
+		kb.Fputv("f", "x", "v")
+		if !reflect.DeepEqual(log, tt.want) {
+			t.Errorf("%s: called %q, want %q", tt.name, log, tt.want)
+		}
+		if got := kb.slot("f", "x")["ifputv"]; !reflect.DeepEqual(got, tt.facet) {
+			t.Errorf("%s: facet %q, want %q", tt.name, got, tt.facet)
+		}
+	}
+}
+
+func TestMultiDemonList(t *testing.T) {
+	log := []string{}
+	kb := mdkb(&log)
+	kb.Faddd("f", "x", "ifputv", "a", 5)
+	kb.Faddd("f", "x", "ifputv", "c", -5)
+	kb.Fputneeded("f", "x", "b", true)
+	want := []Demon{{"f", "x", "ifputv", "c", -5}, {"f", "x", "ifputv", "a", 5}, {"f", "x", "ifneeded", "b", 0}}
+	if got := kb.Flistd("f", "x"); !reflect.DeepEqual(got, want) {
+		t.Errorf("flistd: got %v, want %v", got, want)
+	}
+	if got := kb.Fgetd("f", "x", "ifputv"); got != "c" {
+		t.Errorf("fgetd: got %s, want c", got)
+	}
+	if got := kb.Fgetd("f", "x", "ifneeded"); got != "b" {
+		t.Errorf("fgetd ifneeded: got %s, want b", got)
+	}
+}
+
+func TestMultiDemonErrors(t *testing.T) {
+	log := []string{}
+	kb := mdkb(&log)
+	kb.Faddd("f", "x", "ifputv", "a", 0)
+	tests := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{"unknown demon", func() error { return kb.FadddE("f", "x", "bogus", "a", 0) }, ErrFacetNotFound},
+		{"ifneeded", func() error { return kb.FadddE("f", "x", "ifneeded", "a", 0) }, ErrFacetNotFound},
+		{"missing method", func() error { return kb.FdeldE("f", "x", "ifputv", "b") }, ErrFacetNotFound},
+		{"last method", func() error { return kb.FdeldE("f", "x", "ifputv", "a") }, nil},
+		{"method taken away", func() error { return kb.FdeldE("f", "x", "ifputv", "a") }, ErrFacetNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if kb.Fexistd("f", "x", "ifputv") {
+		t.Error("facet kept after its last demon")
+	}
+}
+
+func TestMultiDemonRewrite(t *testing.T) {
+	log := []string{}
+	kb := mdkb(&log)
+	kb.Faddd("f", "x", "beforeputv", "ex", 2)
+	kb.Faddd("f", "x", "beforeputv", "up", 1)
+	kb.Fputv("f", "x", "hi")
+	if got := kb.Fgetv("f", "x"); got != "HI!" {
+		t.Errorf("got %s, want HI!", got)
+	}
+}
+
+func TestMultiDemonFrames(t *testing.T) {
+	log := []string{}
+	kb := mdkb(&log)
+	kb.Fcreatefs("set")
+	kb.Fadddf("set", "ifincludef", "b", 0)
+	kb.Fadddf("set", "ifincludef", "a", -1)
+	kb.Fadddf("f", "ifincludef", "c", 0)
+	kb.Fsincludef("set", "f")
+	if want := []string{"c:ifincludef", "a:ifincludef", "b:ifincludef"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("called %q, want %q", log, want)
+	}
+	if got := kb.Fslistd("set"); len(got) != 3 {
+		t.Errorf("fslistd: got %v", got)
+	}
+	if got := kb.Fgetdf("set", "ifincludef"); got != "a" {
+		t.Errorf("fgetdf: got %q, want a", got)
+	}
+	if got, want := kb.copyf("set")[fdemonk("set", "ifincludef")], []string{"b", "0", "a", "-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("facet: got %q, want %q", got, want)
+	}
+	data, _ := kb.Fexportf("set")
+	if !strings.Contains(string(data), `"ifincludef":["b","0","a","-1"]`) {
+		t.Errorf("json: %s", data)
+	}
+	kb2 := NewKnowledgeBase()
+	kb2.Fimportf(data)
+	if got := kb2.Flistdf("set"); !reflect.DeepEqual(got, kb.Flistdf("set")) {
+		t.Errorf("json: got %v", got)
+	}
+	kb.Fdeldf("set", "ifincludef", "a")
+	kb.Fdeldf("set", "ifincludef", "b")
+	if _, ok := kb.copyf("set")[fdemonk("set", "ifincludef")]; ok {
+		t.Error("frame demon kept after its last method")
+	}
+	if kb.Fdeldf("set", "ifincludef", "b") {
+		t.Error("fdeldf: took away a missing demon")
+	}
+}
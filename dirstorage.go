@@ -0,0 +1,258 @@
+/**********************************************************************
+ *
+ * file name:    dirstorage.go
+ * description:  frames stored in a directory, one file per frame
+ *
+ * Frame names are encoded as file names, so any frame name is safe to
+ * store: letters, digits, "-", "_" and "." are kept, any other byte is
+ * written as "%" and two hex digits, as is a leading ".", so "../x"
+ * becomes "%2E.%2Fx". Files starting with "." are never frames, which
+ * leaves them for temporary files. A plain frame name like "car" is its
+ * own file name, so frames stored before keep loading. A frame stored
+ * before under any other name, such as "100%" or "a b", was written to
+ * a file of that very name; it is loaded from there when the encoded
+ * file does not exist, and listed and removed along with it. This holds
+ * only for names which stay inside the directory, do not start with "."
+ * and are not the encoded name of another frame, so "x%2Fy" never
+ * reaches the file of "x/y".
+ *
+ *							Functions
+ *
+ * NewDirStorage			create a storage directory
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DirStorage - a directory holding one file per frame
+type DirStorage struct {
+	dir string
+}
+
+// NewDirStorage - create a storage directory rooted at dir
+// the directory is created when the first frame is stored
+func NewDirStorage(dir string) *DirStorage {
+	return &DirStorage{dir: dir}
+}
+
+// fencodef - file name of a frame
+func fencodef(fname string) string {
+	if fname == "" {
+		return "%"
+	}
+	var b strings.Builder
+	for i := 0; i < len(fname); i++ {
+		c := fname[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' && i > 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// fdecodef - frame name of a file, false if the file is not a frame
+func fdecodef(file string) (string, bool) {
+	if file == "%" {
+		return "", true
+	}
+	fname, err := url.PathUnescape(file)
+	if err != nil || fencodef(fname) != file {
+		return "", false
+	}
+	return fname, true
+}
+
+// Dir - the directory holding the frames
+func (d *DirStorage) Dir() string {
+	return d.dir
+}
+
+// path - path of the file holding a frame
+func (d *DirStorage) path(fname string) string {
+	return filepath.Join(d.dir, fencodef(fname))
+}
+
+// legacy - path of the file a frame was stored in before frame names
+// were encoded, "" if it is outside the directory, hidden, or the file
+// of a frame stored since
+func (d *DirStorage) legacy(fname string) string {
+	if _, ok := fdecodef(fname); ok || !filepath.IsLocal(fname) ||
+		strings.HasPrefix(fname, ".") || strings.Contains(fname, "/.") {
+		return ""
+	}
+	return filepath.Join(d.dir, fname)
+}
+
+// Load - read a frame
+// a set of frames interrupted while stored is finished or undone first
+func (d *DirStorage) Load(fname string) (Frame, error) {
+	if err := frecover(d.dir); err != nil {
+		return nil, err
+	}
+	fh, err := os.Open(d.path(fname))
+	if path := d.legacy(fname); errors.Is(err, os.ErrNotExist) && path != "" {
+		fh, err = os.Open(path)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFrameNotFound
+	}
+	if err != nil {
+		return nil, ioerror(err)
+	}
+	defer fh.Close()
+	return fdecode(fh, fname)
+}
+
+// LoadAll - read a list of frames, failing if any of them fails
+func (d *DirStorage) LoadAll(fnames []string) ([]Frame, error) {
+	frames := []Frame{}
+	for _, i := range fnames {
+		x, err := d.Load(i)
+		if err != nil {
+			return nil, ferror("load", i, "", err)
+		}
+		frames = append(frames, x)
+	}
+	return frames, nil
+}
+
+// Store - write a frame, replacing it atomically
+// a set of frames interrupted while stored is finished or undone first
+func (d *DirStorage) Store(fname string, x Frame) error {
+	if err := frecover(d.dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return ioerror(err)
+	}
+	return fwritef(d.path(fname), x)
+}
+
+// StoreAll - write a list of frames, either all of them or none
+// a set of frames interrupted while stored is finished or undone first
+func (d *DirStorage) StoreAll(fnames []string, frames []Frame) error {
+	if err := frecover(d.dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return ioerror(err)
+	}
+	paths := make([]string, len(fnames))
+	for i, fname := range fnames {
+		paths[i] = d.path(fname)
+	}
+	return fwritel(paths, frames)
+}
+
+// Delete - remove a stored frame
+// a legacy file of the frame is removed as well
+func (d *DirStorage) Delete(fname string) error {
+	paths := []string{d.path(fname)}
+	if legacy := d.legacy(fname); legacy != "" {
+		paths = append(paths, legacy)
+	}
+	found := false
+	for _, path := range paths {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return ioerror(err)
+		}
+		found = true
+		fsyncd(path)
+	}
+	if !found {
+		return ErrFrameNotFound
+	}
+	return nil
+}
+
+// DeleteAll - remove a list of stored frames, as many as can be
+func (d *DirStorage) DeleteAll(fnames []string) error {
+	errs := []error{}
+	for _, i := range fnames {
+		if err := d.Delete(i); err != nil {
+			errs = append(errs, ferror("delete", i, "", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// List - names of the stored frames, in sorted order
+func (d *DirStorage) List() ([]string, error) {
+	if err := frecover(d.dir); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(d.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, ioerror(err)
+	}
+	frames := []string{}
+	for _, i := range files {
+		if i.Type().IsRegular() {
+			if fname, ok := fdecodef(i.Name()); ok {
+				frames = append(frames, fname)
+			}
+		}
+	}
+	legacy, err := d.legacyl()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range legacy {
+		if !slices.Contains(frames, i) {
+			frames = append(frames, i)
+		}
+	}
+	sort.Strings(frames)
+	return frames, nil
+}
+
+// legacyl - names of the frames stored in legacy files
+func (d *DirStorage) legacyl() ([]string, error) {
+	frames := []string{}
+	err := filepath.WalkDir(d.dir, func(path string, i fs.DirEntry, err error) error {
+		if err != nil || path == d.dir {
+			return err
+		}
+		fname, err := filepath.Rel(d.dir, path)
+		if err != nil {
+			return err
+		}
+		fname = filepath.ToSlash(fname)
+		if i.IsDir() {
+			if strings.HasPrefix(i.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if i.Type().IsRegular() && d.legacy(fname) != "" {
+			frames = append(frames, fname)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ioerror(err)
+	}
+	return frames, nil
+}
@@ -0,0 +1,116 @@
+package framesets2
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDirStorageNames(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "kb")
+	kb := NewKnowledgeBase()
+	kb.SetStorage(NewDirStorage(dir))
+	tests := []struct {
+		fname string
+		file  string
+	}{
+		{"car", "car"},
+		{"../x", "%2E.%2Fx"},
+		{"a/b", "a%2Fb"},
+		{".hidden", "%2Ehidden"},
+		{"", "%"},
+		{"100%", "100%25"},
+	}
+	for _, tt := range tests {
+		kb.Fcreatef(tt.fname)
+		if err := kb.FstorefE(tt.fname); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, tt.file)); err != nil {
+			t.Errorf("%q: %v", tt.fname, err)
+		}
+	}
+	got, err := kb.Fstored()
+	if want := []string{"", "../x", ".hidden", "100%", "a/b", "car"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("fstored: got %q, %v, want %q", got, err, want)
+	}
+	kb2 := NewKnowledgeBase()
+	kb2.SetStorage(NewDirStorage(dir))
+	if err := kb2.FloadfE("../x"); err != nil {
+		t.Error(err)
+	}
+	if err := kb2.FunstorefE("../x"); err != nil {
+		t.Error(err)
+	}
+	if err := kb2.FunstorefE("../x"); !errors.Is(err, ErrFrameNotFound) {
+		t.Errorf("funstoref again: got %v, want %v", err, ErrFrameNotFound)
+	}
+}
+
+func TestDirStorageLegacy(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "a"), 0755)
+	for _, i := range []string{"100%", "a b", "a/b", ".a.tmp1"} {
+		os.WriteFile(filepath.Join(dir, i), []byte(i+",slots s\ns,facets value\ns,value old\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(filepath.Dir(dir), "outside"), []byte("outside,slots\n"), 0644)
+	d := NewDirStorage(dir)
+	tests := []struct {
+		fname string
+		err   error
+	}{
+		{"100%", nil},
+		{"a b", nil},
+		{"a/b", nil},
+		{"../outside", ErrFrameNotFound},
+		{".a.tmp1", ErrFrameNotFound},
+		{"a%2Fb", ErrFrameNotFound},
+		{"none", ErrFrameNotFound},
+	}
+	for _, tt := range tests {
+		x, err := d.Load(tt.fname)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("load %q: got %v, want %v", tt.fname, err, tt.err)
+		}
+		if err == nil && Getval(x["s,value"]) != "old" {
+			t.Errorf("load %q: got %v", tt.fname, x)
+		}
+	}
+	if got, err := d.List(); !slices.Equal(got, []string{"100%", "a b", "a/b"}) {
+		t.Errorf("list: got %q, %v", got, err)
+	}
+	// storing writes the encoded file, which is loaded from then on
+	d.Store("a b", fvframe("a b", "new"))
+	if x, _ := d.Load("a b"); Getval(x["s,value"]) != "new" {
+		t.Errorf("load after store: got %v", x)
+	}
+	if got, _ := d.List(); !slices.Equal(got, []string{"100%", "a b", "a/b"}) {
+		t.Errorf("list after store: got %q", got)
+	}
+	// deleting removes the encoded file and the legacy one
+	for _, fname := range []string{"100%", "a b"} {
+		if err := d.Delete(fname); err != nil {
+			t.Errorf("delete %q: %v", fname, err)
+		}
+		if _, err := d.Load(fname); !errors.Is(err, ErrFrameNotFound) {
+			t.Errorf("load %q after delete: got %v", fname, err)
+		}
+	}
+	for _, file := range []string{"100%", "a b", "a%20b"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("file %q left: %v", file, err)
+		}
+	}
+	// the encoded name of a frame never reaches the file of another
+	d.Store("x/y", fvframe("x/y", "new"))
+	for _, fname := range []string{"x%2Fy", ".a.tmp1"} {
+		if err := d.Delete(fname); !errors.Is(err, ErrFrameNotFound) {
+			t.Errorf("delete %q: got %v, want %v", fname, err, ErrFrameNotFound)
+		}
+	}
+	if x, _ := d.Load("x/y"); Getval(x["s,value"]) != "new" {
+		t.Errorf("x/y after deleting x%%2Fy: got %v", x)
+	}
+}
@@ -0,0 +1,92 @@
+/**********************************************************************
+ *
+ * file name:    errors.go
+ * description:  errors returned by the frame functions
+ *
+ * Every function which returns a bool or an empty string on failure
+ * has a variant ending in E which returns an error instead. The error
+ * is a *FrameError saying where the failure happened, wrapping one of
+ * the Err values below, so callers can use errors.Is and errors.As.
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrFrameNotFound  = errors.New("frame not found")
+	ErrFrameExists    = errors.New("frame already exists")
+	ErrSlotNotFound   = errors.New("slot not found")
+	ErrSlotExists     = errors.New("slot already exists")
+	ErrFacetNotFound  = errors.New("facet not found")
+	ErrFacetExists    = errors.New("facet already exists")
+	ErrFacetConflict  = errors.New("slot holds a conflicting facet")
+	ErrMethodNotFound = errors.New("method not found")
+	ErrMethodExists   = errors.New("method already exists")
+	ErrNilMethod      = errors.New("method is nil")
+	ErrDemonMissing   = errors.New("demon method not found")
+	ErrValueNotFound  = errors.New("value not found")
+	ErrIndexRange     = errors.New("index out of range")
+	ErrType           = errors.New("value does not match the slot type")
+	ErrNotMember      = errors.New("frame is not a member of the frameset")
+	ErrIO             = errors.New("i/o error")
+	ErrFormat         = errors.New("malformed frame data")
+	ErrNoTransaction  = errors.New("no transaction in progress")
+	ErrConflict       = errors.New("frame changed concurrently")
+	ErrNoHistory      = errors.New("nothing to undo or redo")
+	ErrMarkNotFound   = errors.New("mark not found")
+	ErrBusy           = errors.New("history is in use")
+	ErrReadOnly       = errors.New("snapshot cannot be changed")
+	ErrCycle          = errors.New("frames form a cycle")
+	ErrOrder          = errors.New("parents have no consistent order")
+	ErrConstraint     = errors.New("value breaks a slot constraint")
+	ErrDepth          = errors.New("nesting limit reached")
+	ErrPanic          = errors.New("method panicked")
+)
+
+// FrameError - an error together with the operation, frame and slot
+// in which it happened
+type FrameError struct {
+	Op    string
+	Frame string
+	Slot  string
+	Err   error
+}
+
+func (e *FrameError) Error() string {
+	where := e.Frame
+	if e.Slot != "" {
+		where += "." + e.Slot
+	}
+	if where == "" {
+		return "framesets2: " + e.Op + ": " + e.Err.Error()
+	}
+	return "framesets2: " + e.Op + " " + where + ": " + e.Err.Error()
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// ferror - wrap an error in a FrameError
+// an error which already is a FrameError is returned as is, so a
+// failure in a referenced frame keeps naming that frame
+func ferror(op, fname, sname string, err error) error {
+	var fe *FrameError
+	if err == nil || errors.As(err, &fe) {
+		return err
+	}
+	return &FrameError{Op: op, Frame: fname, Slot: sname, Err: err}
+}
+
+// ioerror - mark an error from the os package as ErrIO
+func ioerror(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrIO, err)
+}
@@ -0,0 +1,86 @@
+package framesets2
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("x")
+	kb.Fcreates("x", "m")
+	kb.Fcreatem("x", "m")
+	kb.Fcreates("x", "v")
+	kb.Fcreatev("x", "v")
+	kb.Fcreated("x", "v", "ifputv")
+	kb.Fputd("x", "v", "ifputv", "nope")
+	tests := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{"no frame", func() error { return errv(kb.FgetvE("y", "s")) }, ErrFrameNotFound},
+		{"no slot", func() error { return errv(kb.FgetvE("x", "s")) }, ErrSlotNotFound},
+		{"method not value", func() error { return errv(kb.FgetvE("x", "m")) }, ErrFacetConflict},
+		{"missing demon", func() error { return kb.FputvE("x", "v", "1") }, ErrDemonMissing},
+		{"not a member", func() error { return kb.FsexcludefE("x", "y") }, ErrNotMember},
+		{"frame exists", func() error { return kb.FcreatefE("x") }, ErrFrameExists},
+		{"slot exists", func() error { return kb.FcreatesE("x", "v") }, ErrSlotExists},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	var fe *FrameError
+	if err := errv(kb.FgetvE("x", "m")); !errors.As(err, &fe) || fe.Op != "fgetv" || fe.Frame != "x" || fe.Slot != "m" {
+		t.Errorf("FrameError: got %#v", fe)
+	}
+}
+
+func TestRemoveFromSnapshot(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("x")
+	kb.Fcreates("x", "s")
+	kb.Fcreatev("x", "s")
+	kb.Fcreates("x", "r")
+	kb.Fcreater("x", "r")
+	kb.Fcreates("x", "m")
+	kb.Fcreatem("x", "m")
+	snap := kb.Snapshot()
+	defer snap.Release()
+	tests := []struct {
+		name   string
+		remove func() error
+		exist  func() bool
+	}{
+		{"fremovev", func() error { return snap.FremovevE("x", "s") }, func() bool { return snap.Fexistv("x", "s") }},
+		{"fremover", func() error { return snap.FremoverE("x", "r") }, func() bool { return snap.Fexistr("x", "r") }},
+		{"fremovem", func() error { return snap.FremovemE("x", "m") }, func() bool { return snap.Fexistm("x", "m") }},
+	}
+	for _, tt := range tests {
+		if err := tt.remove(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, ErrReadOnly)
+		}
+		if !tt.exist() {
+			t.Errorf("%s: facet removed from snapshot", tt.name)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	kb := NewKnowledgeBase()
+	var fe *FrameError
+	if err := kb.FloadfE(filepath.Join(t.TempDir(), "nosuch")); !errors.As(err, &fe) || fe.Op != "floadf" {
+		t.Errorf("floadf: got %v", err)
+	}
+	if kb.Floadf(filepath.Join(t.TempDir(), "nosuch")) {
+		t.Error("floadf: loaded a missing frame")
+	}
+}
+
+// errv - the error of a command returning a value
+func errv[T any](_ T, err error) error {
+	return err
+}
@@ -0,0 +1,381 @@
+/**********************************************************************
+ *
+ * file name:    files.go
+ * description:  crash-safe writing of frame files
+ *
+ * A frame file is never written in place. The frame is written to a
+ * temporary file in the same directory, which is synced to disk and
+ * then renamed over the frame file, so after a crash or a full disk the
+ * file holds either the old frame or the new one, never a part of it.
+ *
+ * A list of frames, as stored by Fstorefs, is written as a set. The
+ * set starts with a manifest, a file named .fwset-<id> in the same
+ * directory, and every frame is written to a synced temporary file
+ * .fwset-<id>-<n>, next to a link to the old frame file, if any, kept
+ * to put back. Once all of them are written, the manifest is given the
+ * names of the files and a commit record and is synced: this is the
+ * point at which the set happens. Only then are the temporary files
+ * renamed over the frame files, after which the manifest and the rest
+ * of the set are removed:
+ *
+ *	"framesets2 set 1"
+ *	"f" "car" ".fwset-123-0" ".fwset-123-0.old"
+ *	"f" "bus" ".fwset-123-1" ""
+ *	"c"
+ *
+ * If a rename fails, an undo record is added to the manifest and the
+ * old frame files are put back. A set interrupted by a crash is found
+ * the first time frames are loaded or listed from its directory: one
+ * without a commit record is thrown away, leaving the old frames; one
+ * with a commit record is finished; one with an undo record is undone.
+ * So after a crash either every frame of the set is new, or none is.
+ *
+ *							Functions
+ *
+ * fbegin					start writing a set of frame files
+ * fcreate					write a synced file of a given name
+ * frecover					finish or undo the sets interrupted in a directory
+ * fsyncd					sync a directory to disk
+ * ftemp					write a synced temporary file
+ * fwritef					write a frame file atomically
+ * fwritel					write a list of frame files, all or nothing
+ * fwritet					write a frame to a synced temporary file
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fsetv - version of the manifest of a set
+const fsetv = 1
+
+var (
+	// fwmu - held while a set is written or recovered
+	fwmu sync.Mutex
+	// frecovered - directories already checked for interrupted sets
+	frecovered = make(map[string]bool)
+	// frename - renames the files of a set, replaced by tests to fail
+	frename = os.Rename
+)
+
+// ftemp - write a synced temporary file next to path
+// returns the name of the temporary file
+func ftemp(path string, fn func(io.Writer) error) (string, error) {
+	fh, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", ioerror(err)
+	}
+	return fsynced(fh, fn)
+}
+
+// fcreate - write a synced file of a given name, which must not exist
+func fcreate(path string, fn func(io.Writer) error) error {
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return ioerror(err)
+	}
+	_, err = fsynced(fh, fn)
+	return err
+}
+
+// fsynced - write a new file, sync it and close it
+// the file is removed if any of it fails
+func fsynced(fh *os.File, fn func(io.Writer) error) (string, error) {
+	err := fn(fh)
+	if err == nil {
+		err = fh.Sync()
+	}
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(fh.Name())
+		return "", ioerror(err)
+	}
+	return fh.Name(), nil
+}
+
+// fwritet - write a frame to a synced temporary file next to path
+func fwritet(path string, x Frame) (string, error) {
+	return ftemp(path, func(w io.Writer) error {
+		return fencode(w, x)
+	})
+}
+
+// fsyncd - sync the directory holding path, so a rename in it is on disk
+// not every system can sync a directory, so failure is ignored
+func fsyncd(path string) {
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// fwritef - write a frame file atomically
+func fwritef(path string, x Frame) error {
+	tmp, err := fwritet(path, x)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return ioerror(err)
+	}
+	fsyncd(path)
+	return nil
+}
+
+// fwset - a set of frame files being written
+// paths are the frame files, tmps the new files and olds the links to
+// the old files, "" for a frame file which did not exist
+type fwset struct {
+	dir   string
+	mark  string
+	paths []string
+	tmps  []string
+	olds  []string
+}
+
+// fbegin - start writing a set of frame files, all in one directory
+// the manifest, the new files and the links to the old files are
+// written, but nothing is committed
+func fbegin(paths []string, frames []Frame) (*fwset, error) {
+	w := &fwset{dir: filepath.Dir(paths[0])}
+	fh, err := os.CreateTemp(w.dir, ".fwset-*")
+	if err != nil {
+		return nil, ioerror(err)
+	}
+	fh.Close()
+	w.mark = fh.Name()
+	fsyncd(w.mark)
+	for i, path := range paths {
+		if filepath.Dir(path) != w.dir {
+			w.discard()
+			return nil, ioerror(fmt.Errorf("%s is not in %s", path, w.dir))
+		}
+		tmp := fmt.Sprintf("%s-%d", w.mark, i)
+		if err := fcreate(tmp, func(wr io.Writer) error {
+			return fencode(wr, frames[i])
+		}); err != nil {
+			w.discard()
+			return nil, err
+		}
+		old := tmp + ".old"
+		if err := os.Link(path, old); errors.Is(err, os.ErrNotExist) {
+			old = ""
+		} else if err != nil {
+			// no hard links here, so the old file is copied
+			if err = fcreate(old, func(wr io.Writer) error {
+				b, err := os.ReadFile(path)
+				if err == nil {
+					_, err = wr.Write(b)
+				}
+				return err
+			}); err != nil {
+				w.discard()
+				return nil, err
+			}
+		}
+		w.paths = append(w.paths, path)
+		w.tmps = append(w.tmps, tmp)
+		w.olds = append(w.olds, old)
+	}
+	return w, nil
+}
+
+// record - append records to the manifest and sync it
+func (w *fwset) record(lines ...string) error {
+	fh, err := os.OpenFile(w.mark, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return ioerror(err)
+	}
+	_, err = fh.WriteString(strings.Join(lines, ""))
+	if err == nil {
+		err = fh.Sync()
+	}
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	return ioerror(err)
+}
+
+// commit - write the files of the set and a commit record to the manifest
+func (w *fwset) commit() error {
+	lines := []string{fencodel("framesets2 set "+strconv.Itoa(fsetv), nil)}
+	for i := range w.paths {
+		old := ""
+		if w.olds[i] != "" {
+			old = filepath.Base(w.olds[i])
+		}
+		lines = append(lines, fencodel("f", []string{filepath.Base(w.paths[i]), filepath.Base(w.tmps[i]), old}))
+	}
+	return w.record(append(lines, fencodel("c", nil))...)
+}
+
+// finish - rename the new files of a committed set over the frame files
+// and remove the rest of the set
+// a new file already renamed is skipped, so a set can be finished again
+func (w *fwset) finish() error {
+	for i, path := range w.paths {
+		if _, err := os.Lstat(w.tmps[i]); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := frename(w.tmps[i], path); err != nil {
+			return ioerror(err)
+		}
+	}
+	fsyncd(w.mark)
+	w.discard()
+	return nil
+}
+
+// undo - put back the old frame files of a set and remove the rest
+// an old file already put back is skipped, so a set can be undone again
+func (w *fwset) undo() error {
+	for i, path := range w.paths {
+		var err error
+		if w.olds[i] == "" {
+			err = os.Remove(path)
+		} else if _, err = os.Lstat(w.olds[i]); err == nil {
+			err = frename(w.olds[i], path)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return ioerror(err)
+		}
+	}
+	fsyncd(w.mark)
+	w.discard()
+	return nil
+}
+
+// discard - remove the manifest of a set and the files left of it
+func (w *fwset) discard() {
+	prefix := filepath.Base(w.mark) + "-"
+	if files, err := os.ReadDir(w.dir); err == nil {
+		for _, i := range files {
+			if strings.HasPrefix(i.Name(), prefix) {
+				os.Remove(filepath.Join(w.dir, i.Name()))
+			}
+		}
+	}
+	os.Remove(w.mark)
+	fsyncd(w.mark)
+}
+
+// fwritel - write a list of frame files in one directory, either all
+// of them or none
+func fwritel(paths []string, frames []Frame) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	fwmu.Lock()
+	defer fwmu.Unlock()
+	w, err := fbegin(paths, frames)
+	if err != nil {
+		return err
+	}
+	if err := w.commit(); err != nil {
+		w.discard()
+		return err
+	}
+	if err := w.finish(); err != nil {
+		if w.record(fencodel("u", nil)) == nil {
+			w.undo()
+		}
+		return err
+	}
+	return nil
+}
+
+// fread - read the manifest of a set
+// returns whether the set was committed and whether it is being undone
+func fread(mark string) (*fwset, bool, bool, error) {
+	fh, err := os.Open(mark)
+	if err != nil {
+		return nil, false, false, ioerror(err)
+	}
+	defer fh.Close()
+	w := &fwset{dir: filepath.Dir(mark), mark: mark}
+	committed, undone := false, false
+	reader := bufio.NewReader(fh)
+	for n := 0; ; n++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// a torn last line was never synced, so it is not there
+			break
+		}
+		kind, items, err := fdecodel(strings.TrimSuffix(line, "\n"))
+		switch {
+		case err != nil:
+			return nil, false, false, err
+		case n == 0:
+			if kind != "framesets2 set "+strconv.Itoa(fsetv) {
+				return nil, false, false, fmt.Errorf("%w: %s is not a set of a known version", ErrFormat, mark)
+			}
+		case kind == "f" && len(items) == 3 && !committed:
+			w.paths = append(w.paths, filepath.Join(w.dir, items[0]))
+			w.tmps = append(w.tmps, filepath.Join(w.dir, items[1]))
+			if items[2] != "" {
+				items[2] = filepath.Join(w.dir, items[2])
+			}
+			w.olds = append(w.olds, items[2])
+		case kind == "c" && len(items) == 0:
+			committed = true
+		case kind == "u" && len(items) == 0 && committed:
+			undone = true
+		default:
+			return nil, false, false, fmt.Errorf("%w: bad record in %s", ErrFormat, mark)
+		}
+	}
+	return w, committed, undone, nil
+}
+
+// frecover - finish or undo the sets interrupted in a directory
+// a directory is checked once
+func frecover(dir string) error {
+	fwmu.Lock()
+	defer fwmu.Unlock()
+	key, err := filepath.Abs(dir)
+	if err != nil {
+		key = dir
+	}
+	if frecovered[key] {
+		return nil
+	}
+	marks, err := filepath.Glob(filepath.Join(dir, ".fwset-*"))
+	if err != nil {
+		return ioerror(err)
+	}
+	for _, mark := range marks {
+		if strings.Contains(filepath.Base(mark)[len(".fwset-"):], "-") {
+			continue
+		}
+		w, committed, undone, err := fread(mark)
+		switch {
+		case err != nil:
+			return err
+		case undone:
+			err = w.undo()
+		case committed:
+			err = w.finish()
+		default:
+			w.discard()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	frecovered[key] = true
+	return nil
+}
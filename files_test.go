@@ -0,0 +1,206 @@
+package framesets2
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// fvframe - a frame with one value
+func fvframe(fname, value string) Frame {
+	return Frame{fname + ",slots": {"s"}, "s,facets": {"value"}, "s,value": {value}}
+}
+
+// freadf - the frame in a file, nil if it cannot be read
+func freadf(path string) Frame {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer fh.Close()
+	x, _ := fdecode(fh, filepath.Base(path))
+	return x
+}
+
+func TestWriteFrame(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a")
+	for _, value := range []string{"old", "new"} {
+		if err := fwritef(path, fvframe("a", value)); err != nil {
+			t.Fatal(err)
+		}
+		if got := Getval(freadf(path)["s,value"]); got != value {
+			t.Errorf("got %q, want %q", got, value)
+		}
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("files left %v", files)
+	}
+	if err := fwritef(filepath.Join(dir, "none", "a"), fvframe("a", "x")); !errors.Is(err, ErrIO) {
+		t.Errorf("missing directory: got %v, want %v", err, ErrIO)
+	}
+}
+
+func TestWriteList(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	fwritef(a, fvframe("a", "old"))
+	tests := []struct {
+		name  string
+		paths []string
+		err   error
+		want  []string
+	}{
+		{"second fails", []string{a, filepath.Join(dir, "none", "b")}, ErrIO, []string{"old", ""}},
+		{"both", []string{a, b}, nil, []string{"new", "new"}},
+	}
+	for _, tt := range tests {
+		err := fwritel(tt.paths, []Frame{fvframe("a", "new"), fvframe("b", "new")})
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+		for i, path := range []string{a, b} {
+			if got := Getval(freadf(path)["s,value"]); got != tt.want[i] {
+				t.Errorf("%s: %s got %q, want %q", tt.name, filepath.Base(path), got, tt.want[i])
+			}
+		}
+		stored := len(slices.DeleteFunc(slices.Clone(tt.want), func(s string) bool { return s == "" }))
+		if files, _ := os.ReadDir(dir); len(files) != stored {
+			t.Errorf("%s: files left %v", tt.name, files)
+		}
+	}
+}
+
+func TestWriteSetCrash(t *testing.T) {
+	tests := []struct {
+		name  string
+		crash func(w *fwset)
+		want  []string
+	}{
+		{"before commit", func(w *fwset) {}, []string{"old", ""}},
+		{"after commit", func(w *fwset) { w.commit() }, []string{"new", "new"}},
+		{"after one rename", func(w *fwset) {
+			w.commit()
+			os.Rename(w.tmps[0], w.paths[0])
+		}, []string{"new", "new"}},
+		{"while undoing", func(w *fwset) {
+			w.commit()
+			os.Rename(w.tmps[0], w.paths[0])
+			w.record(fencodel("u", nil))
+		}, []string{"old", ""}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		d := NewDirStorage(dir)
+		d.Store("a", fvframe("a", "old"))
+		w, err := fbegin([]string{d.path("a"), d.path("b")}, []Frame{fvframe("a", "new"), fvframe("b", "new")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.crash(w)
+		// the set is left as the crash left it, and found as if by a
+		// new process
+		fwmu.Lock()
+		clear(frecovered)
+		fwmu.Unlock()
+		for i, fname := range []string{"a", "b"} {
+			x, err := d.Load(fname)
+			if got := Getval(x["s,value"]); got != tt.want[i] || (got == "") != errors.Is(err, ErrFrameNotFound) {
+				t.Errorf("%s: %s got %q, %v, want %q", tt.name, fname, got, err, tt.want[i])
+			}
+		}
+		stored := len(slices.DeleteFunc(slices.Clone(tt.want), func(s string) bool { return s == "" }))
+		if files, _ := os.ReadDir(dir); len(files) != stored {
+			t.Errorf("%s: files left %v", tt.name, files)
+		}
+	}
+}
+
+func TestStoreRecovers(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(d *DirStorage) error
+	}{
+		{"store", func(d *DirStorage) error { return d.Store("a", fvframe("a", "mine")) }},
+		{"storeall", func(d *DirStorage) error {
+			return d.StoreAll([]string{"a"}, []Frame{fvframe("a", "mine")})
+		}},
+	}
+	for _, tt := range tests {
+		d := NewDirStorage(t.TempDir())
+		d.Store("a", fvframe("a", "old"))
+		w, err := fbegin([]string{d.path("a"), d.path("b")}, []Frame{fvframe("a", "new"), fvframe("b", "new")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.commit()
+		fwmu.Lock()
+		clear(frecovered)
+		fwmu.Unlock()
+		// the set interrupted is finished before the frame is stored,
+		// so it cannot overwrite it later
+		if err := tt.store(d); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, i := range []struct{ fname, want string }{{"a", "mine"}, {"b", "new"}} {
+			if x, _ := d.Load(i.fname); Getval(x["s,value"]) != i.want {
+				t.Errorf("%s: %s got %q, want %q", tt.name, i.fname, Getval(x["s,value"]), i.want)
+			}
+		}
+	}
+}
+
+func TestWriteSetRenameFails(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDirStorage(dir)
+	d.Store("a", fvframe("a", "old"))
+	n := 0
+	frename = func(from, to string) error {
+		if n++; n == 2 {
+			return os.ErrPermission
+		}
+		return os.Rename(from, to)
+	}
+	defer func() { frename = os.Rename }()
+	err := d.StoreAll([]string{"a", "b"}, []Frame{fvframe("a", "new"), fvframe("b", "new")})
+	if !errors.Is(err, ErrIO) {
+		t.Errorf("storeall: got %v, want %v", err, ErrIO)
+	}
+	if x, _ := d.Load("a"); Getval(x["s,value"]) != "old" {
+		t.Errorf("a: got %q, want old", Getval(x["s,value"]))
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 || files[0].Name() != "a" {
+		t.Errorf("files left %v", files)
+	}
+}
+
+func TestStoreSetMissingFrame(t *testing.T) {
+	dir := t.TempDir()
+	kb := NewKnowledgeBase()
+	kb.SetStorage(NewDirStorage(dir))
+	kb.Fcreatef("a")
+	kb.Fcreatef("b")
+	kb.Fcreatefs("set")
+	kb.Fsincludef("set", "a")
+	kb.Fsincludef("set", "b")
+	if err := kb.FstorefsE("set"); err != nil {
+		t.Fatal(err)
+	}
+	kb.Fremovef("b")
+	kb.Fcreates("a", "x")
+	if err := kb.FstorefsE("set"); !errors.Is(err, ErrFrameNotFound) {
+		t.Errorf("fstorefs: got %v, want %v", err, ErrFrameNotFound)
+	}
+	kb2 := NewKnowledgeBase()
+	kb2.SetStorage(NewDirStorage(dir))
+	kb2.Floadf("a")
+	if kb2.Fexists("a", "x") {
+		t.Error("part of the set was written")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, ".*")); len(files) != 0 {
+		t.Errorf("files left %v", files)
+	}
+}
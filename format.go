@@ -0,0 +1,152 @@
+/**********************************************************************
+ *
+ * file name:    format.go
+ * description:  on-disk format of a frame
+ *
+ * A stored frame starts with a header line naming the format and its
+ * version, followed by one line per element of the frame map. Each line
+ * holds the element name and its values as Go quoted strings separated
+ * by spaces, so values may contain commas, spaces, quotes and newlines,
+ * and an element without values is written as its name alone:
+ *
+ *	framesets2 frame 2
+ *	"car,slots" "color" "owner"
+ *	"color,facets" "value"
+ *	"color,value" "dark, metallic red"
+ *	"owner,facets" "value"
+ *	"owner,value"
+ *
+ * Files without a header are in the legacy format of version 1, one
+ * "name v1,v2" line per element, and are still read.
+ *
+ *							Functions
+ *
+ * fdecode					read a frame in either format
+ * fdecodel					read a line of the current format
+ * fdecodel1				read a line of the legacy format
+ * fencode					write a frame in the current format
+ * fencodel					write a line of the current format
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormatVersion - version of the format written by Fstoref
+const FormatVersion = 2
+
+// fheader - start of the header line
+const fheader = "framesets2 frame "
+
+// fencodel - write an element of a frame as a line
+func fencodel(aname string, avalue []string) string {
+	var b strings.Builder
+	b.WriteString(strconv.Quote(aname))
+	for _, i := range avalue {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Quote(i))
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// fencode - write a frame with a header, its elements in sorted order
+func fencode(w io.Writer, x Frame) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString(fheader + strconv.Itoa(FormatVersion) + "\n")
+	keys := make([]string, 0, len(x))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writer.WriteString(fencodel(k, x[k]))
+	}
+	return writer.Flush()
+}
+
+// fdecodel - read a line of the current format
+func fdecodel(line string) (string, []string, error) {
+	items := []string{}
+	for rest := line; rest != ""; {
+		q, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: bad quoting in %q", ErrFormat, line)
+		}
+		x, _ := strconv.Unquote(q)
+		items = append(items, x)
+		rest = rest[len(q):]
+		if rest != "" {
+			if rest[0] != ' ' {
+				return "", nil, fmt.Errorf("%w: bad quoting in %q", ErrFormat, line)
+			}
+			rest = rest[1:]
+		}
+	}
+	if len(items) == 0 {
+		return "", nil, fmt.Errorf("%w: empty line", ErrFormat)
+	}
+	return items[0], items[1:], nil
+}
+
+// fdecodel1 - read a line of the legacy format
+// an element written without values is read back as having none
+func fdecodel1(line string) (string, []string) {
+	aname, avalue, _ := strings.Cut(line, " ")
+	if avalue == "" {
+		return aname, []string{}
+	}
+	return aname, strings.Split(avalue, ",")
+}
+
+// fdecode - read a frame in the current or legacy format
+func fdecode(r io.Reader, fname string) (Frame, error) {
+	x := Frame{fname + ",slots": {}}
+	reader := bufio.NewReader(r)
+	version := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, ioerror(err)
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if version == 0 {
+			if v, ok := strings.CutPrefix(line, fheader); ok {
+				n, verr := strconv.Atoi(v)
+				if verr != nil || n < 2 || n > FormatVersion {
+					return nil, fmt.Errorf("%w: unknown version %q", ErrFormat, v)
+				}
+				version = n
+				continue
+			}
+			version = 1
+		}
+		if version == 1 {
+			if line != "" {
+				aname, avalue := fdecodel1(line)
+				x[aname] = avalue
+			}
+		} else if line != "" {
+			aname, avalue, derr := fdecodel(line)
+			if derr != nil {
+				return nil, derr
+			}
+			x[aname] = avalue
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return x, nil
+}
@@ -0,0 +1,72 @@
+package framesets2
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value []string
+	}{
+		{"comma", []string{"x, y"}},
+		{"leading space", []string{" lead"}},
+		{"newline", []string{"new\nline"}},
+		{"quote", []string{`q"uote`}},
+		{"empty string", []string{""}},
+		{"several", []string{"a", "", "b c"}},
+		{"none", []string{}},
+	}
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("a")
+	for _, tt := range tests {
+		kb.Fcreates("a", tt.name)
+		kb.Fcreatev("a", tt.name)
+		kb.Fputvl("a", tt.name, tt.value)
+	}
+	var b bytes.Buffer
+	if err := fencode(&b, kb.copyf("a")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "framesets2 frame 2\n") {
+		t.Errorf("no header in %q", b.String())
+	}
+	x, err := fdecode(&b, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if got := x[tt.name+",value"]; !slices.Equal(got, tt.value) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.value)
+		}
+	}
+}
+
+func TestFormatDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+		want []string
+	}{
+		{"legacy", "b,slots s\ns,facets value\ns,value 1,2\n", nil, []string{"1", "2"}},
+		{"legacy without values", "b,slots s\ns,facets value\ns,value\n", nil, []string{}},
+		{"current", "framesets2 frame 2\n\"b,slots\" \"s\"\n\"s,value\" \"1,2\"\n", nil, []string{"1,2"}},
+		{"unknown version", "framesets2 frame 9\n", ErrFormat, nil},
+		{"bad line", "framesets2 frame 2\n\"x\" y\n", ErrFormat, nil},
+		{"unterminated quote", "framesets2 frame 2\n\"x\n", ErrFormat, nil},
+	}
+	for _, tt := range tests {
+		x, err := fdecode(strings.NewReader(tt.data), "b")
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+		if got := x["s,value"]; tt.want != nil && !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
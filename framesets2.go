@@ -11,6 +11,28 @@
  *     changed: April 16, 1999 (merged frames and framesets)
  *     changed: November 8, 1999 (added args to fputv, fputm, fputd)
  *     changed: January 26, 2017 (converted to Go)
+ *     changed: October 18, 2026 (added KnowledgeBase)
+ *     changed: October 18, 2026 (made KnowledgeBase safe for concurrent use)
+ *     changed: October 18, 2026 (methods take arguments and return results)
+ *     changed: October 18, 2026 (escaped, versioned format for fstoref)
+ *     changed: October 18, 2026 (added JSON import and export)
+ *     changed: October 18, 2026 (atomic writes in fstoref and fstorefs)
+ *     changed: October 18, 2026 (added storage directory)
+ *     changed: October 18, 2026 (added Storage interface)
+ *     changed: October 18, 2026 (added log-structured KVStorage)
+ *     changed: October 18, 2026 (added journal)
+ *     changed: October 18, 2026 (added transactions)
+ *     changed: October 18, 2026 (added undo and redo)
+ *     changed: October 18, 2026 (added snapshots and forks)
+ *     changed: October 18, 2026 (added inheritance through isa)
+ *     changed: October 18, 2026 (added multiple inheritance)
+ *     changed: October 18, 2026 (added defaults and if-needed demons)
+ *     changed: October 18, 2026 (added slot constraints)
+ *     changed: October 18, 2026 (added frame demons)
+ *     changed: October 18, 2026 (added before and after demon phases)
+ *     changed: October 18, 2026 (added several demons per event with priorities)
+ *     changed: October 18, 2026 (added demon and reference depth limits)
+ *     changed: October 18, 2026 (added panic recovery and failure policies)
  *
  * Copyright (c) 2017 Cris A. Fugate
  *
@@ -61,6 +83,7 @@
  * ftype					facet type
  * i						loop value
  * k						loop key
+ * kb						knowledge base
  * line						line from file
  * lista					first list to be processed
  * listb					second list to be processed
@@ -89,6 +112,10 @@
  * fframes[<fname>][<ename>]				used in operations involving many elements
  * fframes[<fname>][<fname>,slot]			frames in a frameset
  * fframes[<fname>][<fname>,<ftype>]		demon facet
+ * fframes[<fname>][<fname>,after<event>]	after demon
+ * fframes[<fname>][<fname>,before<event>]	before demon
+ * fframes[<fname>][<fname>,default]		default facet
+ * fframes[<fname>][<fname>,demons,<dname>]	frame demon
  * fframes[<fname>][<fname>,facets]			facets in a slot
  * fframes[<fname>][<fname>,ifcreatem]		ifcreatem demon
  * fframes[<fname>][<fname>,ifcreater]		ifcreater demon
@@ -100,6 +127,7 @@
  * fframes[<fname>][<fname>,ifgetm]			ifgetm demon
  * fframes[<fname>][<fname>,ifgetr]			ifgetr demon
  * fframes[<fname>][<fname>,ifgetv]			ifgetv demon
+ * fframes[<fname>][<fname>,ifneeded]		ifneeded demon
  * fframes[<fname>][<fname>,ifputm]			ifputm demon
  * fframes[<fname>][<fname>,ifputr]			ifputr demon
  * fframes[<fname>][<fname>,ifputv]			ifputv demon
@@ -163,12 +191,12 @@
  * Fmember					determine if a value is a member of a list
  * Fmergef					merge slots of a frame into another frame
  * Fpathr					get a list of frames in a reference chain
- * Fpathrr					(recursive version of Fpathr)
  * Fputd					put a value into a demon facet
  * Fputm					put a value into a method facet
  * Fputr					put a value into a reference facet
  * Fputv					put a value into a value facet
  * Fputx					put a function into the function map
+ * Fputxm					put a Method into the function map
  * Fremove					remove a value from a list
  * Fremoved					destroy a demon facet
  * Fremovef					destroy a frame
@@ -200,28 +228,441 @@
  * Funion					return union of two lists
  * Fupdatef					synchronize a frame based on another frame
  * Getval					get value from a frame map element
+ * NewKnowledgeBase		create an empty knowledge base
  * Putval					put value in a frame map element 
+ *
+ * Functions which return a bool or an empty string on failure also
+ * come in a variant ending in E (FputvE, FgetvE, ...) returning an error.
  */
 
 
 package framesets2
 
 import (
-	"bufio"
-	"os"
+	"fmt"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type Frame map[string][]string
 
-var fframes map[string]Frame
-var fmethods map[string]func(string)
+// KnowledgeBase - a store of frames and the methods they call
+// Each knowledge base owns its own fframes and fmethods, so a process
+// can hold any number of them side by side.
+// A knowledge base is safe for concurrent use. mu guards the set of
+// frames, each frame has its own lock, and xmu guards the methods.
+// When changes are handed to a Changer or a Journal, smu is taken
+// before mu and held until they are, so they are handed on in the order
+// they are made while mu is free for the rest of the knowledge base.
+// Demons and methods are always called with no lock held, so they are
+// free to call back into the knowledge base.
+// A transaction or a snapshot is a knowledge base of its own, holding
+// copies of the frames it uses and sharing the methods of the one it
+// was taken of.
+// The knowledge base a demon or method is handed in its MethodContext
+// shares everything with the one calling it but the count of demons
+// and methods running, so commands it calls count towards the limit.
+type KnowledgeBase struct {
+	*fkb
+	depth int
+}
+
+// fkb - the state of a knowledge base
+type fkb struct {
+	mu      sync.RWMutex
+	smu     sync.Mutex
+	fframes map[string]*fentry
+	*fxtable
+	storage Storage
+	journal *Journal
+	tx      *ftx
+	history *fhistory
+	snap    *fsnap
+	snaps   []*fsnap
+	isa     string
+}
+
+// fxtable - the methods of a knowledge base and the lock guarding them
+// fdemons is set once any frame has held a frame demon, so until then
+// no frameset needs to be searched for one
+type fxtable struct {
+	xmu      sync.RWMutex
+	fmethods map[string]Method
+	fdemons  atomic.Bool
+	flimits
+	fpolicy
+}
+
+// fentry - a frame and the lock guarding it
+// gone is set once the frame is removed or replaced, so anyone still
+// holding the entry looks the frame up again. ver counts the writes
+// to the frame, so a transaction can tell if it changed.
+type fentry struct {
+	mu    sync.RWMutex
+	frame Frame
+	gone  bool
+	ver   uint64
+}
+
+// NewKnowledgeBase - create an empty knowledge base
+func NewKnowledgeBase() *KnowledgeBase {
+	return &KnowledgeBase{fkb: &fkb{
+		fframes: make(map[string]*fentry),
+		fxtable: &fxtable{fmethods: make(map[string]Method)},
+		storage: NewDirStorage("."),
+	}}
+}
+
+// read - call fn with the frame locked for reading
+func (e *fentry) read(fn func(Frame)) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.gone {
+		return false
+	}
+	fn(e.frame)
+	return true
+}
+
+// write - call fn with the frame locked for writing
+func (e *fentry) write(fn func(Frame) error) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.gone {
+		return false, nil
+	}
+	e.ver++
+	return true, fn(e.frame)
+}
+
+// entry - look up the entry of a frame
+// in a transaction or a snapshot, a frame it has not used yet is copied
+// into it
+func (kb *KnowledgeBase) entry(fname string) *fentry {
+	kb.mu.RLock()
+	e := kb.fframes[fname]
+	kb.mu.RUnlock()
+	switch {
+	case e != nil:
+		return e
+	case kb.tx != nil:
+		return kb.fault(fname)
+	case kb.snap != nil:
+		return kb.thaw(fname)
+	}
+	return nil
+}
+
+// readf - call fn with a frame locked for reading
+// returns false if the frame does not exist
+// fn must not call back into the knowledge base
+func (kb *KnowledgeBase) readf(fname string, fn func(Frame)) bool {
+	for e := kb.entry(fname); e != nil; e = kb.entry(fname) {
+		if e.read(fn) {
+			return true
+		}
+	}
+	return false
+}
 
-// initialize fframes and fmethods
-func init() {
-	fframes = make(map[string]Frame)
-	fmethods = make(map[string]func(string))
+// writef - call fn with a frame locked for writing
+// returns the error from fn, ErrFrameNotFound or ErrReadOnly
+// fn must not call back into the knowledge base
+// the frame is kept for any snapshot of the knowledge base first, and
+// the changes made by fn are handed to the Changer and the Journal of the
+// knowledge base, if any, and undone if they fail, and then recorded in
+// its history; only the frame stays locked while they are handed on
+func (kb *KnowledgeBase) writef(fname string, fn func(Frame) error) error {
+	if kb.snap != nil {
+		return ErrReadOnly
+	}
+	changer, journal := kb.sinks()
+	history := kb.hist()
+	if changer != nil || journal != nil {
+		kb.smu.Lock()
+		defer kb.checkpointed(journal)
+		defer kb.smu.Unlock()
+	}
+	for e := kb.entry(fname); e != nil; e = kb.entry(fname) {
+		// mu is held until the frame has changed, so no snapshot is taken
+		// in between
+		kb.mu.RLock()
+		e.mu.Lock()
+		if e.gone {
+			e.mu.Unlock()
+			kb.mu.RUnlock()
+			continue
+		}
+		e.ver++
+		f := e.frame
+		fkeep(kb.snaps, fname, func() Frame {
+			return clonef(f)
+		})
+		if changer == nil && journal == nil && history == nil {
+			err := fn(f)
+			e.mu.Unlock()
+			kb.mu.RUnlock()
+			return err
+		}
+		before := clonef(f)
+		err := fn(f)
+		kb.mu.RUnlock()
+		changes := fdiff(fname, before, f)
+		if cerr := fchanged(changer, journal, changes); cerr != nil {
+			for k := range f {
+				delete(f, k)
+			}
+			for k, v := range before {
+				f[k] = v
+			}
+			e.mu.Unlock()
+			return cerr
+		}
+		history.record(hrecord{fname: fname, changes: changes})
+		e.mu.Unlock()
+		return err
+	}
+	return ErrFrameNotFound
+}
+
+// setf - put a frame in fframes, replacing any frame of the same name
+// if create is set, an existing frame is left alone and ErrFrameExists
+// returned; if stored is set, the frame was read from where it is kept,
+// so the Changer of the knowledge base is not told
+// the frame is written to the Journal and then handed to the Changer,
+// and is left alone if either fails
+func (kb *KnowledgeBase) setf(fname string, frame Frame, create, stored bool) error {
+	if kb.snap != nil {
+		return ErrReadOnly
+	}
+	kb.markdf(fname, frame)
+	if kb.tx != nil {
+		kb.entry(fname)
+	}
+	changer, journal := kb.sinks()
+	if stored {
+		changer = nil
+	}
+	if changer != nil || journal != nil {
+		kb.smu.Lock()
+		defer kb.checkpointed(journal)
+		defer kb.smu.Unlock()
+		// no other command changes the frame while smu is held, so it is
+		// handed on before mu is taken
+		old, ok := kb.copye(fname)
+		if ok && create {
+			return ErrFrameExists
+		}
+		if journal != nil {
+			if err := journal.frame(fname, frame); err != nil {
+				return err
+			}
+		}
+		if changer != nil {
+			if err := changer.Apply(fwhole(fname, old, frame)); err != nil {
+				if journal != nil {
+					journal.put(fname, old)
+				}
+				return err
+			}
+		}
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	e := kb.fframes[fname]
+	if e != nil && create {
+		return ErrFrameExists
+	}
+	var old Frame
+	if e != nil {
+		old = e.frame
+	}
+	kb.fframes[fname] = &fentry{frame: frame}
+	if e != nil {
+		e.drop()
+	}
+	fkeep(kb.snaps, fname, func() Frame {
+		return old
+	})
+	if kb.history != nil {
+		kb.history.record(hrecord{fname: fname, whole: true, old: old, new: clonef(frame)})
+	}
+	return nil
+}
+
+// delf - take a frame out of fframes
+// returns ErrFrameNotFound if it is not there
+// the frame is removed in the Journal and then the Changer, and is left
+// alone if either fails
+func (kb *KnowledgeBase) delf(fname string) error {
+	if kb.snap != nil {
+		return ErrReadOnly
+	}
+	if kb.tx != nil {
+		kb.entry(fname)
+	}
+	changer, journal := kb.sinks()
+	if changer != nil || journal != nil {
+		kb.smu.Lock()
+		defer kb.checkpointed(journal)
+		defer kb.smu.Unlock()
+		// as in setf, the frame is removed in the Journal and the Changer
+		// before mu is taken
+		old, ok := kb.copye(fname)
+		if !ok {
+			return ErrFrameNotFound
+		}
+		if journal != nil {
+			if err := journal.remove(fname); err != nil {
+				return err
+			}
+		}
+		if changer != nil {
+			if err := changer.Apply(fwhole(fname, old, nil)); err != nil {
+				if journal != nil {
+					journal.frame(fname, old)
+				}
+				return err
+			}
+		}
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	e := kb.fframes[fname]
+	if e == nil {
+		return ErrFrameNotFound
+	}
+	delete(kb.fframes, fname)
+	e.drop()
+	fkeep(kb.snaps, fname, func() Frame {
+		return e.frame
+	})
+	kb.history.record(hrecord{fname: fname, whole: true, old: e.frame})
+	return nil
+}
+
+// copye - copy of a frame in fframes, and whether it is there
+func (kb *KnowledgeBase) copye(fname string) (Frame, bool) {
+	kb.mu.RLock()
+	e := kb.fframes[fname]
+	kb.mu.RUnlock()
+	var x Frame
+	if e == nil || !e.read(func(f Frame) {
+		x = clonef(f)
+	}) {
+		return nil, false
+	}
+	return x, true
+}
+
+// drop - mark an entry as no longer part of fframes
+func (e *fentry) drop() {
+	e.mu.Lock()
+	e.gone = true
+	e.mu.Unlock()
+}
+
+// clonef - copy a frame so it can be used without holding its lock
+func clonef(frame Frame) Frame {
+	x := make(Frame, len(frame))
+	for k, v := range frame {
+		x[k] = append([]string{}, v...)
+	}
+	return x
+}
+
+// copyf - copy of a frame, or nil if the frame does not exist
+func (kb *KnowledgeBase) copyf(fname string) Frame {
+	var x Frame
+	kb.readf(fname, func(f Frame) {
+		x = clonef(f)
+	})
+	return x
+}
+
+// slot - copy of the facets of a slot keyed by facet type
+// the list of facet types is kept under the key "facets"
+type slot map[string][]string
+
+// fslot - copy a slot out of a locked frame, nil if it does not exist
+func fslot(f Frame, fname, sname string) slot {
+	if !Fmember(f[fname+",slots"], sname) {
+		return nil
+	}
+	s := slot{"facets": append([]string{}, f[sname+",facets"]...)}
+	for _, i := range s["facets"] {
+		s[i] = append([]string{}, f[sname+","+i]...)
+	}
+	return s
+}
+
+// slot - copy of a slot, nil if the frame or slot does not exist
+func (kb *KnowledgeBase) slot(fname, sname string) slot {
+	s, _ := kb.slote(fname, sname)
+	return s
+}
+
+// slote - copy of a slot, with ErrFrameNotFound or ErrSlotNotFound
+// if it does not exist
+// a slot the frame does not have is inherited from its parents
+func (kb *KnowledgeBase) slote(fname, sname string) (slot, error) {
+	_, s, err := kb.slotp(fname, sname)
+	return s, err
+}
+
+// has - determine if a slot has a facet
+func (s slot) has(ftype string) bool {
+	return Fmember(s["facets"], ftype)
+}
+
+// demon - name of the method of the first demon in a demon facet, ""
+// if there is none
+func (s slot) demon(dname string) string {
+	if dname == "ifneeded" {
+		// an ifneeded demon is one method, followed by whether to cache
+		return Getval(s[dname])
+	}
+	return Getval(s.demons(dname))
+}
+
+// missing - error for a slot which lacks a value or method facet
+// a slot holding the other kind of facet is a conflict
+func (s slot) missing(ftype string) error {
+	if (ftype == "value" && s.has("method")) || (ftype == "method" && s.has("value")) {
+		return ErrFacetConflict
+	}
+	return ErrFacetNotFound
+}
+
+// method - get a method from fmethods, nil if it does not exist
+func (kb *KnowledgeBase) method(mname string) Method {
+	kb.xmu.RLock()
+	defer kb.xmu.RUnlock()
+	return kb.fmethods[mname]
+}
+
+// call - call a method from fmethods
+func (kb *KnowledgeBase) call(mname string, c *MethodContext) (any, error) {
+	return kb.invoke(mname, ErrMethodNotFound, c)
+}
+
+// fire - call the methods named in a demon facet of a slot, by priority
+// nothing is called if the slot has no such demon or it is empty
+// stops at the first demon failing
+func (kb *KnowledgeBase) fire(s slot, dname, fname, sname string, args ...any) error {
+	mnames := s.demons(dname)
+	if len(mnames) == 0 {
+		return nil
+	}
+	defer kb.group()()
+	for _, mname := range mnames {
+		if _, err := kb.invoke(mname, ErrDemonMissing, &MethodContext{KB: kb, Frame: fname, Slot: sname, Op: dname, Args: args}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 /* value wrappers
@@ -369,10 +810,10 @@ func Fsubset(lista, listb []string) bool {
 }
 
 // ffind - find all frames having a given value facet
-func Ffind(sname string) []string {
+func (kb *KnowledgeBase) Ffind(sname string) []string {
 	listx := []string{}
-	for _, i := range Flistf() {
-		if Fexistv(i, sname) {
+	for _, i := range kb.Flistf() {
+		if kb.Fexistv(i, sname) {
 			listx = append(listx, i)
 		}
 	}
@@ -380,11 +821,12 @@ func Ffind(sname string) []string {
 }
 
 // ffindeq - find all frames having a given value for a given value facet
-func Ffindeq(sname string, args string) []string {
+// values are compared by the type of the slot, or else as strings
+func (kb *KnowledgeBase) Ffindeq(sname string, args string) []string {
 	listx := []string{}
-	for _, i := range Flistf() {
-		if Fexistv(i, sname) {
-			if Fgetv(i, sname) == args {
+	for _, i := range kb.Flistf() {
+		if kb.Fexistv(i, sname) {
+			if fequalt(kb.Fgettype(i, sname), kb.Fgetv(i, sname), args) {
 				listx = append(listx, i)
 			}
 		}
@@ -393,11 +835,12 @@ func Ffindeq(sname string, args string) []string {
 }
 
 // ffindne - find all frames not having a given value for a given value facet
-func Ffindne(sname string, args string) []string {
+// values are compared by the type of the slot, or else as strings
+func (kb *KnowledgeBase) Ffindne(sname string, args string) []string {
 	listx := []string{}
-	for _, i := range Flistf() {
-		if Fexistv(i, sname) {
-			if Fgetv(i, sname) != args {
+	for _, i := range kb.Flistf() {
+		if kb.Fexistv(i, sname) {
+			if !fequalt(kb.Fgettype(i, sname), kb.Fgetv(i, sname), args) {
 				listx = append(listx, i)
 			}
 		}
@@ -408,42 +851,71 @@ func Ffindne(sname string, args string) []string {
 // frames functions
 
 // fexistf - determine if a frame exists
-func Fexistf(fname string) bool {
-	frames := []string{}
-	for k, _ := range fframes {
-		frames = append(frames, k)
-	}
-	return Fmember(frames, fname)
+func (kb *KnowledgeBase) Fexistf(fname string) bool {
+	return kb.entry(fname) != nil
 }
 
 // fcreatef - create a frame
 // requires that fframes[fname] does not exist
 // modifies fframes, fframes[fname][fname,slots]
-func Fcreatef(fname string) bool {
-	if !Fexistf(fname) {
-		fframes[fname] = Frame{fname + ",slots": {}}
-		return true
-	} else {
-		return false
+// calls ifcreatef demons
+func (kb *KnowledgeBase) Fcreatef(fname string) bool {
+	return kb.FcreatefE(fname) == nil
+}
+
+// fcreatefe - create a frame, returning an error
+func (kb *KnowledgeBase) FcreatefE(fname string) error {
+	defer kb.group()()
+	if kb.Fexistf(fname) {
+		return ferror("fcreatef", fname, "", ErrFrameExists)
+	}
+	if err := kb.firef("beforecreatef", fname, "", nil); err != nil {
+		return ferror("fcreatef", fname, "", err)
+	}
+	if err := kb.setf(fname, Frame{fname + ",slots": {}}, true, false); err != nil {
+		return ferror("fcreatef", fname, "", err)
+	}
+	err := kb.firef("ifcreatef", fname, "", nil)
+	if err == nil {
+		err = kb.firef("aftercreatef", fname, "", nil)
 	}
+	return ferror("fcreatef", fname, "", err)
 }
 
 // fremovef - remove a frame
 // requires that fframes[fname] exists
 // modifies fframes, fframes[fname]
-func Fremovef(fname string) bool {
-	if Fexistf(fname) {
-		delete(fframes, fname)
-		return true
-	} else {
-		return false
+// calls ifremovef demons
+func (kb *KnowledgeBase) Fremovef(fname string) bool {
+	return kb.FremovefE(fname) == nil
+}
+
+// fremovefe - remove a frame, returning an error
+func (kb *KnowledgeBase) FremovefE(fname string) error {
+	defer kb.group()()
+	if !kb.Fexistf(fname) {
+		return ferror("fremovef", fname, "", ErrFrameNotFound)
+	}
+	if err := kb.firef("beforeremovef", fname, "", nil); err != nil {
+		return ferror("fremovef", fname, "", err)
+	}
+	if err := kb.firef("ifremovef", fname, "", nil); err != nil {
+		return ferror("fremovef", fname, "", err)
 	}
+	// the frame holding its after demons is gone once they are called
+	mnames := kb.demonsf("afterremovef", fname, nil)
+	if err := kb.delf(fname); err != nil {
+		return ferror("fremovef", fname, "", err)
+	}
+	return ferror("fremovef", fname, "", kb.callf(mnames, "afterremovef", fname, ""))
 }
 
 // flistf - return list of frames
-func Flistf() []string {
-	frames := []string{}
-	for k, _ := range fframes {
+func (kb *KnowledgeBase) Flistf() []string {
+	frames := kb.flistt()
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	for k, _ := range kb.fframes {
 		frames = append(frames, k)
 	}
 	return frames
@@ -452,33 +924,47 @@ func Flistf() []string {
 // fcopyf - create a new frame based on another frame
 // requires that fframes[fname1] exists
 // modifies fframes, fframes[fname2]
-func Fcopyf(fname1, fname2 string) bool {
-	if Fexistf(fname1) {
-		Fremovef(fname2)
-		Fcreatef(fname2)
-		for k, _ := range fframes[fname1] {
+// calls ifcreatef demons
+func (kb *KnowledgeBase) Fcopyf(fname1, fname2 string) bool {
+	return kb.FcopyfE(fname1, fname2) == nil
+}
+
+// fcopyfe - create a new frame based on another frame, returning an error
+func (kb *KnowledgeBase) FcopyfE(fname1, fname2 string) error {
+	defer kb.group()()
+	if err := kb.firef("beforecreatef", fname2, "", nil); err != nil {
+		return ferror("fcopyf", fname2, "", err)
+	}
+	if x := kb.copyf(fname1); x != nil {
+		y := Frame{fname2 + ",slots": {}}
+		for k, _ := range x {
 			if strings.HasSuffix(k, "slots") {
-				slots := []string{}
-				slots = append(slots, fframes[fname1][fname1+",slots"]...)
-				fframes[fname2][fname2+",slots"] = slots
+				y[fname2+",slots"] = x[fname1+",slots"]
+			} else if fdemonf(fname1, k) {
+				y[fname2+strings.TrimPrefix(k, fname1)] = x[k]
 			} else {
-				elem := []string{}
-				elem = append(elem, fframes[fname1][k]...)
-				fframes[fname2][k] = elem
+				y[k] = x[k]
 			}
 		}
-		return true
+		if err := kb.setf(fname2, y, false, false); err != nil {
+			return ferror("fcopyf", fname2, "", err)
+		}
+		err := kb.firef("ifcreatef", fname2, "", nil)
+		if err == nil {
+			err = kb.firef("aftercreatef", fname2, "", nil)
+		}
+		return ferror("fcopyf", fname2, "", err)
 	} else {
-		return false
+		return ferror("fcopyf", fname1, "", ErrFrameNotFound)
 	}
 }
 
 // fcomparef - determine if two frames are equivalent
 // requires that fframes[fname1] and fframes[fname2] exist
-func Fcomparef(fname1, fname2 string) bool {
-	if Fexistf(fname1) && Fexistf(fname2) {
-		x := fframes[fname1][fname1+",slots"]
-		y := fframes[fname2][fname2+",slots"]
+func (kb *KnowledgeBase) Fcomparef(fname1, fname2 string) bool {
+	if kb.Fexistf(fname1) && kb.Fexistf(fname2) {
+		x := kb.Flists(fname1)
+		y := kb.Flists(fname2)
 		if Fequivalence(x, y) {
 			return true
 		} else {
@@ -492,107 +978,134 @@ func Fcomparef(fname1, fname2 string) bool {
 // fmergef - merge slots of one frame into another frame
 // requires that fframes[fname1] and fframes[fname2] exist
 // modifies fframes[fname2]
-func Fmergef(fname1, fname2 string) bool {
-	if Fexistf(fname1) && Fexistf(fname2) {
-		y := fframes[fname2][fname2+",slots"]
-		for k, _ := range fframes[fname1] {
-			if k != fname1+",set" && k != fname1+",slots" {
-				sname := strings.Split(k, ",")[0]
-				if !Fmember(y, sname) {
-					fframes[fname2][k] = append(fframes[fname2][k], fframes[fname1][k]...)
-					slots := append(fframes[fname2][fname2+",slots"], sname)
-					fframes[fname2][fname2+",slots"] = slots
+func (kb *KnowledgeBase) Fmergef(fname1, fname2 string) bool {
+	return kb.FmergefE(fname1, fname2) == nil
+}
+
+// fmergefe - merge slots of one frame into another frame, returning an error
+func (kb *KnowledgeBase) FmergefE(fname1, fname2 string) error {
+	if x := kb.copyf(fname1); x != nil {
+		err := kb.writef(fname2, func(f Frame) error {
+			y := append([]string{}, f[fname2+",slots"]...)
+			for k, _ := range x {
+				if !fframek(fname1, k) {
+					sname := strings.Split(k, ",")[0]
+					if !Fmember(y, sname) {
+						f[k] = append(f[k], x[k]...)
+						slots := append(f[fname2+",slots"], sname)
+						f[fname2+",slots"] = slots
+					}
 				}
 			}
-		}
-		return true
+			return nil
+		})
+		return ferror("fmergef", fname2, "", err)
 	} else {
-		return false
+		return ferror("fmergef", fname1, "", ErrFrameNotFound)
 	}
 }
 
-// floadf - load a frame into memory
+// floadf - load a frame into memory from the storage directory
 // requires that fframes[fname] exists on disk, but not in memory
-func Floadf(fname string) bool {
-	if _, err := os.Stat(fname); os.IsExist(err) {
-		if !Fexistf(fname) {
-			Fcreatef(fname)
-			fh, _ := os.Open(fname)
-			defer fh.Close()
-			reader := bufio.NewReader(fh)
-			for {
-				line, _, err := reader.ReadLine()
-				if err != nil {
-					break
-				}
-				aname := strings.Split(string(line), " ")[0]
-				avalue := strings.TrimPrefix(string(line), aname+" ")
-				fframes[fname][aname] = strings.Split(avalue, ",")
-			}
-			return true
-		}
-		return false
+func (kb *KnowledgeBase) Floadf(fname string) bool {
+	return kb.FloadfE(fname) == nil
+}
+
+// floadfe - load a frame into memory, returning an error
+func (kb *KnowledgeBase) FloadfE(fname string) error {
+	if kb.Fexistf(fname) {
+		return ferror("floadf", fname, "", ErrFrameExists)
 	}
-	return false
+	x, err := kb.GetStorage().Load(fname)
+	if err != nil {
+		return ferror("floadf", fname, "", err)
+	}
+	if err := fcheckt(x, fname); err != nil {
+		return err
+	}
+	if err := kb.setf(fname, x, true, true); err != nil {
+		return ferror("floadf", fname, "", err)
+	}
+	return nil
 }
 
-// fstoref - store a frame on disk
+// fstoref - store a frame in the storage directory
 // requires that fframes[fname] exists
-func Fstoref(fname string) bool {
-	if Fexistf(fname) {
-		fh, _ := os.Create(fname)
-		defer fh.Close()
-		writer := bufio.NewWriter(fh)
-		for k, _ := range fframes[fname] {
-			writer.WriteString(k + " " + strings.Join(fframes[fname][k], ",") + "\n")
-		}
-		writer.Flush()
-		return true
+func (kb *KnowledgeBase) Fstoref(fname string) bool {
+	return kb.FstorefE(fname) == nil
+}
+
+// fstorefe - store a frame on disk, returning an error
+func (kb *KnowledgeBase) FstorefE(fname string) error {
+	storage := kb.GetStorage()
+	var err error
+	// the frame stays locked, so no change slips in between storing
+	// it and a Changer following it
+	if kb.readf(fname, func(f Frame) {
+		err = storage.Store(fname, f)
+	}) {
+		return ferror("fstoref", fname, "", err)
 	}
-	return false
+	return ferror("fstoref", fname, "", ErrFrameNotFound)
 }
 
 // fupdatef - update structure of a frame from another frame
 // requires that both frames exist
 // modifies fframes[fname2]
-func Fupdatef(fname1, fname2 string) bool {
-	if Fexistf(fname1) && Fexistf(fname2) {
-		copy(fframes[fname2][fname2+",slots"], fframes[fname1][fname1+",slots"])
-		for k, _ := range fframes[fname2] {
-			if !strings.HasSuffix(k, "set") && !strings.HasSuffix(k, "slots") {
-				if _, err := fframes[fname1][k]; err {
-					delete(fframes[fname2], k)
+func (kb *KnowledgeBase) Fupdatef(fname1, fname2 string) bool {
+	return kb.FupdatefE(fname1, fname2) == nil
+}
+
+// fupdatefe - update structure of a frame from another frame, returning an error
+func (kb *KnowledgeBase) FupdatefE(fname1, fname2 string) error {
+	if x := kb.copyf(fname1); x != nil {
+		err := kb.writef(fname2, func(f Frame) error {
+			copy(f[fname2+",slots"], x[fname1+",slots"])
+			for k, _ := range f {
+				if !strings.HasSuffix(k, "set") && !strings.HasSuffix(k, "slots") {
+					if _, err := x[k]; err {
+						delete(f, k)
+					}
 				}
 			}
-		}
-		for k, _ := range fframes[fname1] {
-			if !strings.HasSuffix(k, "set") && !strings.HasSuffix(k, "slots") {
-				if _, err := fframes[fname2][k]; err {
-					copy(fframes[fname2][k], fframes[fname1][k])
+			for k, _ := range x {
+				if !strings.HasSuffix(k, "set") && !strings.HasSuffix(k, "slots") {
+					if _, err := f[k]; err {
+						copy(f[k], x[k])
+					}
 				}
 			}
-		}
-		return true
+			return nil
+		})
+		return ferror("fupdatef", fname2, "", err)
 	} else {
-		return false
+		return ferror("fupdatef", fname1, "", ErrFrameNotFound)
 	}
 }
 
 // ffilterf - filter slots of a frame based on another frame
 // requiers that both frames exist
 // modifies fframes[fname2]
-func Ffilterf(fname1, fname2 string) bool {
-	if Fexistf(fname1) && Fexistf(fname2) {
-		for k, _ := range fframes[fname2] {
-			if !strings.HasSuffix(k, "set") && !strings.HasSuffix(k, "slots") {
-				if _, err := fframes[fname1][k]; err {
-					delete(fframes[fname2], k)
+func (kb *KnowledgeBase) Ffilterf(fname1, fname2 string) bool {
+	return kb.FfilterfE(fname1, fname2) == nil
+}
+
+// ffilterfe - filter slots of a frame based on another frame, returning an error
+func (kb *KnowledgeBase) FfilterfE(fname1, fname2 string) error {
+	if x := kb.copyf(fname1); x != nil {
+		err := kb.writef(fname2, func(f Frame) error {
+			for k, _ := range f {
+				if !strings.HasSuffix(k, "set") && !strings.HasSuffix(k, "slots") {
+					if _, err := x[k]; err {
+						delete(f, k)
+					}
 				}
 			}
-		}
-		return true
+			return nil
+		})
+		return ferror("ffilterf", fname2, "", err)
 	} else {
-		return false
+		return ferror("ffilterf", fname1, "", ErrFrameNotFound)
 	}
 }
 
@@ -601,31 +1114,73 @@ func Ffilterf(fname1, fname2 string) bool {
 // fcreatex - create a method in fmethods
 // requires that fmethods[mname] does not exist
 // modifies fmethods
-func Fcreatex(mname string) bool {
-	if _, err := fmethods[mname]; !err {
-		fmethods[mname] = func(string) {}
-		return true
+func (kb *KnowledgeBase) Fcreatex(mname string) bool {
+	return kb.FcreatexE(mname) == nil
+}
+
+// fcreatexe - create a method in fmethods, returning an error
+func (kb *KnowledgeBase) FcreatexE(mname string) error {
+	_, journal := kb.sinks()
+	defer kb.checkpointed(journal)
+	if journal != nil {
+		// the method is journaled holding smu rather than xmu, which is
+		// taken by every method called
+		kb.smu.Lock()
+		defer kb.smu.Unlock()
+		if kb.Fexistx(mname) {
+			return fmt.Errorf("framesets2: fcreatex %s: %w", mname, ErrMethodExists)
+		}
+		if err := journal.method(mname, true); err != nil {
+			return fmt.Errorf("framesets2: fcreatex %s: %w", mname, err)
+		}
+	}
+	kb.xmu.Lock()
+	defer kb.xmu.Unlock()
+	if _, err := kb.fmethods[mname]; !err {
+		kb.fmethods[mname] = Adapt(func(string) {})
+		return nil
 	} else {
-		return false
+		return fmt.Errorf("framesets2: fcreatex %s: %w", mname, ErrMethodExists)
 	}
 }
 
 // fremovex - remove a method from fmethods
 // requires that fmethods[mname] exists
 // modifies fmethods
-func Fremovex(mname string) bool {
-	if _, err := fmethods[mname]; err {
-		delete(fmethods, mname)
-		return true
+func (kb *KnowledgeBase) Fremovex(mname string) bool {
+	return kb.FremovexE(mname) == nil
+}
+
+// fremovexe - remove a method from fmethods, returning an error
+func (kb *KnowledgeBase) FremovexE(mname string) error {
+	_, journal := kb.sinks()
+	defer kb.checkpointed(journal)
+	if journal != nil {
+		kb.smu.Lock()
+		defer kb.smu.Unlock()
+		if !kb.Fexistx(mname) {
+			return fmt.Errorf("framesets2: fremovex %s: %w", mname, ErrMethodNotFound)
+		}
+		if err := journal.method(mname, false); err != nil {
+			return fmt.Errorf("framesets2: fremovex %s: %w", mname, err)
+		}
+	}
+	kb.xmu.Lock()
+	defer kb.xmu.Unlock()
+	if _, err := kb.fmethods[mname]; err {
+		delete(kb.fmethods, mname)
+		return nil
 	} else {
-		return false
+		return fmt.Errorf("framesets2: fremovex %s: %w", mname, ErrMethodNotFound)
 	}
 
 }
 
 // fexistx - determine if a method exists in fmethods
-func Fexistx(mname string) bool {
-	if _, err := fmethods[mname]; err {
+func (kb *KnowledgeBase) Fexistx(mname string) bool {
+	kb.xmu.RLock()
+	defer kb.xmu.RUnlock()
+	if _, err := kb.fmethods[mname]; err {
 		return true
 	} else {
 		return false
@@ -633,18 +1188,23 @@ func Fexistx(mname string) bool {
 }
 
 // flistx - return list of methods in fmethods
-func Flistx() []string {
+func (kb *KnowledgeBase) Flistx() []string {
+	kb.xmu.RLock()
+	defer kb.xmu.RUnlock()
 	methods := []string{}
-	for k, _ := range fmethods {
+	for k, _ := range kb.fmethods {
 		methods = append(methods, k)
 	}
 	return methods
 }
 
 // fgetx - get a method from fmethods
-func Fgetx(mname string) (func(string), bool) {
-	if _, err := fmethods[mname]; err {
-		return fmethods[mname], true
+// the method is called with only a frame name and its result is dropped
+func (kb *KnowledgeBase) Fgetx(mname string) (func(string), bool) {
+	if method := kb.method(mname); method != nil {
+		return func(fname string) {
+			kb.run(mname, method, &MethodContext{KB: kb, Frame: fname, Op: "fgetx"})
+		}, true
 	} else {
 		return func(string) {}, false
 	}
@@ -653,116 +1213,203 @@ func Fgetx(mname string) (func(string), bool) {
 // fputx - put a method in fmethods
 // requires that fmethods[mname] exists
 // modifies fmethods[mname]
-func Fputx(mname string, method func(string)) bool {
-	if _, err := fmethods[mname]; err {
-		fmethods[mname] = method
-		return true
-	} else {
-		return false
+func (kb *KnowledgeBase) Fputx(mname string, method func(string)) bool {
+	return kb.FputxE(mname, method) == nil
+}
+
+// fputxe - put a method in fmethods, returning an error
+func (kb *KnowledgeBase) FputxE(mname string, method func(string)) error {
+	return kb.putx("fputx", mname, Adapt(method))
+}
+
+// fputxm - put a method taking a MethodContext in fmethods
+// requires that fmethods[mname] exists
+// modifies fmethods[mname]
+func (kb *KnowledgeBase) Fputxm(mname string, method Method) bool {
+	return kb.FputxmE(mname, method) == nil
+}
+
+// fputxme - put a method taking a MethodContext in fmethods, returning an error
+func (kb *KnowledgeBase) FputxmE(mname string, method Method) error {
+	return kb.putx("fputxm", mname, method)
+}
+
+// putx - put a method in fmethods
+// a nil method is refused, as it could not be called
+func (kb *KnowledgeBase) putx(op, mname string, method Method) error {
+	kb.xmu.Lock()
+	defer kb.xmu.Unlock()
+	if _, err := kb.fmethods[mname]; !err {
+		return fmt.Errorf("framesets2: %s %s: %w", op, mname, ErrMethodNotFound)
+	} else if method == nil {
+		return fmt.Errorf("framesets2: %s %s: %w", op, mname, ErrNilMethod)
 	}
+	kb.fmethods[mname] = method
+	return nil
 }
 
 // slot functions
 
-// fexists - determine if a slot exists
+// fexists - determine if a slot exists, in the frame or its parents
 // requires that fframes[fname] exists
-func Fexists(fname, sname string) bool {
-	if Fexistf(fname) {
-		if Fmember(fframes[fname][fname+",slots"], sname) {
-			return true
-		} else {
-			return false
-		}
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fexists(fname, sname string) bool {
+	return kb.slot(fname, sname) != nil
 }
 
 // fcreates - create a slot
 // requires that fframes[fname] exists
 // modifies fframes[fname][fname,slots], fframes[fname][sname,facets]
-func Fcreates(fname, sname string) bool {
-	if Fexistf(fname) {
-		if !Fmember(fframes[fname][fname+",slots"], sname) {
-			slots := append(fframes[fname][fname+",slots"], sname)
-			fframes[fname][fname+",slots"] = slots
-			fframes[fname][sname+",facets"] = []string{}
-			return true
+// calls ifcreates demons
+func (kb *KnowledgeBase) Fcreates(fname, sname string) bool {
+	return kb.FcreatesE(fname, sname) == nil
+}
+
+// fcreatese - create a slot, returning an error
+func (kb *KnowledgeBase) FcreatesE(fname, sname string) error {
+	defer kb.group()()
+	found := false
+	if kb.readf(fname, func(f Frame) {
+		found = Fmember(f[fname+",slots"], sname)
+	}) && !found {
+		if err := kb.firef("beforecreates", fname, sname, nil); err != nil {
+			return ferror("fcreates", fname, sname, err)
+		}
+	}
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[fname+",slots"], sname) {
+			slots := append(f[fname+",slots"], sname)
+			f[fname+",slots"] = slots
+			f[sname+",facets"] = []string{}
+			return nil
 		} else {
-			return false
+			return ErrSlotExists
 		}
-	} else {
-		return false
+	})
+	if err == nil {
+		err = kb.firef("ifcreates", fname, sname, nil)
 	}
+	if err == nil {
+		err = kb.firef("aftercreates", fname, sname, nil)
+	}
+	return ferror("fcreates", fname, sname, err)
 }
 
 // fremoves - remove a slot
 // requires that fframes[fname][sname,facets] exists
 // modifies fframes[fname][fname,slots], fframes[fname][sname,]?
-func Fremoves(fname, sname string) bool {
-	if Fexists(fname, sname) {
-		for k, _ := range fframes[fname] {
-			sname2 := strings.Split(k, ",")[0]
-			if sname == sname2 {
-				delete(fframes[fname], k)
+// calls ifremoves demons
+func (kb *KnowledgeBase) Fremoves(fname, sname string) bool {
+	return kb.FremovesE(fname, sname) == nil
+}
+
+// fremovese - remove a slot, returning an error
+func (kb *KnowledgeBase) FremovesE(fname, sname string) error {
+	defer kb.group()()
+	found := false
+	if !kb.readf(fname, func(f Frame) {
+		found = Fmember(f[fname+",slots"], sname)
+	}) {
+		return ferror("fremoves", fname, sname, ErrFrameNotFound)
+	}
+	if !found {
+		return ferror("fremoves", fname, sname, ErrSlotNotFound)
+	}
+	if err := kb.firef("beforeremoves", fname, sname, nil); err != nil {
+		return ferror("fremoves", fname, sname, err)
+	}
+	if err := kb.firef("ifremoves", fname, sname, nil); err != nil {
+		return ferror("fremoves", fname, sname, err)
+	}
+	err := kb.writef(fname, func(f Frame) error {
+		if Fmember(f[fname+",slots"], sname) {
+			for k, _ := range f {
+				sname2 := strings.Split(k, ",")[0]
+				if sname == sname2 && !fframek(fname, k) {
+					delete(f, k)
+				}
 			}
+			slots := f[fname+",slots"]
+			Fremove(&slots, sname)
+			f[fname+",slots"] = slots
+			return nil
+		} else {
+			return ErrSlotNotFound
 		}
-		slots := fframes[fname][fname+",slots"]
-		Fremove(&slots, sname)
-		fframes[fname][fname+",slots"] = slots
-		return true
-	} else {
-		return false
+	})
+	if err == nil {
+		err = kb.firef("afterremoves", fname, sname, nil)
 	}
+	return ferror("fremoves", fname, sname, err)
 }
 
 // flists - list slots of a frame
 // requires that fframes[fname] exists
-func Flists(fname string) []string {
-	if Fexistf(fname) {
-		return fframes[fname][fname+",slots"]
-	} else {
-		return []string{}
+func (kb *KnowledgeBase) Flists(fname string) []string {
+	slots, _ := kb.FlistsE(fname)
+	return slots
+}
+
+// flistse - list slots of a frame, returning an error
+// the slots of the frame come first, followed by those it inherits
+func (kb *KnowledgeBase) FlistsE(fname string) ([]string, error) {
+	slots := []string{}
+	plist, err := kb.pathp(fname)
+	isa := kb.GetIsa()
+	for _, i := range plist {
+		kb.readf(i, func(f Frame) {
+			for _, j := range f[i+",slots"] {
+				if (i == fname || j != isa) && !Fmember(slots, j) {
+					slots = append(slots, j)
+				}
+			}
+		})
 	}
+	return slots, ferror("flists", fname, "", err)
 }
 
 // fcopys - copy a slot into another frame
 // requires that both frames exist
 // modifies fframes[fname][sname,]
-func Fcopys(fname1, sname, fname2 string) bool {
-	if Fexists(fname1, sname) && Fexistf(fname2) {
-		if !Fmember(fframes[fname2][fname2+",slots"], sname) {
-			slots := append(fframes[fname2][fname2+",slots"], sname)
-			fframes[fname2][fname2+",slots"] = slots
+func (kb *KnowledgeBase) Fcopys(fname1, sname, fname2 string) bool {
+	return kb.FcopysE(fname1, sname, fname2) == nil
+}
+
+// fcopyse - copy a slot into another frame, returning an error
+func (kb *KnowledgeBase) FcopysE(fname1, sname, fname2 string) error {
+	x := kb.copyf(fname1)
+	if x == nil {
+		return ferror("fcopys", fname1, sname, ErrFrameNotFound)
+	}
+	if !Fmember(x[fname1+",slots"], sname) {
+		return ferror("fcopys", fname1, sname, ErrSlotNotFound)
+	}
+	err := kb.writef(fname2, func(f Frame) error {
+		if !Fmember(f[fname2+",slots"], sname) {
+			slots := append(f[fname2+",slots"], sname)
+			f[fname2+",slots"] = slots
 		}
-		for k, _ := range fframes[fname1] {
+		for k, _ := range x {
 			sname2 := strings.Split(k, ",")[0]
-			if sname == sname2 {
-				copy(fframes[fname2][k], fframes[fname1][k])
+			if sname == sname2 && !fframek(fname1, k) {
+				copy(f[k], x[k])
 			}
 		}
-		return true
-	} else {
-		return false
-	}
+		return nil
+	})
+	return ferror("fcopys", fname2, sname, err)
 }
 
 // fcompares - compare a slot in two frames
 // requires that fframes[fname1][sname,facets], fframes[fname2][sname,facets] exist
-func Fcompares(fname1, sname, fname2 string) bool {
+func (kb *KnowledgeBase) Fcompares(fname1, sname, fname2 string) bool {
 	cmp := true
-	if Fexists(fname1, sname) && Fexists(fname2, sname) {
-		x := fframes[fname1][sname+",facets"]
-		y := fframes[fname2][sname+",facets"]
-		if Fequivalence(x, y) {
-			for k, _ := range fframes[fname1] {
-				sname2 := strings.Split(k, ",")[0]
-				if sname == sname2 {
-					x = fframes[fname1][k]
-					y = fframes[fname2][k]
-					if strings.Compare(strings.Join(x, ","), strings.Join(y, ",")) != 0 {
-						cmp = false
-					}
+	x := kb.slot(fname1, sname)
+	y := kb.slot(fname2, sname)
+	if x != nil && y != nil {
+		if Fequivalence(x["facets"], y["facets"]) {
+			for k, _ := range x {
+				if strings.Compare(strings.Join(x[k], ","), strings.Join(y[k], ",")) != 0 {
+					cmp = false
 				}
 			}
 			return cmp
@@ -776,23 +1423,79 @@ func Fcompares(fname1, sname, fname2 string) bool {
 
 // flistt - list of facet types in a slot
 // requires that fframes[fname][sname,facets] exists
-func Flistt(fname, sname string) []string {
-	if Fexists(fname, sname) {
-		return fframes[fname][sname+",facets"]
+func (kb *KnowledgeBase) Flistt(fname, sname string) []string {
+	facets, _ := kb.FlisttE(fname, sname)
+	return facets
+}
+
+// flistte - list of facet types in a slot, returning an error
+func (kb *KnowledgeBase) FlisttE(fname, sname string) ([]string, error) {
+	if s, err := kb.slote(fname, sname); err == nil {
+		return s["facets"], nil
 	} else {
-		return []string{}
+		return []string{}, ferror("flistt", fname, sname, err)
 	}
 }
 
+// facet helpers
+// These work on a frame which is already locked for writing.
+
+// fcreatet - add a facet of type ftype to a slot
+// requires that the slot exists and has neither ftype nor any of excl
+func fcreatet(f Frame, fname, sname, ftype string, excl ...string) error {
+	facets := f[sname+",facets"]
+	if !Fmember(f[fname+",slots"], sname) {
+		return ErrSlotNotFound
+	}
+	if Fmember(facets, ftype) {
+		return ErrFacetExists
+	}
+	for _, i := range excl {
+		if Fmember(facets, i) {
+			return ErrFacetConflict
+		}
+	}
+	f[sname+","+ftype] = []string{}
+	f[sname+",facets"] = append(facets, ftype)
+	return nil
+}
+
+// fremovet - remove a facet of type ftype from a slot
+// requires that the facet exists
+func fremovet(f Frame, sname, ftype string) error {
+	facets := f[sname+",facets"]
+	if !Fmember(facets, ftype) {
+		return ErrFacetNotFound
+	}
+	delete(f, sname+","+ftype)
+	Fremove(&facets, ftype)
+	f[sname+",facets"] = facets
+	return nil
+}
+
+// fputt - put a value in a facet of type ftype
+// requires that the facet exists
+func fputt(f Frame, sname, ftype, value string) error {
+	if !Fmember(f[sname+",facets"], ftype) {
+		return ErrFacetNotFound
+	}
+	vector := f[sname+","+ftype]
+	Putval(&vector, value)
+	f[sname+","+ftype] = vector
+	return nil
+}
+
+// getval - get the value of a facet
+// used to read a facet again after a demon has been called
+func (kb *KnowledgeBase) getval(fname, sname, ftype string) string {
+	return Getval(kb.slot(fname, sname)[ftype])
+}
+
 // fexistrx - determine if a reference facet exists (internal)
 // requires that fframes[fname][sname,facets] exists
-func Fexistrx(fname, sname string) bool {
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			return true
-		} else {
-			return false
-		}
+func (kb *KnowledgeBase) Fexistrx(fname, sname string) bool {
+	if kb.slot(fname, sname).has("ref") {
+		return true
 	} else {
 		return false
 	}
@@ -800,11 +1503,13 @@ func Fexistrx(fname, sname string) bool {
 
 // fexistr - determine if a reference facet exists
 // requires that fframes[fname][sname,facets] exists
-func Fexistr(fname, sname string) bool {
-	if Fexistrx(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ifexistr") {
-			fmethods[Getval(fframes[fname][sname+",ifexistr"])](fname)
+func (kb *KnowledgeBase) Fexistr(fname, sname string) bool {
+	if s := kb.slot(fname, sname); s.has("ref") {
+		if _, err := kb.before(s, "ifexistr", fname, sname, s["ref"], s["ref"]); err != nil {
+			return false
 		}
+		kb.fire(s, "ifexistr", fname, sname)
+		kb.after(s, "ifexistr", fname, sname, s["ref"], s["ref"])
 		return true
 	} else {
 		return false
@@ -814,154 +1519,189 @@ func Fexistr(fname, sname string) bool {
 // fcreater - create a reference facet
 // requires that fframes[fname][sname,facets] exists
 // modifies fframes[fname][sname,facets], fframes[fname][sname,ref]
-func Fcreater(fname, sname string) bool {
-	if Fexists(fname, sname) {
-		if !Fmember(fframes[fname][sname+",facets"], "ref") {
-			x := Fmember(fframes[fname][sname+",facets"], "method")
-			y := Fmember(fframes[fname][sname+",facets"], "value")
-			if !(x || y) {
-				slots := append(fframes[fname][sname+",facets"], "ref")
-				fframes[fname][sname+",facets"] = slots
-				fframes[fname][sname+",ref"] = []string{}
-				if Fmember(fframes[fname][sname+",facets"], "ifcreater") {
-					fmethods[Getval(fframes[fname][sname+",ifcreater"])](fname)
-				}
-				return true
-			} else {
-				return false
+func (kb *KnowledgeBase) Fcreater(fname, sname string) bool {
+	return kb.FcreaterE(fname, sname) == nil
+}
+
+// fcreatere - create a reference facet, returning an error
+func (kb *KnowledgeBase) FcreaterE(fname, sname string) error {
+	defer kb.group()()
+	var demon slot
+	_, err := kb.before(kb.slot(fname, sname), "ifcreater", fname, sname, nil, nil)
+	if err == nil {
+		err = kb.writef(fname, func(f Frame) error {
+			if err := fcreatet(f, fname, sname, "ref", "method", "value"); err != nil {
+				return err
 			}
-		} else {
-			return false
-		}
-	} else {
-		return false
+			demon = fslot(f, fname, sname)
+			return nil
+		})
+	}
+	if err == nil {
+		err = kb.fire(demon, "ifcreater", fname, sname)
+	}
+	if err == nil {
+		err = kb.after(demon, "ifcreater", fname, sname, nil, demon["ref"])
 	}
+	return ferror("fcreater", fname, sname, err)
 }
 
 // fremover - remove a reference facet
 // requires that fframes[fname][sname,ref] exists
 // modifies fframes[fname][sname,facets], fframes[fname][sname,ref]
 // calls ifremover demon
-func Fremover(fname, sname string) bool {
-	if Fexistrx(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ifremover") {
-			fmethods[Getval(fframes[fname][sname+",ifremover"])](fname)
-		}
-		delete(fframes[fname], sname+",ref")
-		facets := fframes[fname][sname+",facets"]
-		Fremove(&facets, "ref")
-		fframes[fname][sname+",facets"] = facets
-		return true
-	} else {
-		return false
+func (kb *KnowledgeBase) Fremover(fname, sname string) bool {
+	return kb.FremoverE(fname, sname) == nil
+}
+
+// fremovere - remove a reference facet, returning an error
+func (kb *KnowledgeBase) FremoverE(fname, sname string) error {
+	defer kb.group()()
+	s, err := kb.slote(fname, sname)
+	if err == nil {
+		if s.has("ref") {
+			if _, err = kb.before(s, "ifremover", fname, sname, s["ref"], nil); err == nil {
+				err = kb.fire(s, "ifremover", fname, sname)
+			}
+			if err == nil {
+				err = kb.writef(fname, func(f Frame) error {
+					return fremovet(f, sname, "ref")
+				})
+			}
+			if err == nil {
+				err = kb.after(s, "ifremover", fname, sname, s["ref"], nil)
+			}
+		} else {
+			err = ErrFacetNotFound
+		}
 	}
+	return ferror("fremover", fname, sname, err)
 }
 
 // fgetr - get a value from a reference facet
 // requires that fframes[fname][sname,ref] exists
 // calls ifgetr demon
-func Fgetr(fname, sname string) string {
-	if Fexistrx(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ifgetr") {
-			fmethods[Getval(fframes[fname][sname+",ifgetr"])](fname)
+func (kb *KnowledgeBase) Fgetr(fname, sname string) string {
+	r, _ := kb.FgetrE(fname, sname)
+	return r
+}
+
+// fgetre - get a value from a reference facet, returning an error
+func (kb *KnowledgeBase) FgetrE(fname, sname string) (string, error) {
+	s, err := kb.slote(fname, sname)
+	if err == nil {
+		if s.has("ref") {
+			if err = kb.fire(s, "ifgetr", fname, sname); err == nil {
+				value, err := kb.got(s, "ifgetr", fname, sname, kb.slot(fname, sname)["ref"])
+				return Getval(value), ferror("fgetr", fname, sname, err)
+			}
+		} else {
+			err = ErrFacetNotFound
 		}
-		return Getval(fframes[fname][sname+",ref"])
-	} else {
-		return ""
 	}
+	return "", ferror("fgetr", fname, sname, err)
 }
 
 // fputr - put a value in a reference facet
 // requires that ffframes[fname][sname,ref] exists
 // modifies fname(sname,ref)
 // calls ifputr demon
-func Fputr(fname1, sname, fname2 string) bool {
-	if Fexistrx(fname1, sname) {
-		ref := fframes[fname1][sname+",ref"]
-		Putval(&ref, fname2)
-		fframes[fname1][sname+",ref"] = ref
-		if Fmember(fframes[fname1][sname+",facets"], "ifputr") {
-			fmethods[Getval(fframes[fname1][sname+",ifputr"])](fname1)
+func (kb *KnowledgeBase) Fputr(fname1, sname, fname2 string) bool {
+	return kb.FputrE(fname1, sname, fname2) == nil
+}
+
+// fputre - put a value in a reference facet, returning an error
+func (kb *KnowledgeBase) FputrE(fname1, sname, fname2 string) error {
+	defer kb.group()()
+	var demon slot
+	s := kb.slot(fname1, sname)
+	c := kb.constraintp(fname1, sname)
+	value := []string{fname2}
+	if s.has("ref") {
+		var err error
+		value, err = kb.before(s, "ifputr", fname1, sname, s["ref"], value)
+		if err != nil {
+			return ferror("fputr", fname1, sname, err)
 		}
-		return true
-	} else {
-		return false
 	}
+	err := kb.writef(fname1, func(f Frame) error {
+		fown(f, fname1, sname, s)
+		if !Fmember(f[fname1+",slots"], sname) {
+			return ErrSlotNotFound
+		}
+		if err := c.check(TypeRef, value, ConstraintEnum, ConstraintPattern); err != nil {
+			return err
+		}
+		if err := fputt(f, sname, "ref", Getval(value)); err != nil {
+			return err
+		}
+		demon = fslot(f, fname1, sname)
+		return nil
+	})
+	if err == nil {
+		err = kb.fire(demon, "ifputr", fname1, sname)
+	}
+	if err == nil {
+		err = kb.after(demon, "ifputr", fname1, sname, s["ref"], demon["ref"])
+	}
+	return ferror("fputr", fname1, sname, err)
 }
 
 // flistr - list of references in a frame
 // requires that fframes[fname] exists
-func Flistr(fname string) []string {
+func (kb *KnowledgeBase) Flistr(fname string) []string {
 	flist := []string{}
-	if Fexistf(fname) {
-		for k, _ := range fframes[fname] {
+	kb.readf(fname, func(f Frame) {
+		for k, _ := range f {
 			sname := strings.Split(k, ",")[0]
 			ftype := strings.Split(k, ",")[1]
 			if ftype == "ref" {
 				flist = append(flist, sname)
 			}
 		}
-	}
+	})
 	return flist
 }
 
 // fpathr - return chain of references
+// the chain stops before a frame it has passed, or at the limit
 // requires that fframes[fname][sname,facets] exists
-func Fpathr(fname, sname string) []string {
-	plist := []string{}
-	if Fexists(fname, sname) {
-		plist := append(plist, fname)
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := Getval(fframes[fname][sname+",ref"])
-			fpathrr(fname2, sname, plist)
-		} else {
-			return plist
-		}
-	} else {
-		return plist
-	}
-	// useless return statement
+func (kb *KnowledgeBase) Fpathr(fname, sname string) []string {
+	plist, _ := kb.FpathrE(fname, sname)
 	return plist
 }
 
-// recursive fpathr (blame go)
-func fpathrr(fname string, sname string, plist []string) []string {
-	if Fexists(fname, sname) {
-		if !Fmember(plist, fname) {
-			plist = append(plist, fname)
-			if Fmember(fframes[fname][sname+",facets"], "ref") {
-				fname2 := Getval(fframes[fname][sname+",ref"])
-				fpathrr(fname2, sname, plist)
-			} else {
-				return plist
-			}
-		} else {
-			return plist
+// fpathre - return chain of references, returning an error
+// fails with ErrCycle before a frame the chain has passed and with
+// ErrDepth at the limit, as a command following the chain would,
+// returning the chain up to there
+func (kb *KnowledgeBase) FpathrE(fname, sname string) ([]string, error) {
+	plist := []string{}
+	for {
+		s, err := kb.slote(fname, sname)
+		if err == nil && !s.has("ref") {
+			return append(plist, fname), nil
 		}
-	} else {
-		return plist
+		if err == nil {
+			err = kb.fpassed(plist, fname, sname)
+		}
+		if err != nil {
+			return plist, ferror("fpathr", fname, sname, err)
+		}
+		plist = append(plist, fname)
+		fname = Getval(s["ref"])
 	}
-	// useless return statement
-	return plist
 }
 
 // fexistm - determine if a method facet exists
 // requires that fframes[fname][sname,facets] exists
 // calls ifref and ifexistm demons
-func Fexistm(fname, sname string) bool {
+func (kb *KnowledgeBase) Fexistm(fname, sname string) bool {
 	found := false
-	if Fexists(fname, sname) {
-		if Fexistrx(fname, sname) {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
-			}
-			found = Fexistm(fname2, sname)
-		}
-		if Fmember(fframes[fname][sname+",facets"], "method") {
-			if Fmember(fframes[fname][sname+",facets"], "ifexistm") {
-				fmethods[Getval(fframes[fname][sname+",ifexistm"])](fname)
-			}
+	if fname, s, err := kb.follow(fname, sname); err == nil && s.has("method") {
+		if _, err := kb.before(s, "ifexistm", fname, sname, s["method"], s["method"]); err == nil {
+			kb.fire(s, "ifexistm", fname, sname)
+			kb.after(s, "ifexistm", fname, sname, s["method"], s["method"])
 			found = true
 		}
 	}
@@ -973,31 +1713,39 @@ func Fexistm(fname, sname string) bool {
 // modifies fframes[fname][sname,facets],fframes[fname][sname,method] where fname is
 //          the original or referenced frame
 // calls ifref and ifcreatem demons
-func Fcreatem(fname, sname string) bool {
-	created := false
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "method") ||
-			Fmember(fframes[fname][sname+",facets"], "value") {
-			created = false
+func (kb *KnowledgeBase) Fcreatem(fname, sname string) bool {
+	return kb.FcreatemE(fname, sname) == nil
+}
+
+// fcreateme - create a method facet, returning an error
+func (kb *KnowledgeBase) FcreatemE(fname, sname string) error {
+	defer kb.group()()
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("method") {
+			err = ErrFacetExists
+		} else if s.has("value") {
+			err = ErrFacetConflict
 		} else {
-			if Fmember(fframes[fname][sname+",facets"], "ref") {
-				fname2 := fframes[fname][sname+",ref"][0]
-				if Fmember(fframes[fname][sname+",facets"], "ifref") {
-					fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
-				}
-				created = Fcreatem(fname2, sname)
-			} else {
-				fframes[fname][sname+",method"] = []string{}
-				facets := append(fframes[fname][sname+",facets"], "method")
-				fframes[fname][sname+",facets"] = facets
-				if Fmember(fframes[fname][sname+",facets"], "ifcreatem") {
-					fmethods[Getval(fframes[fname][sname+",ifcreatem"])](fname)
-				}
-				created = true
+			var demon slot
+			if _, err = kb.before(s, "ifcreatem", fname, sname, nil, nil); err == nil {
+				err = kb.writef(fname, func(f Frame) error {
+					if err := fcreatet(f, fname, sname, "method", "value", "ref"); err != nil {
+						return err
+					}
+					demon = fslot(f, fname, sname)
+					return nil
+				})
+			}
+			if err == nil {
+				err = kb.fire(demon, "ifcreatem", fname, sname)
+			}
+			if err == nil {
+				err = kb.after(demon, "ifcreatem", fname, sname, nil, demon["method"])
 			}
 		}
 	}
-	return created
+	return ferror("fcreatem", fname, sname, err)
 }
 
 // fremovem - remove a method facet
@@ -1005,76 +1753,67 @@ func Fcreatem(fname, sname string) bool {
 // modifies fframes[fname][sname,facets], fframes[fname][sname,method] where fname is
 //          the original or referenced frame
 // calls ifref and ifremovem demons
-func Fremovem(fname, sname string) bool {
-	removed := false
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
+func (kb *KnowledgeBase) Fremovem(fname, sname string) bool {
+	return kb.FremovemE(fname, sname) == nil
+}
+
+// fremoveme - remove a method facet, returning an error
+func (kb *KnowledgeBase) FremovemE(fname, sname string) error {
+	defer kb.group()()
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("method") {
+			if _, err = kb.before(s, "ifremovem", fname, sname, s["method"], nil); err == nil {
+				err = kb.fire(s, "ifremovem", fname, sname)
 			}
-			removed = Fremovem(fname2, sname)
-		} else {
-			if Fmember(fframes[fname][sname+",facets"], "method") {
-				if Fmember(fframes[fname][sname+",facets"], "ifremovem") {
-					fmethods[Getval(fframes[fname][sname+"ifremovem"])](fname)
-				}
-				delete(fframes[fname], sname+",method")
-				facets := fframes[fname][sname+",facets"]
-				Fremove(&facets, "method")
-				fframes[fname][sname+",facets"] = facets
-				removed = true
+			if err == nil {
+				err = kb.writef(fname, func(f Frame) error {
+					return fremovet(f, sname, "method")
+				})
+			}
+			if err == nil {
+				err = kb.after(s, "ifremovem", fname, sname, s["method"], nil)
 			}
+		} else {
+			err = s.missing("method")
 		}
 	}
-	return removed
+	return ferror("fremovem", fname, sname, err)
 }
 
 // fexecm - execute a method
-func Fexecm(fname, sname string) bool {
-	executed := false
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
-			}
-			executed = Fexecm(fname2, sname)
-		} else {
-			if Fmember(fframes[fname][sname+",facets"], "method") {
-				if Fmember(fframes[fname][sname+",facets"], "ifexecm") {
-					fmethods[Getval(fframes[fname][sname+",ifexecm"])](fname)
-				}
-				fmethods[Getval(fframes[fname][sname+",method"])](fname)
-				executed = true
-			}
-		}
-	}
-	return executed
+func (kb *KnowledgeBase) Fexecm(fname, sname string) bool {
+	return kb.FexecmE(fname, sname) == nil
+}
+
+// fexecme - execute a method, returning an error
+func (kb *KnowledgeBase) FexecmE(fname, sname string) error {
+	_, err := kb.Fexecma(fname, sname)
+	return err
 }
 
 // fgetm - get a value from a method
 // requires that fframes[fname][sname,facets] exists
 // calls ifref and ifexecm demons
-func Fgetm(fname string, sname string) string {
-	pname := ""
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
+func (kb *KnowledgeBase) Fgetm(fname string, sname string) string {
+	pname, _ := kb.FgetmE(fname, sname)
+	return pname
+}
+
+// fgetme - get a value from a method, returning an error
+func (kb *KnowledgeBase) FgetmE(fname string, sname string) (string, error) {
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("method") {
+			if err = kb.fire(s, "ifgetm", fname, sname); err == nil {
+				value, err := kb.got(s, "ifgetm", fname, sname, kb.slot(fname, sname)["method"])
+				return Getval(value), ferror("fgetm", fname, sname, err)
 			}
-			pname = Fgetm(fname2, sname)
 		} else {
-			if Fmember(fframes[fname][sname+",facets"], "method") {
-				if Fmember(fframes[fname][sname+",facets"], "ifgetm") {
-					fmethods[Getval(fframes[fname][sname+",ifgetm"])](fname)
-				}
-				pname = Getval(fframes[fname][sname+",method"])
-			}
+			err = s.missing("method")
 		}
 	}
-	return pname
+	return "", ferror("fgetm", fname, sname, err)
 }
 
 // fputm - put a value in a method facet
@@ -1082,47 +1821,46 @@ func Fgetm(fname string, sname string) string {
 // modifies fframes[fname][sname,method] where fname is the original or
 //          referenced frame
 // calls ifref and ifputm demons
-func Fputm(fname, sname, args string) bool {
-	put := false
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
+func (kb *KnowledgeBase) Fputm(fname, sname, args string) bool {
+	return kb.FputmE(fname, sname, args) == nil
+}
+
+// fputme - put a value in a method facet, returning an error
+func (kb *KnowledgeBase) FputmE(fname, sname, args string) error {
+	defer kb.group()()
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("method") {
+			value, err := kb.before(s, "ifputm", fname, sname, s["method"], []string{args})
+			if err == nil {
+				err = kb.fire(s, "ifputm", fname, sname)
 			}
-			put = Fputm(fname2, sname, args)
-		} else {
-			if Fmember(fframes[fname][sname+",facets"], "method") {
-				if Fmember(fframes[fname][sname+",facets"], "ifputm") {
-					fmethods[Getval(fframes[fname][sname+",ifputm"])](fname)
-				}
-				method := fframes[fname][sname+",method"]
-				Putval(&method, args)
-				fframes[fname][sname+",method"] = method
-				put = true
+			if err == nil {
+				err = kb.writef(fname, func(f Frame) error {
+					fown(f, fname, sname, s)
+					return fputt(f, sname, "method", Getval(value))
+				})
 			}
+			if err == nil {
+				err = kb.after(s, "ifputm", fname, sname, s["method"], kb.slot(fname, sname)["method"])
+			}
+			return ferror("fputm", fname, sname, err)
+		} else {
+			err = s.missing("method")
 		}
 	}
-	return put
+	return ferror("fputm", fname, sname, err)
 }
 
 // fexistv - determine if a value facet exists
 // requires that fframes[fname][sname,facets] exists
 // calls ifref and ifexistv demons
-func Fexistv(fname, sname string) bool {
+func (kb *KnowledgeBase) Fexistv(fname, sname string) bool {
 	found := false
-	if Fexists(fname, sname) {
-		if Fexistrx(fname, sname) {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
-			}
-			found = Fexistv(fname2, sname)
-		}
-		if Fmember(fframes[fname][sname+",facets"], "value") {		
-			if Fmember(fframes[fname][sname+",facets"], "ifexistmv") {
-				fmethods[Getval(fframes[fname][sname+",ifexistv"])](fname)
-			}
+	if fname, s, err := kb.follow(fname, sname); err == nil && s.has("value") {
+		if _, err := kb.before(s, "ifexistv", fname, sname, s["value"], s["value"]); err == nil {
+			kb.fire(s, "ifexistv", fname, sname)
+			kb.after(s, "ifexistv", fname, sname, s["value"], s["value"])
 			found = true
 		}
 	}
@@ -1134,31 +1872,39 @@ func Fexistv(fname, sname string) bool {
 // modifies fframes[fname][sname,facets],fframes[fname][sname,value] where fname is
 //          the original or referenced frame
 // calls ifref and ifcreatev demons
-func Fcreatev(fname, sname string) bool {
-	created := false
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "method") ||
-			Fmember(fframes[fname][sname+",facets"], "value") {
-			created = false
+func (kb *KnowledgeBase) Fcreatev(fname, sname string) bool {
+	return kb.FcreatevE(fname, sname) == nil
+}
+
+// fcreateve - create a value facet, returning an error
+func (kb *KnowledgeBase) FcreatevE(fname, sname string) error {
+	defer kb.group()()
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("value") {
+			err = ErrFacetExists
+		} else if s.has("method") {
+			err = ErrFacetConflict
 		} else {
-			if Fmember(fframes[fname][sname+",facets"], "ref") {
-				fname2 := fframes[fname][sname+",ref"][0]
-				if Fmember(fframes[fname][sname+",facets"], "ifref") {
-					fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
-				}
-				created = Fcreatev(fname2, sname)
-			} else {
-				fframes[fname][sname+",value"] = []string{}
-				facets := append(fframes[fname][sname+",facets"], "value")
-				fframes[fname][sname+",facets"] = facets
-				if Fmember(fframes[fname][sname+",facets"], "ifcreatev") {
-					fmethods[Getval(fframes[fname][sname+",ifcreatev"])](fname)
-				}
-				created = true
+			var demon slot
+			if _, err = kb.before(s, "ifcreatev", fname, sname, nil, nil); err == nil {
+				err = kb.writef(fname, func(f Frame) error {
+					if err := fcreatet(f, fname, sname, "value", "method", "ref"); err != nil {
+						return err
+					}
+					demon = fslot(f, fname, sname)
+					return nil
+				})
+			}
+			if err == nil {
+				err = kb.fire(demon, "ifcreatev", fname, sname)
+			}
+			if err == nil {
+				err = kb.after(demon, "ifcreatev", fname, sname, nil, demon["value"])
 			}
 		}
 	}
-	return created
+	return ferror("fcreatev", fname, sname, err)
 }
 
 // fremovev - remove a value facet
@@ -1166,53 +1912,84 @@ func Fcreatev(fname, sname string) bool {
 // modifies fframes[fname][sname,facets],fframes[fname][sname,value] where fname is
 //          the original or referenced frame
 // calls ifref and ifremovev demons
-func Fremovev(fname, sname string) bool {
-	removed := false
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
+func (kb *KnowledgeBase) Fremovev(fname, sname string) bool {
+	return kb.FremovevE(fname, sname) == nil
+}
+
+// fremoveve - remove a value facet, returning an error
+func (kb *KnowledgeBase) FremovevE(fname, sname string) error {
+	defer kb.group()()
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("value") {
+			if _, ok := kb.constraintp(fname, sname)[ConstraintRequired]; ok {
+				err = fmt.Errorf("%w: value is required", ErrConstraint)
+			} else if _, err = kb.before(s, "ifremovev", fname, sname, s["value"], nil); err == nil {
+				err = kb.fire(s, "ifremovev", fname, sname)
 			}
-			removed = Fremovev(fname2, sname)
-		} else {
-			if Fmember(fframes[fname][sname+",facets"], "value") {
-				if Fmember(fframes[fname][sname+",facets"], "ifremovev") {
-					fmethods[Getval(fframes[fname][sname+"ifremovev"])](fname)
-				}
-				delete(fframes[fname], sname+",value")
-				facets := fframes[fname][sname+",facets"]
-				Fremove(&facets, "value")
-				fframes[fname][sname+",facets"] = facets
-				removed = true
+			if err == nil {
+				err = kb.writef(fname, func(f Frame) error {
+					return fremovet(f, sname, "value")
+				})
 			}
+			if err == nil {
+				err = kb.after(s, "ifremovev", fname, sname, s["value"], nil)
+			}
+		} else {
+			err = s.missing("value")
 		}
 	}
-	return removed
+	return ferror("fremovev", fname, sname, err)
 }
 
 // fgetv - get a value from a value facet
 // requires that fframes[fname][sname,facets] exists
 // calls ifref and ifgetv demons
-func Fgetv(fname string, sname string) string {
-	pname := ""
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
+func (kb *KnowledgeBase) Fgetv(fname string, sname string) string {
+	pname, _ := kb.FgetvE(fname, sname)
+	return pname
+}
+
+// fgetve - get a value from a value facet, returning an error
+func (kb *KnowledgeBase) FgetvE(fname string, sname string) (string, error) {
+	value, err := kb.getvl("fgetv", fname, sname)
+	return Getval(value), err
+}
+
+// getvl - get the list in a value facet
+// a slot without values gets them from its ifneeded demon or default
+// follows references and calls ifref, ifgetv and ifneeded demons
+func (kb *KnowledgeBase) getvl(op, fname, sname string) ([]string, error) {
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("value") {
+			if err = kb.fire(s, "ifgetv", fname, sname); err == nil {
+				value := kb.slot(fname, sname)["value"]
+				if len(value) == 0 {
+					var found bool
+					if value, found, err = kb.needed(fname, sname, s); !found {
+						value = []string{}
+					}
+				}
+				if err == nil {
+					value, err = kb.got(s, "ifgetv", fname, sname, value)
+				}
+				return value, ferror(op, fname, sname, err)
 			}
-			pname = Fgetv(fname2, sname)
+		} else if s.has("method") {
+			err = s.missing("value")
 		} else {
-			if Fmember(fframes[fname][sname+",facets"], "value") {
-				if Fmember(fframes[fname][sname+",facets"], "ifgetv") {
-					fmethods[Getval(fframes[fname][sname+",ifgetv"])](fname)
+			value, found, err := kb.needed(fname, sname, s)
+			if found {
+				if err == nil {
+					value, err = kb.got(s, "ifgetv", fname, sname, value)
 				}
-				pname = Getval(fframes[fname][sname+",value"])
+				return value, ferror(op, fname, sname, err)
 			}
+			err = s.missing("value")
 		}
 	}
-	return pname
+	return []string{}, ferror(op, fname, sname, err)
 }
 
 // fputv - put a value in a value facet
@@ -1220,39 +1997,95 @@ func Fgetv(fname string, sname string) string {
 // modifies fframes[fname][sname,value] where fname is the original or
 //          referenced frame
 // calls ifref and ifputv demons
-func Fputv(fname, sname, args string) bool {
-	put := false
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], "ref") {
-			fname2 := fframes[fname][sname+",ref"][0]
-			if Fmember(fframes[fname][sname+",facets"], "ifref") {
-				fmethods[Getval(fframes[fname][sname+",ifref"])](fname)
+func (kb *KnowledgeBase) Fputv(fname, sname, args string) bool {
+	return kb.FputvE(fname, sname, args) == nil
+}
+
+// fputve - put a value in a value facet, returning an error
+func (kb *KnowledgeBase) FputvE(fname, sname, args string) error {
+	return kb.putv("fputv", fname, sname, func(value []string) ([]string, error) {
+		Putval(&value, args)
+		return value, nil
+	})
+}
+
+// putv - change the list in a value facet
+// fn gets the current list and returns the new one, which must match
+// the type and constraints of the slot, if it has any
+// follows references and calls ifref and ifputv demons
+func (kb *KnowledgeBase) putv(op, fname, sname string, fn func([]string) ([]string, error)) error {
+	defer kb.group()()
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("value") {
+			if s.phased("ifputv") {
+				fn, err = kb.beforev(s, fname, sname, fn)
 			}
-			put = Fputv(fname2, sname, args)
-		} else {
-			if Fmember(fframes[fname][sname+",facets"], "value") {
-				if Fmember(fframes[fname][sname+",facets"], "ifputm") {
-					fmethods[Getval(fframes[fname][sname+",ifputm"])](fname)
+			if err == nil {
+				err = kb.checkr(s, fn)
+			}
+			if err == nil {
+				if err = kb.fire(s, "ifputv", fname, sname); err == nil {
+					var old, new []string
+					c := kb.constraintp(fname, sname)
+					err = kb.writef(fname, func(f Frame) error {
+						fown(f, fname, sname, s)
+						if !Fmember(f[sname+",facets"], "value") {
+							return ErrFacetNotFound
+						}
+						// fn gets a copy, so a rejected put leaves the values alone
+						vtype := Getval(f[sname+",type"])
+						value, err := fn(append([]string{}, f[sname+",value"]...))
+						if err == nil {
+							value, err = fnormv(vtype, value)
+						}
+						if err == nil {
+							err = c.check(vtype, value)
+						}
+						if err == nil {
+							old, new = f[sname+",value"], value
+							f[sname+",value"] = value
+						}
+						return err
+					})
+					if err == nil {
+						err = kb.after(s, "ifputv", fname, sname, old, new)
+					}
 				}
-				value := fframes[fname][sname+",value"]
-				Putval(&value, args)
-				fframes[fname][sname+",value"] = value
-				put = true
 			}
+		} else {
+			err = s.missing("value")
+		}
+	}
+	return ferror(op, fname, sname, err)
+}
+
+// follow - follow the references in a slot
+// returns the frame at the end of the chain and a copy of its slot
+// fails on a cycle or a chain longer than the limit
+// calls ifref demons
+func (kb *KnowledgeBase) follow(fname, sname string) (string, slot, error) {
+	plist := []string{}
+	for {
+		s, err := kb.slote(fname, sname)
+		if err != nil || !s.has("ref") {
+			return fname, s, err
+		}
+		if err = kb.fpassed(plist, fname, sname); err != nil {
+			return fname, s, err
+		}
+		plist = append(plist, fname)
+		if fname, err = kb.ref(s, fname, sname); err != nil {
+			return fname, s, err
 		}
 	}
-	return put
 }
 
 // fexistd - determine if a demon facet exists
 // requires that fframes[fname][sname,facets] exists
-func Fexistd(fname, sname, dname string) bool {
-	if Fexists(fname, sname) {
-		if Fmember(fframes[fname][sname+",facets"], dname) {
-			return true
-		} else {
-			return false
-		}
+func (kb *KnowledgeBase) Fexistd(fname, sname, dname string) bool {
+	if kb.slot(fname, sname).has(dname) {
+		return true
 	} else {
 		return false
 	}
@@ -1261,352 +2094,498 @@ func Fexistd(fname, sname, dname string) bool {
 // fcreated - create a demon facet
 // requires that fframes[fname][sname,facets] exists
 // modifies fframes[fname][sname,facets],fframes[fname][sname,dname]
-func Fcreated(fname, sname, dname string) bool {
-	if Fexists(fname, sname) {
-		if !Fmember(fframes[fname][sname+",facets"], dname) {
-			fframes[fname][sname+","+dname] = []string{}
-			facets := append(fframes[fname][sname+",facets"], dname)
-			fframes[fname][sname+",facets"] = facets
-			return true
-		} else {
-			return false
-		}
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fcreated(fname, sname, dname string) bool {
+	return kb.FcreatedE(fname, sname, dname) == nil
+}
+
+// fcreatede - create a demon facet, returning an error
+func (kb *KnowledgeBase) FcreatedE(fname, sname, dname string) error {
+	err := kb.writef(fname, func(f Frame) error {
+		return fcreatet(f, fname, sname, dname)
+	})
+	return ferror("fcreated", fname, sname, err)
 }
 
 // fremoved - remove a demon facet
 // requires that fframes[fname][sname,dname] exists
 // modifies fframes[fname][sname,facets],fframes[fname][sname,dname]
-func Fremoved(fname, sname, dname string) bool {
-	if Fexistd(fname, sname, dname) {
-		delete(fframes[fname], sname+","+dname)
-		facets:= fframes[fname][sname+",facets"]
-		Fremove(&facets, dname)
-		fframes[fname][sname+",facets"] = facets
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fremoved(fname, sname, dname string) bool {
+	return kb.FremovedE(fname, sname, dname) == nil
+}
+
+// fremovede - remove a demon facet, returning an error
+func (kb *KnowledgeBase) FremovedE(fname, sname, dname string) error {
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[fname+",slots"], sname) {
+			return ErrSlotNotFound
+		}
+		return fremovet(f, sname, dname)
+	})
+	return ferror("fremoved", fname, sname, err)
 }
 
 // fgetd - get a value from a demon facet
 // requires that fframes[fname][sname,dname] exists
-func Fgetd(fname, sname, dname string) string {
-	if Fexistd(fname, sname, dname) {
-		return Getval(fframes[fname][sname+","+dname])
-	} else {
-		return ""
+func (kb *KnowledgeBase) Fgetd(fname, sname, dname string) string {
+	mname, _ := kb.FgetdE(fname, sname, dname)
+	return mname
+}
+
+// fgetde - get a value from a demon facet, returning an error
+func (kb *KnowledgeBase) FgetdE(fname, sname, dname string) (string, error) {
+	s, err := kb.slote(fname, sname)
+	if err == nil {
+		if s.has(dname) {
+			return s.demon(dname), nil
+		}
+		err = ErrFacetNotFound
 	}
+	return "", ferror("fgetd", fname, sname, err)
 }
 
 // fputd - put a value in a demon facet
 // requires that fframes[fname][sname,dname] exists
 // modifies fframes[fname][sname,dname]
-func Fputd(fname, sname, dname, args string) bool {
-	if Fexistd(fname, sname, dname) {
-		demon := fframes[fname][sname+","+dname]
-		Putval(&demon, args)
-		fframes[fname][sname+","+dname] = demon
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fputd(fname, sname, dname, args string) bool {
+	return kb.FputdE(fname, sname, dname, args) == nil
+}
+
+// fputde - put a value in a demon facet, returning an error
+func (kb *KnowledgeBase) FputdE(fname, sname, dname, args string) error {
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[fname+",slots"], sname) {
+			return ErrSlotNotFound
+		}
+		if err := fputt(f, sname, dname, args); err != nil {
+			return err
+		}
+		// the method put in place of the first demon has priority 0
+		if vector := f[sname+","+dname]; fdemont(dname) && dname != "ifneeded" && len(vector) > 1 {
+			vector[1] = "0"
+		}
+		return nil
+	})
+	return ferror("fputd", fname, sname, err)
 }
 
 // fexecd - directly execute a demon
 // requires that fframes[fname][sname,dname] exists
-func Fexecd(fname, sname, dname string) bool {
-	if Fexistd(fname, sname, dname) {
-		fmethods[Getval(fframes[fname][sname+","+dname])](fname)
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fexecd(fname, sname, dname string) bool {
+	return kb.FexecdE(fname, sname, dname) == nil
+}
+
+// fexecde - directly execute a demon, returning an error
+func (kb *KnowledgeBase) FexecdE(fname, sname, dname string) error {
+	_, err := kb.Fexecda(fname, sname, dname)
+	return err
 }
 
 // fcreatefs - create a frameset
 // requires that fframes[name] does not exist
 // modifies fframes[name][name,set], fframes[name][name,slots]
-func Fcreatefs(name string) bool {
-	if !Fexistf(name) {
-		fframes[name] = Frame{name + ",slots": {}}
-		fframes[name][name+",set"] = []string{}
-		return true
-	} else {
-		return false
+func (kb *KnowledgeBase) Fcreatefs(name string) bool {
+	return kb.FcreatefsE(name) == nil
+}
+
+// fcreatefse - create a frameset, returning an error
+func (kb *KnowledgeBase) FcreatefsE(name string) error {
+	if err := kb.setf(name, Frame{name + ",slots": {}, name + ",set": {}}, true, false); err != nil {
+		return ferror("fcreatefs", name, "", err)
 	}
+	return nil
 }
 
 // fremovefs - remove a frameset
 // requires that fframes[name] exists
 // modifies fframes[name]
-func Fremovefs(name string) bool {
-	if Fremovef(name) {
+func (kb *KnowledgeBase) Fremovefs(name string) bool {
+	if kb.Fremovef(name) {
 		return true
 	} else {
 		return false
 	}
 }
 
+// fremovefse - remove a frameset, returning an error
+func (kb *KnowledgeBase) FremovefsE(name string) error {
+	return kb.FremovefE(name)
+}
+
 // fslistf - return a list of frames in a frameset
 // requires that fframes[name] exists
-func Fslistf(name string) []string {
-	if Fexistf(name) {
-		return fframes[name][name+",set"]
-	} else {
-		return []string{}
+func (kb *KnowledgeBase) Fslistf(name string) []string {
+	s, _ := kb.FslistfE(name)
+	return s
+}
+
+// fslistfe - return a list of frames in a frameset, returning an error
+func (kb *KnowledgeBase) FslistfE(name string) ([]string, error) {
+	s := []string{}
+	if !kb.readf(name, func(f Frame) {
+		s = append(s, f[name+",set"]...)
+	}) {
+		return s, ferror("fslistf", name, "", ErrFrameNotFound)
 	}
+	return s, nil
 }
 
 // floadfs - load a frameset into memory
 // requires that fframes[name] exists on disk, but not in memory
-func Floadfs(name string) bool {
-	if Floadf(name) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Floadf(i)
+func (kb *KnowledgeBase) Floadfs(name string) bool {
+	return kb.FloadfsE(name) == nil
+}
+
+// floadfse - load a frameset into memory, returning an error
+// members which are already in memory are left alone
+func (kb *KnowledgeBase) FloadfsE(name string) error {
+	defer kb.group()()
+	if kb.Fexistf(name) {
+		return ferror("floadfs", name, "", ErrFrameExists)
+	}
+	storage := kb.GetStorage()
+	x, err := storage.Load(name)
+	if err != nil {
+		return ferror("floadfs", name, "", err)
+	}
+	s := []string{}
+	for _, i := range x[name+",set"] {
+		if !kb.Fexistf(i) {
+			s = append(s, i)
+		}
+	}
+	frames, err := storage.LoadAll(s)
+	if err != nil {
+		return ferror("floadfs", name, "", err)
+	}
+	frames = append(frames, x)
+	s = append(s, name)
+	for i, f := range frames {
+		if err := fcheckt(f, s[i]); err != nil {
+			return err
+		}
+	}
+	for i, f := range frames {
+		if err := kb.setf(s[i], f, true, true); err != nil {
+			return ferror("floadfs", s[i], "", err)
 		}
-		return true
-	} else {
-		return false
 	}
+	return nil
 }
 
 // fstorefs - store a frameset on disk
 // requires that fframes[name] exists
-func Fstorefs(name string) bool {
-	if Fstoref(name) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fstoref(i)
+func (kb *KnowledgeBase) Fstorefs(name string) bool {
+	return kb.FstorefsE(name) == nil
+}
+
+// fstorefse - store a frameset on disk, returning an error
+// either the frameset and all of its members are stored or none is
+func (kb *KnowledgeBase) FstorefsE(name string) error {
+	s, err := kb.FslistfE(name)
+	if err != nil {
+		return ferror("fstorefs", name, "", err)
+	}
+	frames := []Frame{}
+	s = append([]string{name}, s...)
+	for _, i := range s {
+		x := kb.copyf(i)
+		if x == nil {
+			return ferror("fstorefs", i, "", ErrFrameNotFound)
 		}
-		return true
-	} else {
-		return false
+		frames = append(frames, x)
 	}
+	return ferror("fstorefs", name, "", kb.GetStorage().StoreAll(s, frames))
 }
 
 // fsincludef - include a frame in a frameset
 // requires that fframes[name] exists
 // modifies fframes[name][name,set]
-func Fsincludef(name, fname string) bool {
-	if Fexistf(name) && Fexistf(fname) {
-		set := append(fframes[name][name+",set"], fname)
-		fframes[name][name+",set"] = set
-		return true
+// calls ifincludef demons
+func (kb *KnowledgeBase) Fsincludef(name, fname string) bool {
+	return kb.FsincludefE(name, fname) == nil
+}
+
+// fsincludefe - include a frame in a frameset, returning an error
+// the frame must satisfy the constraints of the slots of the frameset
+func (kb *KnowledgeBase) FsincludefE(name, fname string) error {
+	defer kb.group()()
+	if !kb.Fexistf(name) {
+		return ferror("fsincludef", name, "", ErrFrameNotFound)
+	}
+	if kb.Fexistf(fname) {
+		if slots, err := kb.FlistsE(name); err == nil {
+			if err := kb.checkf("fsincludef", fname, name, slots); err != nil {
+				return err
+			}
+		}
+		if err := kb.firef("beforeincludef", fname, "", []string{name}, name); err != nil {
+			return ferror("fsincludef", name, "", err)
+		}
+		err := kb.writef(name, func(f Frame) error {
+			set := append(f[name+",set"], fname)
+			f[name+",set"] = set
+			return nil
+		})
+		if err == nil {
+			err = kb.firef("ifincludef", fname, "", []string{name}, name)
+		}
+		if err == nil {
+			err = kb.firef("afterincludef", fname, "", []string{name}, name)
+		}
+		return ferror("fsincludef", name, "", err)
 	} else {
-		return false
+		return ferror("fsincludef", fname, "", ErrFrameNotFound)
 	}
 }
 
 // fsexcludef - exclude a frame from a frameset
 // requires that fframes[name] exists
 // modifies fframes[name][name,set]
-func Fsexcludef(name, fname string) bool {
-	if Fexistf(name) {
-		if Fmember(fframes[name][name+",set"], fname) {
-			set := fframes[name][name+",set"]
+// calls ifexcludef demons
+func (kb *KnowledgeBase) Fsexcludef(name, fname string) bool {
+	return kb.FsexcludefE(name, fname) == nil
+}
+
+// fsexcludefe - exclude a frame from a frameset, returning an error
+func (kb *KnowledgeBase) FsexcludefE(name, fname string) error {
+	defer kb.group()()
+	member := false
+	kb.readf(name, func(f Frame) {
+		member = Fmember(f[name+",set"], fname)
+	})
+	if member {
+		if err := kb.firef("beforeexcludef", fname, "", []string{name}, name); err != nil {
+			return ferror("fsexcludef", name, "", err)
+		}
+		if err := kb.firef("ifexcludef", fname, "", []string{name}, name); err != nil {
+			return ferror("fsexcludef", name, "", err)
+		}
+	}
+	err := kb.writef(name, func(f Frame) error {
+		if Fmember(f[name+",set"], fname) {
+			set := f[name+",set"]
 			Fremove(&set, fname)
-			fframes[name][name+",set"] = set
-			return true
+			f[name+",set"] = set
+			return nil
 		} else {
-			return false
+			return ErrNotMember
 		}
-	} else {
-		return false
+	})
+	if err == nil {
+		err = kb.firef("afterexcludef", fname, "", []string{name}, name)
+	}
+	return ferror("fsexcludef", name, "", err)
+}
+
+// fsall - apply a function to a frameset and then to each of its members
+// failures on members are ignored, only the frameset itself counts
+func (kb *KnowledgeBase) fsall(name string, fn func(fname string) error) error {
+	defer kb.group()()
+	if err := fn(name); err != nil {
+		return err
+	}
+	s := kb.Fslistf(name)
+	for _, i := range s {
+		fn(i)
 	}
+	return nil
 }
 
 // fscreates - create a slot in a frameset
 // requires that fframes[name] exists
 // modifies fframes[name][name,slots], fframes[name][sname,facets], associated frames
-func Fscreates(name, sname string) bool {
-	if Fcreates(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fcreates(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fscreates(name, sname string) bool {
+	return kb.FscreatesE(name, sname) == nil
+}
+
+// fscreatese - create a slot in a frameset, returning an error
+func (kb *KnowledgeBase) FscreatesE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FcreatesE(fname, sname)
+	})
 }
 
 // fsremoves - remove a slot from a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][name,slots], fframes[name][sname,], associated frames
-func Fsremoves(name, sname string) bool {
-	if Fremoves(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fremoves(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fsremoves(name, sname string) bool {
+	return kb.FsremovesE(name, sname) == nil
+}
+
+// fsremovese - remove a slot from a frameset, returning an error
+func (kb *KnowledgeBase) FsremovesE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FremovesE(fname, sname)
+	})
 }
 
 // fscreated - create a demon facet in a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][sname,facets], fframes[name][sname,dname], associated frames
-func Fscreated(name, sname, dname string) bool {
-	if Fcreated(name, sname, dname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fcreated(i, sname, dname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fscreated(name, sname, dname string) bool {
+	return kb.FscreatedE(name, sname, dname) == nil
+}
+
+// fscreatede - create a demon facet in a frameset, returning an error
+func (kb *KnowledgeBase) FscreatedE(name, sname, dname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FcreatedE(fname, sname, dname)
+	})
 }
 
 // fsremoved - remove a demon facet from a frameset
 // requires that fframes[name][sname,dname] exists
 // modifies fframes[name][name,slots], fframes[name][sname,dname], associated frames
-func Fsremoved(name, sname, dname string) bool {
-	if Fremoved(name, sname, dname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fremoved(i, sname, dname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fsremoved(name, sname, dname string) bool {
+	return kb.FsremovedE(name, sname, dname) == nil
+}
+
+// fsremovede - remove a demon facet from a frameset, returning an error
+func (kb *KnowledgeBase) FsremovedE(name, sname, dname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FremovedE(fname, sname, dname)
+	})
 }
 
 // fscreatem - create a method facet in a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][sname,facets], fframes[name][sname,method], associated frames
-func Fscreatem(name, sname string) bool {
-	if Fcreatem(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fcreatem(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fscreatem(name, sname string) bool {
+	return kb.FscreatemE(name, sname) == nil
+}
+
+// fscreateme - create a method facet in a frameset, returning an error
+func (kb *KnowledgeBase) FscreatemE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FcreatemE(fname, sname)
+	})
 }
 
 // fsremovem - remove a method facet from a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][sname,facets], fframes[name][sname,method], associated frames
-func Fsremovem(name, sname string) bool {
-	if Fremovem(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fremovem(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fsremovem(name, sname string) bool {
+	return kb.FsremovemE(name, sname) == nil
+}
+
+// fsremoveme - remove a method facet from a frameset, returning an error
+func (kb *KnowledgeBase) FsremovemE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FremovemE(fname, sname)
+	})
 }
 
 // fscreater - create a reference facet in a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][sname,facets], fframes[name][sname,ref], associated frames
-func Fscreater(name, sname string) bool {
-	if Fcreater(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fcreater(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fscreater(name, sname string) bool {
+	return kb.FscreaterE(name, sname) == nil
+}
+
+// fscreatere - create a reference facet in a frameset, returning an error
+func (kb *KnowledgeBase) FscreaterE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FcreaterE(fname, sname)
+	})
 }
 
 // fsremover - remove a reference facet from a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][sname,facets], fframes[name][sname,ref], associated frames
-func Fsremover(name, sname string) bool {
-	if Fremover(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fremover(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fsremover(name, sname string) bool {
+	return kb.FsremoverE(name, sname) == nil
+}
+
+// fsremovere - remove a reference facet from a frameset, returning an error
+func (kb *KnowledgeBase) FsremoverE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FremoverE(fname, sname)
+	})
 }
 
 // fscreatev - create a value facet in a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][sname,facets], fframes[name][sname,value], associated frames
-func Fscreatev(name, sname string) bool {
-	if Fcreatev(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fcreatev(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fscreatev(name, sname string) bool {
+	return kb.FscreatevE(name, sname) == nil
+}
+
+// fscreateve - create a value facet in a frameset, returning an error
+func (kb *KnowledgeBase) FscreatevE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FcreatevE(fname, sname)
+	})
 }
 
 // fsremovev - remove a value facet from of a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies fframes[name][sname,facets], fframes[name][sname,value], associated frames
-func Fsremovev(name, sname string) bool {
-	if Fremovev(name, sname) {
-		s := Fslistf(name)
-		for _, i := range s {
-			Fremovev(i, sname)
-		}
-		return true
-	} else {
-		return false
-	}
+func (kb *KnowledgeBase) Fsremovev(name, sname string) bool {
+	return kb.FsremovevE(name, sname) == nil
+}
+
+// fsremoveve - remove a value facet from a frameset, returning an error
+func (kb *KnowledgeBase) FsremovevE(name, sname string) error {
+	return kb.fsall(name, func(fname string) error {
+		return kb.FremovevE(fname, sname)
+	})
 }
 
 // fsputr - put a value in reference facet in a frameset
 // requires that fframes[name][sname,facets] exists
 // modifies the fframes[name][sname,ref]
-func Fsputr(name, sname, fname string) bool {
-	if Fexistr(name, sname) {
-		Fputr(name, sname, fname)
-		s := Fslistf(name)
-		for _, i := range s {
-			Fputr(i, sname, fname)
-		}
-		return true
-	} else {
-		return false
+func (kb *KnowledgeBase) Fsputr(name, sname, fname string) bool {
+	return kb.FsputrE(name, sname, fname) == nil
+}
+
+// fsputre - put a value in reference facet in a frameset, returning an error
+func (kb *KnowledgeBase) FsputrE(name, sname, fname string) error {
+	if !kb.Fexistr(name, sname) {
+		return kb.facete("fsputr", name, sname, "ref")
 	}
+	return kb.fsall(name, func(fname2 string) error {
+		return kb.FputrE(fname2, sname, fname)
+	})
 }
 
 // fsgetr - get a value from a reference facet in a frameset
 // requires that fframes[name][sname,ref] exists
-func Fsgetr(name, sname string) string {
-	if Fexistr(name, sname) {
-		r := Fgetr(name, sname)
-		return r
+func (kb *KnowledgeBase) Fsgetr(name, sname string) string {
+	r, _ := kb.FsgetrE(name, sname)
+	return r
+}
+
+// fsgetre - get a value from a reference facet in a frameset, returning an error
+func (kb *KnowledgeBase) FsgetrE(name, sname string) (string, error) {
+	if kb.Fexistr(name, sname) {
+		return kb.FgetrE(name, sname)
 	} else {
-		return ""
+		return "", kb.facete("fsgetr", name, sname, "ref")
 	}
 }
 
+// facete - error saying why a slot does not have a facet
+func (kb *KnowledgeBase) facete(op, fname, sname, ftype string) error {
+	s, err := kb.slote(fname, sname)
+	if err == nil && !s.has(ftype) {
+		err = ErrFacetNotFound
+	}
+	return ferror(op, fname, sname, err)
+}
+
 // fsmemberf - get list of framesets in which a frame is a member
 // requires that the frame exists
-func Fsmemberf(name string) []string {
+func (kb *KnowledgeBase) Fsmemberf(name string) []string {
 	mlist := []string{}
-	if Fexistf(name) {
-		for _, i := range Flistf() {
-			if _, err := fframes[i][i+",set"]; err {
-				if Fmember(Fslistf(i), name) {
-					mlist = append(mlist, i)
+	if kb.Fexistf(name) {
+		for _, i := range kb.Flistf() {
+			kb.readf(i, func(f Frame) {
+				if _, err := f[i+",set"]; err {
+					if Fmember(f[i+",set"], name) {
+						mlist = append(mlist, i)
+					}
 				}
-			}
+			})
 		}
 		return mlist
 	} else {
@@ -0,0 +1,314 @@
+/**********************************************************************
+ *
+ * file name:    history.go
+ * description:  undo and redo of changes to frames
+ *
+ * A knowledge base may keep a history of the changes made to its
+ * frames, so they can be undone and redone. Every change is recorded
+ * together with what it replaced: the elements of a frame put or
+ * removed, and frames created, loaded, replaced or removed. Undo puts
+ * the frames back as they were before the last step, and Redo makes
+ * the step again.
+ *
+ * A step is normally one change to one frame. Commands which change
+ * many frames, such as the frameset commands, which change the frameset
+ * and each of its members, commands firing demons, and committing a
+ * transaction, make a single step of everything they change, and Group
+ * does the same for any function. Mark puts a named mark between two
+ * steps, and UndoTo undoes every step after the mark.
+ *
+ * The history keeps at most the number of steps given to SetHistory,
+ * dropping the oldest. Making a new step throws away the steps which
+ * could be redone. The history is meant for a single editor: steps are
+ * not told apart by goroutine, and changes made by another goroutine
+ * while a group is open or a step is undone end up in that step or
+ * are not recorded. Undo, Redo and UndoTo therefore fail with ErrBusy
+ * while a group is open or another undo or redo is under way, rather
+ * than undo half a step. Methods are not part of the history.
+ *
+ *							Functions
+ *
+ * Group					make a single step of the changes made by a function
+ * Mark						put a named mark in the history
+ * Redo						make the last undone step again
+ * SetHistory				keep a history of a number of steps
+ * Undo						undo the last step
+ * UndoTo					undo every step after a mark
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// fhistory - the undo and redo history of a knowledge base
+// the top of each stack is its end; a step without records is a mark
+type fhistory struct {
+	mu        sync.Mutex
+	max       int
+	undo      []hstep
+	redo      []hstep
+	depth     int
+	open      *hstep
+	replaying bool
+}
+
+// hstep - a step of the history, or a mark if it has no records
+type hstep struct {
+	mark    string
+	records []hrecord
+}
+
+// hrecord - a change to one frame, either changes to its elements or
+// the whole frame before and after, nil if absent
+type hrecord struct {
+	fname   string
+	changes []Change
+	whole   bool
+	old     Frame
+	new     Frame
+}
+
+// SetHistory - keep a history of at most n steps, or none if n is 0
+// the history kept so far is thrown away
+func (kb *KnowledgeBase) SetHistory(n int) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if n <= 0 {
+		kb.history = nil
+	} else {
+		kb.history = &fhistory{max: n}
+	}
+}
+
+// hist - the history of a knowledge base, nil if it keeps none
+func (kb *KnowledgeBase) hist() *fhistory {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	return kb.history
+}
+
+// record - add a change to the open group, or make a step of it
+func (h *fhistory) record(r hrecord) {
+	if h == nil || (!r.whole && len(r.changes) == 0) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.replaying {
+		return
+	}
+	if h.depth > 0 {
+		h.open.records = append(h.open.records, r)
+		return
+	}
+	h.push(hstep{records: []hrecord{r}})
+}
+
+// push - put a new step on the undo stack
+// requires that h.mu is held
+func (h *fhistory) push(step hstep) {
+	h.undo = append(h.undo, step)
+	if step.records != nil {
+		h.redo = nil
+	}
+	if len(h.undo) > h.max {
+		h.undo = append([]hstep{}, h.undo[len(h.undo)-h.max:]...)
+	}
+}
+
+// begin - open a group of changes making a single step
+func (h *fhistory) begin() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.depth == 0 {
+		h.open = &hstep{}
+	}
+	h.depth++
+}
+
+// end - close a group of changes
+func (h *fhistory) end() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.depth--
+	if h.depth == 0 {
+		if len(h.open.records) > 0 {
+			h.push(*h.open)
+		}
+		h.open = nil
+	}
+}
+
+// group - open a group of changes, returning the function closing it
+func (kb *KnowledgeBase) group() func() {
+	h := kb.hist()
+	if h == nil {
+		return func() {}
+	}
+	h.begin()
+	return h.end
+}
+
+// Group - call fn, making a single step of the changes it makes
+func (kb *KnowledgeBase) Group(fn func() error) error {
+	defer kb.group()()
+	return fn()
+}
+
+// Mark - put a named mark in the history after the last step
+func (kb *KnowledgeBase) Mark(name string) error {
+	h := kb.hist()
+	if h == nil {
+		return ferror("mark", "", "", ErrNoHistory)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.push(hstep{mark: name})
+	return nil
+}
+
+// busy - ErrBusy if a group is open or a step is being undone or redone
+// requires that h.mu is held
+func (h *fhistory) busy() error {
+	if h.depth > 0 || h.replaying {
+		return ErrBusy
+	}
+	return nil
+}
+
+// Undo - undo the last step
+func (kb *KnowledgeBase) Undo() error {
+	return ferror("undo", "", "", kb.undo())
+}
+
+// undo - undo the last step, moving it and any marks after it to the
+// redo stack
+func (kb *KnowledgeBase) undo() error {
+	h := kb.hist()
+	if h == nil {
+		return ErrNoHistory
+	}
+	h.mu.Lock()
+	if err := h.busy(); err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	n := len(h.undo) - 1
+	for n >= 0 && h.undo[n].records == nil {
+		n--
+	}
+	if n < 0 {
+		h.mu.Unlock()
+		return ErrNoHistory
+	}
+	for i := len(h.undo) - 1; i >= n; i-- {
+		h.redo = append(h.redo, h.undo[i])
+	}
+	step := h.undo[n]
+	h.undo = h.undo[:n]
+	h.replaying = true
+	h.mu.Unlock()
+	return kb.replay(h, step, true)
+}
+
+// Redo - make the last undone step again
+func (kb *KnowledgeBase) Redo() error {
+	h := kb.hist()
+	if h == nil {
+		return ferror("redo", "", "", ErrNoHistory)
+	}
+	h.mu.Lock()
+	if err := h.busy(); err != nil {
+		h.mu.Unlock()
+		return ferror("redo", "", "", err)
+	}
+	n := len(h.redo) - 1
+	for n >= 0 && h.redo[n].records == nil {
+		n--
+	}
+	if n < 0 {
+		h.mu.Unlock()
+		return ferror("redo", "", "", ErrNoHistory)
+	}
+	step := h.redo[n]
+	h.undo = append(h.undo, h.redo[n+1:]...)
+	h.undo = append(h.undo, step)
+	h.redo = h.redo[:n]
+	// marks made right after the step go back with it
+	for len(h.redo) > 0 && h.redo[len(h.redo)-1].records == nil {
+		h.undo = append(h.undo, h.redo[len(h.redo)-1])
+		h.redo = h.redo[:len(h.redo)-1]
+	}
+	h.replaying = true
+	h.mu.Unlock()
+	return ferror("redo", "", "", kb.replay(h, step, false))
+}
+
+// UndoTo - undo every step made after the last mark of a name
+func (kb *KnowledgeBase) UndoTo(name string) error {
+	h := kb.hist()
+	if h == nil {
+		return ferror("undoto", "", "", ErrNoHistory)
+	}
+	h.mu.Lock()
+	err := h.busy()
+	n := len(h.undo) - 1
+	for n >= 0 && (h.undo[n].records != nil || h.undo[n].mark != name) {
+		n--
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return ferror("undoto", "", "", err)
+	}
+	if n < 0 {
+		return ferror("undoto", "", "", fmt.Errorf("%w: %q", ErrMarkNotFound, name))
+	}
+	for {
+		h.mu.Lock()
+		top := len(h.undo) - 1
+		done := top < 0 || (h.undo[top].records == nil && h.undo[top].mark == name)
+		h.mu.Unlock()
+		if done {
+			return nil
+		}
+		if err := kb.undo(); err != nil {
+			return ferror("undoto", "", "", err)
+		}
+	}
+}
+
+// replay - undo or redo the records of a step
+// the frames are changed as by any command, so the Changer and the
+// Journal see the changes, but the history does not record them
+func (kb *KnowledgeBase) replay(h *fhistory, step hstep, undo bool) error {
+	defer func() {
+		h.mu.Lock()
+		h.replaying = false
+		h.mu.Unlock()
+	}()
+	errs := []error{}
+	for i := range step.records {
+		r := step.records[i]
+		if undo {
+			r = step.records[len(step.records)-1-i]
+			r.changes, r.old, r.new = finverse(r.changes), r.new, r.old
+		}
+		switch {
+		case !r.whole:
+			errs = append(errs, kb.writef(r.fname, func(f Frame) error {
+				fput(f, r.changes)
+				return nil
+			}))
+		case r.new == nil:
+			errs = append(errs, kb.delf(r.fname))
+		default:
+			errs = append(errs, kb.setf(r.fname, clonef(r.new), false, false))
+		}
+	}
+	return errors.Join(errs...)
+}
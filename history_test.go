@@ -0,0 +1,128 @@
+package framesets2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// hkb - a knowledge base keeping a history, holding a frame a with a
+// value s of 1 and a frameset set of frames a and b
+func hkb() *KnowledgeBase {
+	kb := fvkb("1")
+	kb.Fcreatef("b")
+	kb.Fcreatefs("set")
+	kb.Fsincludef("set", "a")
+	kb.Fsincludef("set", "b")
+	kb.SetHistory(100)
+	return kb
+}
+
+func TestUndoRedo(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(kb *KnowledgeBase)
+	}{
+		{"fputv", func(kb *KnowledgeBase) { kb.Fputv("a", "s", "2") }},
+		{"fremoves", func(kb *KnowledgeBase) { kb.Fremoves("a", "s") }},
+		{"fcreatef", func(kb *KnowledgeBase) { kb.Fcreatef("c") }},
+		{"fremovef", func(kb *KnowledgeBase) { kb.Fremovef("a") }},
+		{"fmergef", func(kb *KnowledgeBase) { kb.Fcreatef("c"); kb.Fmergef("a", "c") }},
+		{"frameset", func(kb *KnowledgeBase) { kb.Fscreates("set", "z"); kb.Fscreatev("set", "z") }},
+		{"transaction", func(kb *KnowledgeBase) {
+			tx := kb.Begin()
+			tx.Fcreatef("t")
+			tx.Fremovef("b")
+			tx.Fputv("a", "s", "9")
+			tx.Commit()
+		}},
+		{"group", func(kb *KnowledgeBase) {
+			kb.Group(func() error {
+				kb.Fcreatef("g1")
+				kb.Fcreatef("g2")
+				return nil
+			})
+		}},
+	}
+	for _, tt := range tests {
+		kb := hkb()
+		before := kbdump(kb)
+		kb.Mark("m")
+		tt.change(kb)
+		after := kbdump(kb)
+		if err := kb.UndoTo("m"); err != nil {
+			t.Errorf("%s: undo: %v", tt.name, err)
+		}
+		if got := kbdump(kb); !reflect.DeepEqual(got, before) {
+			t.Errorf("%s: undo: got %v, want %v", tt.name, got, before)
+		}
+		for kb.Redo() == nil {
+		}
+		if got := kbdump(kb); !reflect.DeepEqual(got, after) {
+			t.Errorf("%s: redo: got %v, want %v", tt.name, got, after)
+		}
+	}
+}
+
+func TestUndoErrors(t *testing.T) {
+	kb := NewKnowledgeBase()
+	if err := kb.Undo(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("no history: got %v", err)
+	}
+	kb = hkb()
+	kb.Fputv("a", "s", "2")
+	tests := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{"redo nothing", kb.Redo, ErrNoHistory},
+		{"undoto missing mark", func() error { return kb.UndoTo("nope") }, ErrMarkNotFound},
+		{"undo in group", func() error { return kb.Group(kb.Undo) }, ErrBusy},
+		{"redo in group", func() error { return kb.Group(kb.Redo) }, ErrBusy},
+		{"undoto in group", func() error {
+			kb.Mark("m")
+			return kb.Group(func() error { return kb.UndoTo("m") })
+		}, ErrBusy},
+		{"undo while replaying", func() error {
+			kb.history.replaying = true
+			defer func() { kb.history.replaying = false }()
+			return kb.Undo()
+		}, ErrBusy},
+		{"undo", kb.Undo, nil},
+		{"redo", kb.Redo, nil},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if got := kb.Fgetv("a", "s"); got != "2" {
+		t.Errorf("got %s, want 2", got)
+	}
+}
+
+func TestUndoNewStep(t *testing.T) {
+	kb := hkb()
+	kb.Fcreatef("c")
+	kb.Undo()
+	kb.Fcreatef("d")
+	if err := kb.Redo(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("redo after new step: got %v", err)
+	}
+}
+
+func TestUndoBounded(t *testing.T) {
+	kb := hkb()
+	kb.SetHistory(3)
+	for _, fname := range []string{"x1", "x2", "x3", "x4"} {
+		kb.Fcreatef(fname)
+	}
+	n := 0
+	for kb.Undo() == nil {
+		n++
+	}
+	if n != 3 || !kb.Fexistf("x1") || kb.Fexistf("x2") {
+		t.Errorf("undid %d steps", n)
+	}
+}
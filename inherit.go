@@ -0,0 +1,443 @@
+/**********************************************************************
+ *
+ * file name:    inherit.go
+ * description:  inheritance of slots from parent frames
+ *
+ * A frame may name its parents in the reference facet of a designated
+ * slot, "isa" unless set otherwise with SetIsa:
+ *
+ *	fframes[<fname>][isa,ref]			parent frames
+ *
+ * A slot which a frame does not have itself is looked up in its
+ * ancestors, so a frame inherits every slot of its parents which it
+ * does not override with a slot of its own. Fexists, Flists, Fgetv,
+ * Fexecm and every other command reading a slot follow the ancestors,
+ * and demons of an inherited slot are fired just as for a slot of the
+ * frame itself. The isa slot itself is never inherited.
+ *
+ * The ancestors are searched in the order given by the C3
+ * linearization, as in Python or Dylan: a frame comes before its
+ * parents, parents keep the order in which they are named, and every
+ * ancestor comes before its own ancestors. A frame whose parents allow
+ * no such order fails with ErrOrder. Where two ancestors neither of
+ * which overrides the other define a slot differently, the first one
+ * wins, and Fconflicts reports the slot.
+ *
+ * Putting a value, method or reference into an inherited slot first
+ * copies the slot into the frame, so the put overrides the slot of the
+ * parent instead of changing it. Every other command changing a slot
+ * works only on the slots of the frame itself.
+ *
+ * Fputp refuses parents which would make a frame its own ancestor or
+ * allow no order, and a lookup running into a cycle made some other way
+ * fails with ErrCycle. A parent which does not exist is passed over.
+ *
+ *							Functions
+ *
+ * Fconflicts				get the slots a frame inherits conflicting definitions of
+ * Fgetp					get the first parent of a frame
+ * Finherits				get the frame a slot is inherited from
+ * Flistp					get the parents of a frame
+ * Fpathp					get a frame and its ancestors in lookup order
+ * Fputp					put parents into a frame
+ * Fremovep					remove the parents of a frame
+ * GetIsa					get the name of the slot naming parents
+ * SetIsa					set the name of the slot naming parents
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// fisa - name of the slot naming the parents of a frame, unless set
+const fisa = "isa"
+
+// SetIsa - set the name of the slot naming the parents of a frame
+func (kb *KnowledgeBase) SetIsa(sname string) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	kb.isa = sname
+}
+
+// GetIsa - get the name of the slot naming the parents of a frame
+// a transaction or a snapshot uses that of the knowledge base it was
+// taken of, unless set
+func (kb *KnowledgeBase) GetIsa() string {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	switch {
+	case kb.isa != "":
+		return kb.isa
+	case kb.tx != nil:
+		return kb.tx.parent.GetIsa()
+	case kb.snap != nil:
+		return kb.snap.live.GetIsa()
+	}
+	return fisa
+}
+
+// Conflict - a slot inherited from ancestors which define it
+// differently, neither of them overriding the other
+// Frames is in lookup order, so the slot is inherited from the first
+type Conflict struct {
+	Slot   string
+	Frames []string
+}
+
+// fparents - parents named in a locked frame
+func fparents(f Frame, fname, isa string) []string {
+	plist := []string{}
+	if Fmember(f[fname+",slots"], isa) && Fmember(f[isa+",facets"], "ref") {
+		for _, i := range f[isa+",ref"] {
+			if i != "" && !Fmember(plist, i) {
+				plist = append(plist, i)
+			}
+		}
+	}
+	return plist
+}
+
+// pathp - the frame and its ancestors, in lookup order
+// fails with ErrFrameNotFound if the frame does not exist, ErrCycle if
+// it is its own ancestor and ErrOrder if its ancestors have no order;
+// on failure the list holds only the frame
+func (kb *KnowledgeBase) pathp(fname string) ([]string, error) {
+	isa := kb.GetIsa()
+	memo := map[string][]string{}
+	var linp func(fname string, stack []string) ([]string, error)
+	linp = func(fname string, stack []string) ([]string, error) {
+		if plist, ok := memo[fname]; ok {
+			return plist, nil
+		}
+		if Fmember(stack, fname) {
+			return nil, ErrCycle
+		}
+		parents := []string{}
+		if !kb.readf(fname, func(f Frame) {
+			parents = fparents(f, fname, isa)
+		}) {
+			return nil, ErrFrameNotFound
+		}
+		stack = append(slices.Clip(stack), fname)
+		lists := [][]string{}
+		for _, i := range parents {
+			plist, err := linp(i, stack)
+			if err == ErrFrameNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			lists = append(lists, plist)
+		}
+		plist, err := fmergep(fname, lists)
+		if err != nil {
+			return nil, err
+		}
+		memo[fname] = plist
+		return plist, nil
+	}
+	plist, err := linp(fname, nil)
+	if err == ErrFrameNotFound {
+		return []string{}, err
+	}
+	if err != nil {
+		return []string{fname}, err
+	}
+	return plist, nil
+}
+
+// fmergep - C3 linearization of a frame from those of its parents
+func fmergep(fname string, lists [][]string) ([]string, error) {
+	parents := []string{}
+	for _, i := range lists {
+		parents = append(parents, i[0])
+	}
+	lists = append(lists, parents)
+	plist := []string{fname}
+	for {
+		for len(lists) > 0 && len(lists[0]) == 0 {
+			lists = lists[1:]
+		}
+		if len(lists) == 0 {
+			return plist, nil
+		}
+		head := ""
+		for _, i := range lists {
+			if len(i) > 0 && !slices.ContainsFunc(lists, func(j []string) bool {
+				return len(j) > 1 && Fmember(j[1:], i[0])
+			}) {
+				head = i[0]
+				break
+			}
+		}
+		if head == "" {
+			return plist, ErrOrder
+		}
+		plist = append(plist, head)
+		for k, i := range lists {
+			if len(i) > 0 && i[0] == head {
+				lists[k] = i[1:]
+			}
+		}
+	}
+}
+
+// slotp - copy of a slot, looked up in a frame and then its parents
+// returns the frame the slot was found in
+func (kb *KnowledgeBase) slotp(fname, sname string) (string, slot, error) {
+	var s slot
+	parents := []string{}
+	isa := kb.GetIsa()
+	if !kb.readf(fname, func(f Frame) {
+		if s = fslot(f, fname, sname); s == nil {
+			parents = fparents(f, fname, isa)
+		}
+	}) {
+		return fname, nil, ErrFrameNotFound
+	}
+	if s != nil || len(parents) == 0 || sname == isa {
+		if s == nil {
+			return fname, nil, ErrSlotNotFound
+		}
+		return fname, s, nil
+	}
+	plist, err := kb.pathp(fname)
+	for _, i := range plist {
+		if i == fname {
+			continue
+		}
+		kb.readf(i, func(f Frame) {
+			s = fslot(f, i, sname)
+		})
+		if s != nil {
+			return i, s, nil
+		}
+	}
+	if err != nil {
+		return fname, nil, err
+	}
+	return fname, nil, ErrSlotNotFound
+}
+
+// fown - copy an inherited slot into a locked frame, so a put into it
+// overrides the slot of the ancestor
+// s is a copy of the slot as looked up, nil if there is none
+func fown(f Frame, fname, sname string, s slot) {
+	if s == nil || Fmember(f[fname+",slots"], sname) {
+		return
+	}
+	f[fname+",slots"] = append(f[fname+",slots"], sname)
+	for k, v := range s {
+		f[sname+","+k] = append([]string{}, v...)
+	}
+}
+
+// fputp - put parents into a frame, replacing any it has
+// requires that fframes[fname] and the parent frames exist
+// modifies fframes[fname][fname,slots], fframes[fname][isa,ref]
+func (kb *KnowledgeBase) Fputp(fname string, pnames ...string) bool {
+	return kb.FputpE(fname, pnames...) == nil
+}
+
+// fputpe - put parents into a frame, returning an error
+func (kb *KnowledgeBase) FputpE(fname string, pnames ...string) error {
+	lists := [][]string{}
+	parents := []string{}
+	for _, i := range pnames {
+		plist, err := kb.pathp(i)
+		if err != nil {
+			return ferror("fputp", i, "", err)
+		}
+		if Fmember(plist, fname) {
+			return ferror("fputp", fname, "", ErrCycle)
+		}
+		if !Fmember(parents, i) {
+			lists = append(lists, plist)
+			parents = append(parents, i)
+		}
+	}
+	if _, err := fmergep(fname, lists); err != nil {
+		return ferror("fputp", fname, "", err)
+	}
+	isa := kb.GetIsa()
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[fname+",slots"], isa) {
+			f[fname+",slots"] = append(f[fname+",slots"], isa)
+			f[isa+",facets"] = []string{}
+		}
+		if Fmember(f[isa+",facets"], "value") || Fmember(f[isa+",facets"], "method") {
+			return ErrFacetConflict
+		}
+		if !Fmember(f[isa+",facets"], "ref") {
+			f[isa+",facets"] = append(f[isa+",facets"], "ref")
+		}
+		f[isa+",ref"] = parents
+		return nil
+	})
+	return ferror("fputp", fname, isa, err)
+}
+
+// fgetp - get the first parent of a frame, "" if it has none
+// requires that fframes[fname] exists
+func (kb *KnowledgeBase) Fgetp(fname string) string {
+	pname, _ := kb.FgetpE(fname)
+	return pname
+}
+
+// fgetpe - get the first parent of a frame, returning an error
+func (kb *KnowledgeBase) FgetpE(fname string) (string, error) {
+	pname := ""
+	isa := kb.GetIsa()
+	if !kb.readf(fname, func(f Frame) {
+		pname = Getval(fparents(f, fname, isa))
+	}) {
+		return "", ferror("fgetp", fname, "", ErrFrameNotFound)
+	}
+	return pname, nil
+}
+
+// flistp - get the parents of a frame
+// requires that fframes[fname] exists
+func (kb *KnowledgeBase) Flistp(fname string) []string {
+	plist, _ := kb.FlistpE(fname)
+	return plist
+}
+
+// flistpe - get the parents of a frame, returning an error
+func (kb *KnowledgeBase) FlistpE(fname string) ([]string, error) {
+	plist := []string{}
+	isa := kb.GetIsa()
+	if !kb.readf(fname, func(f Frame) {
+		plist = fparents(f, fname, isa)
+	}) {
+		return plist, ferror("flistp", fname, "", ErrFrameNotFound)
+	}
+	return plist, nil
+}
+
+// fremovep - remove the parents of a frame
+// requires that fframes[fname][isa,facets] exists
+// modifies fframes[fname][fname,slots], fframes[fname][isa,]
+func (kb *KnowledgeBase) Fremovep(fname string) bool {
+	return kb.FremovepE(fname) == nil
+}
+
+// fremovepe - remove the parents of a frame, returning an error
+func (kb *KnowledgeBase) FremovepE(fname string) error {
+	isa := kb.GetIsa()
+	err := kb.writef(fname, func(f Frame) error {
+		if !Fmember(f[fname+",slots"], isa) {
+			return ErrSlotNotFound
+		}
+		for k := range f {
+			if strings.HasPrefix(k, isa+",") {
+				delete(f, k)
+			}
+		}
+		slots := f[fname+",slots"]
+		Fremove(&slots, isa)
+		f[fname+",slots"] = slots
+		return nil
+	})
+	return ferror("fremovep", fname, isa, err)
+}
+
+// fpathp - get a frame and its ancestors in lookup order
+// requires that fframes[fname] exists
+func (kb *KnowledgeBase) Fpathp(fname string) []string {
+	plist, _ := kb.FpathpE(fname)
+	return plist
+}
+
+// fpathpe - get a frame and its ancestors in lookup order, returning an error
+func (kb *KnowledgeBase) FpathpE(fname string) ([]string, error) {
+	plist, err := kb.pathp(fname)
+	return plist, ferror("fpathp", fname, "", err)
+}
+
+// finherits - get the frame a slot is inherited from, the frame itself
+// if it has the slot, or "" if neither it nor its parents have it
+func (kb *KnowledgeBase) Finherits(fname, sname string) string {
+	pname, _ := kb.FinheritsE(fname, sname)
+	return pname
+}
+
+// finheritse - get the frame a slot is inherited from, returning an error
+func (kb *KnowledgeBase) FinheritsE(fname, sname string) (string, error) {
+	pname, _, err := kb.slotp(fname, sname)
+	if err != nil {
+		return "", ferror("finherits", fname, sname, err)
+	}
+	return pname, nil
+}
+
+// fconflicts - get the slots a frame inherits conflicting definitions of
+// requires that fframes[fname] exists
+func (kb *KnowledgeBase) Fconflicts(fname string) []Conflict {
+	conflicts, _ := kb.FconflictsE(fname)
+	return conflicts
+}
+
+// fconflictse - get the slots a frame inherits conflicting definitions
+// of, returning an error
+func (kb *KnowledgeBase) FconflictsE(fname string) ([]Conflict, error) {
+	conflicts := []Conflict{}
+	plist, err := kb.pathp(fname)
+	if err != nil {
+		return conflicts, ferror("fconflicts", fname, "", err)
+	}
+	isa := kb.GetIsa()
+	local := []string{}
+	kb.readf(fname, func(f Frame) {
+		local = append(local, f[fname+",slots"]...)
+	})
+	// the ancestors defining each inherited slot, in lookup order
+	defs := map[string][]string{}
+	slots := map[string]slot{}
+	for _, i := range plist[1:] {
+		kb.readf(i, func(f Frame) {
+			for _, j := range f[i+",slots"] {
+				if j != isa && !Fmember(local, j) {
+					defs[j] = append(defs[j], i)
+					slots[i+","+j] = fslot(f, i, j)
+				}
+			}
+		})
+	}
+	for sname, frames := range defs {
+		first := frames[0]
+		ancestors, _ := kb.pathp(first)
+		c := Conflict{Slot: sname, Frames: []string{first}}
+		for _, i := range frames[1:] {
+			if !Fmember(ancestors, i) && !fsames(slots[first+","+sname], slots[i+","+sname]) {
+				c.Frames = append(c.Frames, i)
+			}
+		}
+		if len(c.Frames) > 1 {
+			conflicts = append(conflicts, c)
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Slot < conflicts[j].Slot
+	})
+	return conflicts, nil
+}
+
+// fsames - determine if two copies of slots hold the same facets
+func fsames(x, y slot) bool {
+	if !Fequivalence(append([]string{}, x["facets"]...), append([]string{}, y["facets"]...)) {
+		return false
+	}
+	for _, i := range x["facets"] {
+		if !slices.Equal(x[i], y[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,221 @@
+package framesets2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// ikb - a knowledge base in which puppy is a dog and a dog an animal
+// animal has a value legs of 4 and a method speak calling m, which
+// notes the frame it was called for in called
+func ikb(called *string) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("animal")
+	kb.Fcreates("animal", "legs")
+	kb.Fcreatev("animal", "legs")
+	kb.Fputv("animal", "legs", "4")
+	kb.Fcreates("animal", "speak")
+	kb.Fcreatem("animal", "speak")
+	kb.Fputm("animal", "speak", "m")
+	kb.Fcreatex("m")
+	kb.Fputxm("m", func(c *MethodContext) (any, error) {
+		*called = c.Frame
+		return nil, nil
+	})
+	kb.Fcreatef("dog")
+	kb.Fcreatef("puppy")
+	kb.Fputp("dog", "animal")
+	kb.Fputp("puppy", "dog")
+	return kb
+}
+
+func TestInherit(t *testing.T) {
+	called := ""
+	kb := ikb(&called)
+	if got := kb.Fgetv("puppy", "legs"); got != "4" {
+		t.Errorf("fgetv: got %q, want 4", got)
+	}
+	if !kb.Fexists("puppy", "legs") {
+		t.Error("fexists: inherited slot does not exist")
+	}
+	if got, want := kb.Flists("puppy"), []string{"isa", "legs", "speak"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("flists: got %q, want %q", got, want)
+	}
+	tests := []struct {
+		sname string
+		where string
+	}{
+		{"legs", "animal"},
+		{"isa", "puppy"},
+		{"zz", ""},
+	}
+	for _, tt := range tests {
+		if got := kb.Finherits("puppy", tt.sname); got != tt.where {
+			t.Errorf("finherits %s: got %q, want %q", tt.sname, got, tt.where)
+		}
+	}
+	kb.Fexecm("puppy", "speak")
+	if called != "puppy" {
+		t.Errorf("fexecm: called for %q, want puppy", called)
+	}
+	if got, want := kb.Fpathp("puppy"), []string{"puppy", "dog", "animal"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fpathp: got %q, want %q", got, want)
+	}
+	if got := kb.Fgetp("puppy"); got != "dog" {
+		t.Errorf("fgetp: got %q, want dog", got)
+	}
+	if got := kb.Fgetp("animal"); got != "" {
+		t.Errorf("fgetp without parent: got %q", got)
+	}
+}
+
+func TestInheritOverride(t *testing.T) {
+	called := ""
+	kb := ikb(&called)
+	kb.Fputv("dog", "legs", "3")
+	tests := []struct {
+		fname string
+		value string
+		where string
+	}{
+		{"animal", "4", "animal"},
+		{"dog", "3", "dog"},
+		{"puppy", "3", "dog"},
+	}
+	for _, tt := range tests {
+		if got := kb.Fgetv(tt.fname, "legs"); got != tt.value {
+			t.Errorf("%s: got %s, want %s", tt.fname, got, tt.value)
+		}
+		if got := kb.Finherits(tt.fname, "legs"); got != tt.where {
+			t.Errorf("%s: inherits from %s, want %s", tt.fname, got, tt.where)
+		}
+	}
+	kb.Fremovep("dog")
+	if kb.Fgetv("puppy", "legs") != "3" || kb.Fexists("dog", "speak") {
+		t.Error("fremovep: still inherits")
+	}
+	kb.SetIsa("ako")
+	if kb.Fgetv("puppy", "legs") != "" {
+		t.Error("setisa: still inherits through isa")
+	}
+}
+
+func TestInheritCycle(t *testing.T) {
+	called := ""
+	kb := ikb(&called)
+	if err := kb.FputpE("animal", "puppy"); !errors.Is(err, ErrCycle) {
+		t.Errorf("fputp: got %v, want %v", err, ErrCycle)
+	}
+	// a cycle made through the isa slot itself is found when looked up
+	kb.Fcreates("animal", "isa")
+	kb.Fcreater("animal", "isa")
+	kb.Fputr("animal", "isa", "puppy")
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"fgetv", func() error { return errv(kb.FgetvE("puppy", "nothing")) }},
+		{"fpathp", func() error { return errv(kb.FpathpE("puppy")) }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, ErrCycle) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, ErrCycle)
+		}
+	}
+}
+
+// mkb - a knowledge base of the classic C3 example, in which Z has the
+// parents K1, K2 and K3
+func mkb() *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	for _, fname := range []string{"O", "A", "B", "C", "D", "E", "K1", "K2", "K3", "Z", "X", "Y"} {
+		kb.Fcreatef(fname)
+	}
+	for _, fname := range []string{"A", "B", "C", "D", "E"} {
+		kb.Fputp(fname, "O")
+	}
+	kb.Fputp("K1", "A", "B", "C")
+	kb.Fputp("K2", "D", "B", "E")
+	kb.Fputp("K3", "D", "A")
+	kb.Fputp("Z", "K1", "K2", "K3")
+	return kb
+}
+
+func TestMultiInherit(t *testing.T) {
+	kb := mkb()
+	if got, want := kb.Fpathp("Z"), []string{"Z", "K1", "K2", "K3", "D", "A", "B", "C", "E", "O"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fpathp: got %q, want %q", got, want)
+	}
+	if got, want := kb.Flistp("Z"), []string{"K1", "K2", "K3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("flistp: got %q, want %q", got, want)
+	}
+	if got := kb.Fgetp("Z"); got != "K1" {
+		t.Errorf("fgetp: got %q, want K1", got)
+	}
+	kb.Fremovef("B")
+	if got, want := kb.Fpathp("K1"), []string{"K1", "A", "C", "O"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("missing parent: got %v, want %v", got, want)
+	}
+}
+
+func TestMultiInheritErrors(t *testing.T) {
+	kb := mkb()
+	tests := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{"consistent order", func() error { return kb.FputpE("X", "A", "O") }, nil},
+		{"inconsistent order", func() error { return kb.FputpE("Y", "O", "A") }, ErrOrder},
+		{"cycle", func() error { return kb.FputpE("O", "Z") }, ErrCycle},
+		{"missing parent", func() error { return kb.FputpE("Y", "nope") }, ErrFrameNotFound},
+		{"missing frame", func() error { return errv(kb.FpathpE("nope")) }, ErrFrameNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if got := kb.Flistp("Y"); len(got) != 0 {
+		t.Errorf("parents put despite an error: %q", got)
+	}
+}
+
+func TestMultiInheritConflicts(t *testing.T) {
+	kb := mkb()
+	put := func(fname, sname, value string) {
+		kb.Fcreates(fname, sname)
+		kb.Fcreatev(fname, sname)
+		kb.Fputv(fname, sname, value)
+	}
+	put("O", "color", "grey")
+	put("A", "color", "red")
+	put("B", "color", "blue")
+	put("C", "size", "1")
+	put("E", "size", "1")
+	put("D", "color", "red")
+	if kb.Fgetv("Z", "color") != "red" || kb.Finherits("Z", "color") != "D" {
+		t.Errorf("color inherited from %s", kb.Finherits("Z", "color"))
+	}
+	tests := []struct {
+		name   string
+		fname  string
+		change func()
+		want   []Conflict
+	}{
+		{"differing values", "Z", func() {}, []Conflict{{Slot: "color", Frames: []string{"D", "B"}}}},
+		{"single parent", "A", func() {}, nil},
+		{"own slot", "Z", func() { put("Z", "color", "green") }, nil},
+	}
+	for _, tt := range tests {
+		tt.change()
+		got := kb.Fconflicts(tt.fname)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+/**********************************************************************
+ *
+ * file name:    journal.go
+ * description:  journal of every change to a knowledge base
+ *
+ * A knowledge base may keep a journal in a directory of its own. Every
+ * change is appended to the journal before the command making it
+ * returns: elements of frames being put or removed, frames being
+ * created, loaded, replaced or removed, and methods being created or
+ * removed from fmethods. OpenJournal replays the journal, so after a
+ * crash the knowledge base comes back as it was at the last change.
+ *
+ * Every so many changes, and whenever Checkpoint is called, the whole
+ * knowledge base is written to a checkpoint and the journal before it
+ * is dropped, so the journal does not grow without end. Each checkpoint
+ * and journal file carries a generation number:
+ *
+ *	checkpoint.3			every frame and method at the start of 3
+ *	journal.3				changes made since then
+ *
+ * A checkpoint is taken by first starting the journal of the next
+ * generation and then writing every frame, so no change is lost, and
+ * the files of older generations are only removed once the checkpoint
+ * is on disk. Replaying starts from the newest checkpoint, followed by
+ * the journals of the same or later generations.
+ *
+ * Journal lines are records of Go quoted strings, as in a KVStorage
+ * log: "p", a frame, an element and its values; "d", a frame and an
+ * element; "n" and a frame, which is created empty or emptied; "r" and
+ * a frame, which is removed; "x" or "y" and a method, which is created
+ * or removed. "c" commits the records since the last commit, and only
+ * committed records are replayed. Only the names of methods are kept,
+ * so a replayed method does nothing until it is put again with Fputx.
+ *
+ *							Functions
+ *
+ * Checkpoint				write a checkpoint and drop the journal before it
+ * CloseJournal				stop keeping a journal
+ * GetJournal				get the journal of a knowledge base
+ * OpenJournal				replay a journal and keep it from now on
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// jheader - start of the header line of a journal or checkpoint
+const jheader = "framesets2 journal "
+
+// jevery - number of records between checkpoints, unless set
+const jevery = 10000
+
+// Journal - the journal of a knowledge base
+type Journal struct {
+	mu     sync.Mutex
+	dir    string
+	gen    int
+	fh     *os.File
+	n      int
+	every  int
+	nosync bool
+	busy   bool
+	err    error
+}
+
+// jfile - path of a journal or checkpoint file of a generation
+func (j *Journal) jfile(kind string, gen int) string {
+	return filepath.Join(j.dir, kind+"."+strconv.Itoa(gen))
+}
+
+// write - append records and a commit to the journal
+// a journal which failed to write stays failed, since the changes
+// after it could not be replayed
+func (j *Journal) write(lines []string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err != nil {
+		return j.err
+	}
+	if j.fh == nil {
+		return ioerror(os.ErrClosed)
+	}
+	var b strings.Builder
+	for _, i := range lines {
+		b.WriteString(i)
+	}
+	b.WriteString(fencodel("c", nil))
+	_, err := j.fh.WriteString(b.String())
+	if err == nil && !j.nosync {
+		err = j.fh.Sync()
+	}
+	if err != nil {
+		j.err = ioerror(err)
+		return j.err
+	}
+	j.n += len(lines)
+	return nil
+}
+
+// jchanges - records of a list of changes
+func jchanges(changes []Change) []string {
+	lines := []string{}
+	for _, c := range changes {
+		if c.Key == "" {
+			lines = append(lines, fencodel("r", []string{c.Frame}))
+		} else if c.New == nil {
+			lines = append(lines, fencodel("d", []string{c.Frame, c.Key}))
+		} else {
+			lines = append(lines, fencodel("p", append([]string{c.Frame, c.Key}, c.New...)))
+		}
+	}
+	return lines
+}
+
+// jframe - records of a whole frame
+func jframe(fname string, x Frame) []string {
+	lines := []string{fencodel("n", []string{fname})}
+	keys := make([]string, 0, len(x))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, fencodel("p", append([]string{fname, k}, x[k]...)))
+	}
+	return lines
+}
+
+// changes - record changes to elements of frames
+func (j *Journal) changes(changes []Change) error {
+	return j.write(jchanges(changes))
+}
+
+// frame - record a frame being created or replaced
+func (j *Journal) frame(fname string, x Frame) error {
+	return j.write(jframe(fname, x))
+}
+
+// put - record a frame being put back as it was, removed if x is nil
+func (j *Journal) put(fname string, x Frame) error {
+	if x == nil {
+		return j.remove(fname)
+	}
+	return j.frame(fname, x)
+}
+
+// remove - record a frame being removed
+func (j *Journal) remove(fname string) error {
+	return j.write([]string{fencodel("r", []string{fname})})
+}
+
+// method - record a method being created or removed
+func (j *Journal) method(mname string, created bool) error {
+	if created {
+		return j.write([]string{fencodel("x", []string{mname})})
+	}
+	return j.write([]string{fencodel("y", []string{mname})})
+}
+
+// due - determine if a checkpoint is due, and if so claim it
+func (j *Journal) due() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.busy || j.fh == nil || j.err != nil || j.n < j.every {
+		return false
+	}
+	j.busy = true
+	return true
+}
+
+// jstate - frames and methods being replayed
+type jstate struct {
+	frames  map[string]Frame
+	methods map[string]bool
+}
+
+// replay - apply the committed records of a journal or checkpoint file
+// returns the end of the last commit, where writing may go on
+func (st *jstate) replay(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		// a file torn before its header holds no records
+		return 0, nil
+	}
+	v, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), jheader)
+	if n, verr := strconv.Atoi(v); !ok || verr != nil || n < 2 || n > FormatVersion {
+		return 0, fmt.Errorf("%w: not a journal of a known version", ErrFormat)
+	}
+	off := int64(len(line))
+	end := off
+	pending := [][]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err != io.EOF {
+				return 0, ioerror(err)
+			}
+			break
+		}
+		kind, items, derr := fdecodel(strings.TrimSuffix(line, "\n"))
+		if derr != nil {
+			break
+		}
+		off += int64(len(line))
+		if kind != "c" {
+			pending = append(pending, append([]string{kind}, items...))
+			continue
+		}
+		for _, i := range pending {
+			if err := st.apply(i); err != nil {
+				return 0, err
+			}
+		}
+		pending = pending[:0]
+		end = off
+	}
+	return end, nil
+}
+
+// apply - apply a record
+func (st *jstate) apply(record []string) error {
+	kind, items := record[0], record[1:]
+	switch {
+	case kind == "p" && len(items) >= 2:
+		if st.frames[items[0]] == nil {
+			st.frames[items[0]] = Frame{}
+		}
+		st.frames[items[0]][items[1]] = append([]string{}, items[2:]...)
+	case kind == "d" && len(items) == 2:
+		delete(st.frames[items[0]], items[1])
+	case kind == "n" && len(items) == 1:
+		st.frames[items[0]] = Frame{}
+	case kind == "r" && len(items) == 1:
+		delete(st.frames, items[0])
+	case kind == "x" && len(items) == 1:
+		st.methods[items[0]] = true
+	case kind == "y" && len(items) == 1:
+		delete(st.methods, items[0])
+	default:
+		return fmt.Errorf("%w: bad journal record %q", ErrFormat, kind)
+	}
+	return nil
+}
+
+// jgens - generations of the journal and checkpoint files in a directory
+func jgens(dir string) ([]int, []int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, ioerror(err)
+	}
+	journals, checkpoints := []int{}, []int{}
+	for _, i := range files {
+		kind, v, ok := strings.Cut(i.Name(), ".")
+		gen, err := strconv.Atoi(v)
+		if !ok || err != nil {
+			continue
+		}
+		if kind == "journal" {
+			journals = append(journals, gen)
+		} else if kind == "checkpoint" {
+			checkpoints = append(checkpoints, gen)
+		}
+	}
+	sort.Ints(journals)
+	sort.Ints(checkpoints)
+	return journals, checkpoints, nil
+}
+
+// OpenJournal - replay the journal in a directory and keep it from now on
+// the directory is created if it does not exist; frames and methods
+// already in the knowledge base are kept unless the journal replaces them
+// every is the number of records between checkpoints, 0 for the default
+func (kb *KnowledgeBase) OpenJournal(dir string, every int) error {
+	if every <= 0 {
+		every = jevery
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ferror("openjournal", "", "", ioerror(err))
+	}
+	j := &Journal{dir: dir, every: every}
+	journals, checkpoints, err := jgens(dir)
+	if err != nil {
+		return ferror("openjournal", "", "", err)
+	}
+	st := &jstate{frames: make(map[string]Frame), methods: make(map[string]bool)}
+	base := 0
+	if len(checkpoints) > 0 {
+		base = checkpoints[len(checkpoints)-1]
+		j.gen = base
+		if err := j.replay(st, "checkpoint", j.gen); err != nil {
+			return ferror("openjournal", "", "", err)
+		}
+	}
+	end := int64(-1)
+	for _, gen := range journals {
+		if gen >= j.gen {
+			j.gen = gen
+			if end, err = j.replayj(st, gen); err != nil {
+				return ferror("openjournal", "", "", err)
+			}
+		}
+	}
+	// the journal goes on where its last commit ended
+	if end < 0 {
+		fh, err := os.OpenFile(j.jfile("journal", j.gen), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err == nil {
+			_, err = fh.WriteString(jheader + strconv.Itoa(FormatVersion) + "\n")
+			j.fh = fh
+		}
+		if err != nil {
+			return ferror("openjournal", "", "", ioerror(err))
+		}
+	} else {
+		fh, err := os.OpenFile(j.jfile("journal", j.gen), os.O_WRONLY, 0644)
+		if err == nil {
+			err = fh.Truncate(end)
+			j.fh = fh
+		}
+		if err == nil {
+			_, err = fh.Seek(end, io.SeekStart)
+		}
+		if err != nil {
+			return ferror("openjournal", "", "", ioerror(err))
+		}
+	}
+	j.fh.Sync()
+	fsyncd(j.jfile("journal", j.gen))
+	j.drop(base)
+	for fname, x := range st.frames {
+		if len(x) > 0 {
+			if err := kb.setf(fname, x, false, true); err != nil {
+				return ferror("openjournal", fname, "", err)
+			}
+		}
+	}
+	for mname := range st.methods {
+		kb.Fcreatex(mname)
+	}
+	kb.mu.Lock()
+	kb.journal = j
+	kb.mu.Unlock()
+	return nil
+}
+
+// replay - replay a checkpoint file
+func (j *Journal) replay(st *jstate, kind string, gen int) error {
+	fh, err := os.Open(j.jfile(kind, gen))
+	if err != nil {
+		return ioerror(err)
+	}
+	defer fh.Close()
+	_, err = st.replay(fh)
+	return err
+}
+
+// replayj - replay a journal file, returning where its last commit ended
+// a journal with a torn header is started again
+func (j *Journal) replayj(st *jstate, gen int) (int64, error) {
+	fh, err := os.Open(j.jfile("journal", gen))
+	if err != nil {
+		return 0, ioerror(err)
+	}
+	defer fh.Close()
+	end, err := st.replay(fh)
+	if err == nil && end == 0 {
+		end = -1
+	}
+	return end, err
+}
+
+// drop - remove the files of generations before gen
+func (j *Journal) drop(gen int) {
+	journals, checkpoints, err := jgens(j.dir)
+	if err != nil {
+		return
+	}
+	for _, i := range journals {
+		if i < gen {
+			os.Remove(j.jfile("journal", i))
+		}
+	}
+	for _, i := range checkpoints {
+		if i < gen {
+			os.Remove(j.jfile("checkpoint", i))
+		}
+	}
+}
+
+// Checkpoint - write every frame and method to a checkpoint and drop
+// the journal before it
+func (kb *KnowledgeBase) Checkpoint() error {
+	kb.mu.RLock()
+	j := kb.journal
+	kb.mu.RUnlock()
+	if j == nil {
+		return ferror("checkpoint", "", "", errors.New("no journal"))
+	}
+	j.mu.Lock()
+	if j.busy {
+		j.mu.Unlock()
+		return nil
+	}
+	j.busy = true
+	j.mu.Unlock()
+	return ferror("checkpoint", "", "", kb.checkpoint(j))
+}
+
+// checkpointed - take a checkpoint if one is due
+// called once a command has released every lock
+func (kb *KnowledgeBase) checkpointed(j *Journal) {
+	if j != nil && j.due() {
+		kb.checkpoint(j)
+	}
+}
+
+// checkpoint - start a new generation of the journal and write a
+// checkpoint for it
+// requires that j.busy is set
+func (kb *KnowledgeBase) checkpoint(j *Journal) error {
+	defer func() {
+		j.mu.Lock()
+		j.busy = false
+		j.mu.Unlock()
+	}()
+	j.mu.Lock()
+	if j.err != nil || j.fh == nil {
+		err := j.err
+		j.mu.Unlock()
+		if err == nil {
+			err = ioerror(os.ErrClosed)
+		}
+		return err
+	}
+	gen := j.gen + 1
+	fh, err := os.OpenFile(j.jfile("journal", gen), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err == nil {
+		_, err = fh.WriteString(jheader + strconv.Itoa(FormatVersion) + "\n")
+		if err == nil {
+			err = fh.Sync()
+		}
+		if err != nil {
+			fh.Close()
+			os.Remove(fh.Name())
+		}
+	}
+	if err != nil {
+		j.mu.Unlock()
+		return ioerror(err)
+	}
+	fsyncd(fh.Name())
+	j.fh.Close()
+	j.fh, j.gen, j.n = fh, gen, 0
+	j.mu.Unlock()
+	// every change from here on is in the new journal, so a frame copied
+	// after this point is at least as new as the journal before it
+	lines := []string{}
+	fnames := kb.Flistf()
+	sort.Strings(fnames)
+	for _, i := range fnames {
+		if x := kb.copyf(i); x != nil {
+			lines = append(lines, jframe(i, x)...)
+		}
+	}
+	for _, i := range kb.Flistx() {
+		lines = append(lines, fencodel("x", []string{i}))
+	}
+	tmp, err := ftemp(j.jfile("checkpoint", gen), func(w io.Writer) error {
+		writer := bufio.NewWriter(w)
+		writer.WriteString(jheader + strconv.Itoa(FormatVersion) + "\n")
+		for _, i := range lines {
+			writer.WriteString(i)
+		}
+		writer.WriteString(fencodel("c", nil))
+		return writer.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.jfile("checkpoint", gen)); err != nil {
+		os.Remove(tmp)
+		return ioerror(err)
+	}
+	fsyncd(j.jfile("checkpoint", gen))
+	j.drop(gen)
+	return nil
+}
+
+// CloseJournal - stop keeping a journal
+// the journal stays on disk, to be opened again
+func (kb *KnowledgeBase) CloseJournal() error {
+	kb.mu.Lock()
+	j := kb.journal
+	kb.journal = nil
+	kb.mu.Unlock()
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.fh == nil {
+		return j.err
+	}
+	err := j.fh.Close()
+	j.fh = nil
+	if j.err != nil {
+		return j.err
+	}
+	return ferror("closejournal", "", "", ioerror(err))
+}
+
+// SetSync - set whether every change is synced to disk, which it is
+// unless turned off
+func (j *Journal) SetSync(on bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.nosync = !on
+}
+
+// GetJournal - get the journal of a knowledge base, nil if it has none
+func (kb *KnowledgeBase) GetJournal() *Journal {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	return kb.journal
+}
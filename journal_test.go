@@ -0,0 +1,152 @@
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// kbdump - every frame of a knowledge base
+func kbdump(kb *KnowledgeBase) map[string]Frame {
+	m := map[string]Frame{}
+	for _, i := range kb.Flistf() {
+		m[i] = kb.copyf(i)
+	}
+	return m
+}
+
+func TestJournalReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "j")
+	kb := NewKnowledgeBase()
+	if err := kb.OpenJournal(dir, 50); err != nil {
+		t.Fatal(err)
+	}
+	kb.GetJournal().SetSync(false)
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatev("a", "s")
+	kb.Fputv("a", "s", "x,y")
+	kb.Fcreatex("m")
+	kb.Fcreatefs("set")
+	kb.Fsincludef("set", "a")
+	kb.Fcreatef("gone")
+	kb.Fremovef("gone")
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				n := fmt.Sprint("f", g, "_", i%10)
+				kb.Fcreatef(n)
+				kb.Fcreates(n, "s")
+				kb.Fcreatev(n, "s")
+				kb.Fputv(n, "s", fmt.Sprint(i))
+				if i%7 == 0 {
+					kb.Fremovef(n)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	want := kbdump(kb)
+	// the journal is not closed, as after a crash
+	kb2 := NewKnowledgeBase()
+	if err := kb2.OpenJournal(dir, 50); err != nil {
+		t.Fatal(err)
+	}
+	if got := kbdump(kb2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("replay: got %d frames, want %d", len(got), len(want))
+	}
+	if !kb2.Fexistx("m") || kb2.Fexistf("gone") {
+		t.Error("replay: methods or removed frames differ")
+	}
+	kb2.Fputv("a", "s", "later")
+	kb2.Checkpoint()
+	kb2.Fputv("a", "s", "after")
+	kb2.CloseJournal()
+	files, _ := filepath.Glob(filepath.Join(dir, "journal.*"))
+	sort.Strings(files)
+	f, _ := os.OpenFile(files[len(files)-1], os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString("\"p\" \"a\" \"s,value\" \"torn\"\n")
+	f.Close()
+	kb3 := NewKnowledgeBase()
+	kb3.OpenJournal(dir, 0)
+	if got := kb3.Fgetv("a", "s"); got != "after" {
+		t.Errorf("uncommitted tail: got %q, want after", got)
+	}
+}
+
+func TestJournalFails(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(kb *KnowledgeBase) error
+	}{
+		{"fcreatef", func(kb *KnowledgeBase) error { return kb.FcreatefE("b") }},
+		{"fremovef", func(kb *KnowledgeBase) error { return kb.FremovefE("a") }},
+		{"fcopyf", func(kb *KnowledgeBase) error { return kb.FcopyfE("a", "b") }},
+		{"fcreatefs", func(kb *KnowledgeBase) error { return kb.FcreatefsE("b") }},
+		{"fimportf", func(kb *KnowledgeBase) error { return kb.Fimportf([]byte(`{"name":"b","slots":{}}`)) }},
+		{"fcreatex", func(kb *KnowledgeBase) error { return kb.FcreatexE("n") }},
+		{"fremovex", func(kb *KnowledgeBase) error { return kb.FremovexE("m") }},
+		{"fputv", func(kb *KnowledgeBase) error { return kb.FputvE("a", "s", "y") }},
+	}
+	for _, tt := range tests {
+		kb := NewKnowledgeBase()
+		if err := kb.OpenJournal(t.TempDir(), 0); err != nil {
+			t.Fatal(err)
+		}
+		kb.Fcreatef("a")
+		kb.Fcreates("a", "s")
+		kb.Fcreatev("a", "s")
+		kb.Fputv("a", "s", "x")
+		kb.Fcreatex("m")
+		j := kb.GetJournal()
+		j.mu.Lock()
+		j.fh.Close()
+		j.mu.Unlock()
+		want := kbdump(kb)
+		if err := tt.fn(kb); !errors.Is(err, ErrIO) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, ErrIO)
+		}
+		if got := kbdump(kb); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: frames changed to %v", tt.name, got)
+		}
+		if !kb.Fexistx("m") || kb.Fexistx("n") {
+			t.Errorf("%s: methods changed", tt.name)
+		}
+	}
+}
+
+// fchanger - a storage whose Changer fails
+type fchanger struct {
+	*MemStorage
+}
+
+func (fchanger) Apply([]Change) error {
+	return ErrIO
+}
+
+func TestJournalChangerFails(t *testing.T) {
+	dir := t.TempDir()
+	kb := NewKnowledgeBase()
+	kb.OpenJournal(dir, 0)
+	kb.Fcreatef("a")
+	kb.SetStorage(fchanger{NewMemStorage()})
+	if err := kb.FremovefE("a"); !errors.Is(err, ErrIO) {
+		t.Errorf("fremovef: got %v, want %v", err, ErrIO)
+	}
+	if err := kb.FcreatefE("b"); !errors.Is(err, ErrIO) {
+		t.Errorf("fcreatef: got %v, want %v", err, ErrIO)
+	}
+	kb2 := NewKnowledgeBase()
+	kb2.OpenJournal(dir, 0)
+	if !kb2.Fexistf("a") || kb2.Fexistf("b") {
+		t.Errorf("replay: got %q, want [a]", kb2.Flistf())
+	}
+}
@@ -0,0 +1,381 @@
+/**********************************************************************
+ *
+ * file name:    json.go
+ * description:  JSON import and export of frames
+ *
+ * A frame is written as a JSON object holding its name, its slots and,
+ * for a frameset, its members. Slots and facets keep their order:
+ *
+ *	{
+ *	  "name": "car",
+ *	  "slots": {
+ *	    "color": {"value": ["red"], "type": "string", "ifputv": "paint"},
+ *	    "owner": {"ref": "person"},
+ *	    "drive": {"method": "drive"}
+ *	  },
+ *	  "demons": {"ifincludef": "register"},
+ *	  "members": ["car1", "car2"]
+ *	}
+ *
+ * Each slot maps its facet types (value, method, ref, type or a demon
+ * type) to the facet contents. A value or default facet is always a
+ * list of strings; any other facet is a string, or a list of strings
+ * when it holds more than one. "demons" maps the frame demon types of
+ * the frame to their methods, in the same way, and is present only if
+ * it has any.
+ * "members" is present only for a frameset, and lists its frames in
+ * order. The commands write and read the name of a frame as given;
+ * Frame, which is only the map of a frame, finds its name from its one
+ * <fname>,slots element, and fails if that is ambiguous.
+ *
+ * Framesets and whole knowledge bases are written as a list of frames,
+ * a frameset first and then its members:
+ *
+ *	{"frames": [{"name": "cars", ...}, {"name": "car1", ...}]}
+ *
+ *							Functions
+ *
+ * Fexport					export every frame as JSON
+ * Fexportf					export a frame as JSON
+ * Fexportfs				export a frameset and its members as JSON
+ * Fimport					import a list of frames from JSON
+ * Fimportf					import a frame from JSON
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fnamef - name of the frame held in a frame map
+// the frame is the one whose slots element is not a facet of a slot of
+// the same name; "" if no frame, or more than one, is found
+func fnamef(f Frame) string {
+	names := []string{}
+	for k := range f {
+		if fname, ok := strings.CutSuffix(k, ",slots"); ok && !Fmember(f[fname+",facets"], "slots") {
+			names = append(names, fname)
+		}
+	}
+	if len(names) != 1 {
+		return ""
+	}
+	return names[0]
+}
+
+// jnamed - a frame and its name, as read and written by the commands
+type jnamed struct {
+	name  string
+	frame Frame
+}
+
+// jstring - append a string to a JSON buffer
+func jstring(b *bytes.Buffer, s string) {
+	x, _ := json.Marshal(s)
+	b.Write(x)
+}
+
+// jlist - append a list of strings to a JSON buffer
+func jlist(b *bytes.Buffer, lista []string) {
+	if lista == nil {
+		lista = []string{}
+	}
+	x, _ := json.Marshal(lista)
+	b.Write(x)
+}
+
+// MarshalJSON - write a frame as JSON
+// the name of the frame is taken from its slots element
+func (f Frame) MarshalJSON() ([]byte, error) {
+	fname := fnamef(f)
+	if fname == "" {
+		return nil, fmt.Errorf("%w: frame has no single slots element", ErrFormat)
+	}
+	return jnamed{fname, f}.MarshalJSON()
+}
+
+// MarshalJSON - write a frame of a given name as JSON
+func (x jnamed) MarshalJSON() ([]byte, error) {
+	fname, f := x.name, x.frame
+	if _, ok := f[fname+",slots"]; !ok {
+		return nil, fmt.Errorf("%w: frame %q has no slots element", ErrFormat, fname)
+	}
+	var b bytes.Buffer
+	b.WriteString(`{"name":`)
+	jstring(&b, fname)
+	b.WriteString(`,"slots":{`)
+	for i, sname := range f[fname+",slots"] {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		jstring(&b, sname)
+		b.WriteString(":{")
+		for j, ftype := range f[sname+",facets"] {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			jstring(&b, ftype)
+			b.WriteByte(':')
+			if value := f[sname+","+ftype]; ftype == "value" || ftype == "default" || len(value) > 1 {
+				jlist(&b, value)
+			} else {
+				jstring(&b, Getval(value))
+			}
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte('}')
+	demons := false
+	for _, dname := range fdemonsf {
+		if mname, ok := f[fdemonk(fname, dname)]; ok {
+			if demons {
+				b.WriteByte(',')
+			} else {
+				b.WriteString(`,"demons":{`)
+				demons = true
+			}
+			jstring(&b, dname)
+			b.WriteByte(':')
+			if len(mname) > 1 {
+				jlist(&b, mname)
+			} else {
+				jstring(&b, Getval(mname))
+			}
+		}
+	}
+	if demons {
+		b.WriteByte('}')
+	}
+	if set, ok := f[fname+",set"]; ok {
+		b.WriteString(`,"members":`)
+		jlist(&b, set)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// jobject - the keys and raw values of a JSON object, in order
+func jobject(data []byte) ([]string, []json.RawMessage, error) {
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil, nil, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil, fmt.Errorf("%w: expected an object", ErrFormat)
+	}
+	keys := []string{}
+	values := []json.RawMessage{}
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		var v json.RawMessage
+		if err := d.Decode(&v); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		keys = append(keys, t.(string))
+		values = append(values, v)
+	}
+	return keys, values, nil
+}
+
+// jfacet - read the contents of a facet, a string or a list of strings
+func jfacet(data json.RawMessage) ([]string, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s == "" {
+			return []string{}, nil
+		}
+		return []string{s}, nil
+	}
+	lista := []string{}
+	if err := json.Unmarshal(data, &lista); err != nil {
+		return nil, fmt.Errorf("%w: facet is not a string or a list of strings", ErrFormat)
+	}
+	if lista == nil {
+		lista = []string{}
+	}
+	return lista, nil
+}
+
+// UnmarshalJSON - read a frame from JSON
+func (f *Frame) UnmarshalJSON(data []byte) error {
+	var x jnamed
+	if err := x.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*f = x.frame
+	return nil
+}
+
+// UnmarshalJSON - read a frame and its name from JSON
+func (f *jnamed) UnmarshalJSON(data []byte) error {
+	var x struct {
+		Name    string                     `json:"name"`
+		Slots   json.RawMessage            `json:"slots"`
+		Demons  map[string]json.RawMessage `json:"demons"`
+		Members *[]string                  `json:"members"`
+	}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if x.Name == "" {
+		return fmt.Errorf("%w: frame has no name", ErrFormat)
+	}
+	frame := Frame{x.Name + ",slots": {}}
+	snames, slots, err := jobject(x.Slots)
+	if err != nil {
+		return err
+	}
+	for i, sname := range snames {
+		if Fmember(frame[x.Name+",slots"], sname) {
+			return fmt.Errorf("%w: slot %q appears twice", ErrFormat, sname)
+		}
+		ftypes, facets, err := jobject(slots[i])
+		if err != nil {
+			return err
+		}
+		frame[x.Name+",slots"] = append(frame[x.Name+",slots"], sname)
+		frame[sname+",facets"] = append([]string{}, ftypes...)
+		for j, ftype := range ftypes {
+			value, err := jfacet(facets[j])
+			if err != nil {
+				return err
+			}
+			frame[sname+","+ftype] = value
+		}
+	}
+	for dname, data := range x.Demons {
+		if !Fmember(fdemonsf, dname) {
+			return fmt.Errorf("%w: no frame demon %q", ErrFormat, dname)
+		}
+		mnames, err := jfacet(data)
+		if err != nil {
+			return err
+		}
+		frame[fdemonk(x.Name, dname)] = mnames
+	}
+	if x.Members != nil {
+		frame[x.Name+",set"] = append([]string{}, *x.Members...)
+	}
+	if err := fcheckt(frame, x.Name); err != nil {
+		return err
+	}
+	*f = jnamed{x.Name, frame}
+	return nil
+}
+
+// fexportf - export a frame as JSON
+// requires that fframes[fname] exists
+func (kb *KnowledgeBase) Fexportf(fname string) ([]byte, error) {
+	x := kb.copyf(fname)
+	if x == nil {
+		return nil, ferror("fexportf", fname, "", ErrFrameNotFound)
+	}
+	data, err := json.Marshal(jnamed{fname, x})
+	return data, ferror("fexportf", fname, "", err)
+}
+
+// fexportl - export a list of frames as JSON
+func fexportl(op string, frames []jnamed) ([]byte, error) {
+	x := struct {
+		Frames []jnamed `json:"frames"`
+	}{frames}
+	data, err := json.Marshal(x)
+	return data, ferror(op, "", "", err)
+}
+
+// fexportfs - export a frameset and its members as JSON
+// requires that fframes[name] and its members exist
+func (kb *KnowledgeBase) Fexportfs(name string) ([]byte, error) {
+	s, err := kb.FslistfE(name)
+	if err != nil {
+		return nil, ferror("fexportfs", name, "", err)
+	}
+	frames := []jnamed{}
+	for _, i := range append([]string{name}, s...) {
+		x := kb.copyf(i)
+		if x == nil {
+			return nil, ferror("fexportfs", i, "", ErrFrameNotFound)
+		}
+		frames = append(frames, jnamed{i, x})
+	}
+	return fexportl("fexportfs", frames)
+}
+
+// fexport - export every frame as JSON, ordered by name
+func (kb *KnowledgeBase) Fexport() ([]byte, error) {
+	names := kb.Flistf()
+	sort.Strings(names)
+	frames := []jnamed{}
+	for _, i := range names {
+		if x := kb.copyf(i); x != nil {
+			frames = append(frames, jnamed{i, x})
+		}
+	}
+	return fexportl("fexport", frames)
+}
+
+// fimportf - import a frame from JSON
+// requires that the frame does not exist
+// modifies fframes[fname]
+func (kb *KnowledgeBase) Fimportf(data []byte) error {
+	var x jnamed
+	if err := json.Unmarshal(data, &x); err != nil {
+		return ferror("fimportf", "", "", err)
+	}
+	if err := kb.setf(x.name, x.frame, true, false); err != nil {
+		return ferror("fimportf", x.name, "", err)
+	}
+	return nil
+}
+
+// fimport - import a list of frames from JSON, as written by Fexport or Fexportfs
+// either every frame is imported or none is
+// requires that none of the frames exist
+// modifies fframes
+func (kb *KnowledgeBase) Fimport(data []byte) error {
+	defer kb.group()()
+	var x struct {
+		Frames []jnamed `json:"frames"`
+	}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return ferror("fimport", "", "", err)
+	}
+	frames := []string{}
+	for _, f := range x.Frames {
+		if Fmember(frames, f.name) || kb.Fexistf(f.name) {
+			return ferror("fimport", f.name, "", ErrFrameExists)
+		}
+		frames = append(frames, f.name)
+	}
+	for i, f := range x.Frames {
+		if err := kb.setf(f.name, f.frame, true, false); err != nil {
+			for _, j := range frames[:i] {
+				kb.delf(j)
+			}
+			return ferror("fimport", f.name, "", err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON - export every frame of a knowledge base, as Fexport
+func (kb *KnowledgeBase) MarshalJSON() ([]byte, error) {
+	return kb.Fexport()
+}
+
+// UnmarshalJSON - import frames into a knowledge base, as Fimport
+func (kb *KnowledgeBase) UnmarshalJSON(data []byte) error {
+	if kb.fkb == nil {
+		*kb = KnowledgeBase{fkb: &fkb{fframes: make(map[string]*fentry), fxtable: &fxtable{fmethods: make(map[string]Method)}}}
+	}
+	return kb.Fimport(data)
+}
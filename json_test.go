@@ -0,0 +1,94 @@
+package framesets2
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("car")
+	kb.Fcreates("car", "color")
+	kb.Fcreatev("car", "color")
+	kb.Fputvl("car", "color", []string{"red", "a,b"})
+	kb.Fcreated("car", "color", "ifputv")
+	kb.Fputd("car", "color", "ifputv", "paint")
+	kb.Fcreates("car", "drive")
+	kb.Fcreatem("car", "drive")
+	kb.Fputm("car", "drive", "go")
+	kb.Fcreates("car", "empty")
+	kb.Fcreatev("car", "empty")
+	kb.Fcreatefs("cars")
+	kb.Fsincludef("cars", "car")
+	data, err := kb.Fexportfs("cars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb2 := NewKnowledgeBase()
+	if err := kb2.Fimport(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, fname := range []string{"car", "cars"} {
+		if got, want := kb2.copyf(fname), kb.copyf(fname); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", fname, got, want)
+		}
+	}
+	if err := kb2.Fimport(data); !errors.Is(err, ErrFrameExists) {
+		t.Errorf("import again: got %v, want %v", err, ErrFrameExists)
+	}
+	all, _ := json.Marshal(kb)
+	var kb3 KnowledgeBase
+	if err := json.Unmarshal(all, &kb3); err != nil {
+		t.Fatal(err)
+	}
+	if len(kb3.Flistf()) != 2 || kb3.Fgetv("car", "color") != "red" {
+		t.Errorf("unmarshal: got %q", kb3.Flistf())
+	}
+}
+
+func TestJSONFrameName(t *testing.T) {
+	// a slot named after the frame with a facet named slots gives the
+	// frame map two elements ending in ,slots
+	kb := NewKnowledgeBase()
+	kb.Fimportf([]byte(`{"name":"x","slots":{"y":{"slots":"a"}}}`))
+	data, err := kb.Fexportf("x")
+	if err != nil || !strings.HasPrefix(string(data), `{"name":"x"`) {
+		t.Fatalf("fexportf: got %s, %v", data, err)
+	}
+	tests := []struct {
+		name  string
+		frame Frame
+		want  string
+	}{
+		{"frame", Frame{"x,slots": {"y"}, "y,facets": {"value"}, "y,value": {}}, "x"},
+		{"facet named slots", kb.copyf("x"), "x"},
+		{"no slots", Frame{"y,facets": {}}, ""},
+		{"two frames", Frame{"x,slots": {}, "z,slots": {}}, ""},
+	}
+	for _, tt := range tests {
+		if got := fnamef(tt.frame); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"value not strings", `{"name":"x","slots":{"s":{"value":[1]}}}`, ErrFormat},
+		{"no name", `{"slots":{}}`, ErrFormat},
+		{"slot twice", `{"name":"x","slots":{"s":{},"s":{}}}`, ErrFormat},
+		{"bad demon", `{"name":"x","slots":{},"demons":{"ifputv":"m"}}`, ErrFormat},
+	}
+	for _, tt := range tests {
+		if err := NewKnowledgeBase().Fimportf([]byte(tt.data)); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package framesets2
+
+import "testing"
+
+func TestKnowledgeBaseIsolation(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatev("a", "s")
+	kb.Fputv("a", "s", "x")
+	Fcreatef("b")
+	defer Fremovef("b")
+	if got := kb.Fgetv("a", "s"); got != "x" {
+		t.Errorf("value in instance: got %q, want x", got)
+	}
+	if Fexistf("a") {
+		t.Error("frame of an instance exists in the default knowledge base")
+	}
+	if !Fexistf("b") {
+		t.Error("frame of the default knowledge base does not exist in it")
+	}
+	if kb.Fexistf("b") {
+		t.Error("frame of the default knowledge base exists in an instance")
+	}
+	if NewKnowledgeBase().Fexistf("a") {
+		t.Error("frame of an instance exists in another")
+	}
+}
+
+func TestKnowledgeBaseMethods(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb2 := NewKnowledgeBase()
+	called := ""
+	kb.Fcreatex("m")
+	kb.Fputx("m", func(fname string) { called = fname })
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatem("a", "s")
+	kb.Fputm("a", "s", "m")
+	kb.Fexecm("a", "s")
+	if called != "a" {
+		t.Errorf("fexecm: called for %q, want a", called)
+	}
+	if kb2.Fexistx("m") || Fexistx("m") {
+		t.Error("method of an instance exists in another knowledge base")
+	}
+}
@@ -0,0 +1,498 @@
+/**********************************************************************
+ *
+ * file name:    kvstorage.go
+ * description:  frames stored in a log of element changes
+ *
+ * KVStorage keeps frames in a single append-only log file. Each element
+ * of a frame, fframes[<fname>][<ename>], is a key, and every change to
+ * it is a record appended to the log, so changing one value of a huge
+ * knowledge base writes one short record rather than whole frames. A
+ * KVStorage is a Changer: once a frame is stored, every change made to
+ * it in memory (Fputv, Fputr, Fcreates and so on) is written to the log
+ * as it happens. So is replacing the whole frame, as Fcopyf onto it
+ * does, while removing it with Fremovef removes it from the log.
+ *
+ * The log starts with a header line naming the format and its version.
+ * Every other line is a record holding Go quoted strings: "p", the
+ * frame, the element and its values to put an element; "d", the frame
+ * and the element to remove it; or "c" alone to commit the records
+ * before it. Records are only used once committed, so a store, a batch
+ * or the changes of one command are written all or nothing, and a
+ * record torn by a crash is dropped when the log is opened again. Only
+ * the last line, missing its newline, can be torn; a bad line anywhere
+ * else fails to open with ErrFormat rather than losing what follows:
+ *
+ *	framesets2 log 2
+ *	"p" "car" "car,slots" "color"
+ *	"p" "car" "color,facets" "value"
+ *	"c"
+ *	"p" "car" "color,value" "red"
+ *	"c"
+ *
+ * Opening the log reads it once to find where the latest record of each
+ * element is, but keeps no values in memory; a frame is read from the
+ * log when it is loaded. When more than half of the log is taken up by
+ * records which were replaced or removed, it is compacted: the latest
+ * records are copied to a new log, which is renamed over the old one.
+ *
+ *							Functions
+ *
+ * OpenKVStorage			open or create a log
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// lheader - start of the header line of a log
+const lheader = "framesets2 log "
+
+// lcompact - smallest amount of replaced records worth compacting
+const lcompact = 1 << 20
+
+// krecord - where the latest record of an element is in the log
+type krecord struct {
+	off int64
+	n   int64
+}
+
+// kop - a record to be written
+type kop struct {
+	del   bool
+	fname string
+	key   string
+	value []string
+}
+
+// KVStorage - frames stored in a log of element changes
+type KVStorage struct {
+	mu     sync.Mutex
+	path   string
+	fh     *os.File
+	size   int64
+	live   int64
+	index  map[string]map[string]krecord
+	nosync bool
+}
+
+// OpenKVStorage - open a log, creating it if it does not exist
+func OpenKVStorage(path string) (*KVStorage, error) {
+	s := &KVStorage{path: path}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path - the log file
+func (s *KVStorage) Path() string {
+	return s.path
+}
+
+// SetSync - set whether every write is synced to disk, which it is
+// unless turned off
+func (s *KVStorage) SetSync(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nosync = !on
+}
+
+// Close - close the log
+func (s *KVStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fh == nil {
+		return ioerror(os.ErrClosed)
+	}
+	err := s.fh.Close()
+	s.fh = nil
+	return ioerror(err)
+}
+
+// open - open the log and find the latest record of every element
+// a tail of records which were never committed is cut off
+func (s *KVStorage) open() error {
+	fh, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return ioerror(err)
+	}
+	index, size, live, err := kscan(fh)
+	if err == nil {
+		if size == 0 {
+			header := lheader + strconv.Itoa(FormatVersion) + "\n"
+			_, err = fh.WriteAt([]byte(header), 0)
+			size = int64(len(header))
+		}
+		if err == nil {
+			err = fh.Truncate(size)
+		}
+		if err == nil {
+			err = fh.Sync()
+		}
+		err = ioerror(err)
+	}
+	if err != nil {
+		fh.Close()
+		return err
+	}
+	s.fh, s.index, s.size, s.live = fh, index, size, live
+	return nil
+}
+
+// kscan - read a log, returning the latest record of every element,
+// the end of the last commit and the size of the latest records
+func kscan(r io.Reader) (map[string]map[string]krecord, int64, int64, error) {
+	index := make(map[string]map[string]krecord)
+	reader := bufio.NewReader(r)
+	line, err := reader.ReadString('\n')
+	if line == "" && err == io.EOF {
+		return index, 0, 0, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, 0, 0, ioerror(err)
+	}
+	v, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), lheader)
+	if n, verr := strconv.Atoi(v); err != nil || !ok || verr != nil || n < 2 || n > FormatVersion {
+		return nil, 0, 0, fmt.Errorf("%w: not a log of a known version", ErrFormat)
+	}
+	off := int64(len(line))
+	size, live := off, int64(0)
+	pending := []kop{}
+	records := []krecord{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// a last line torn before its newline ends the log
+			if err != io.EOF {
+				return nil, 0, 0, ioerror(err)
+			}
+			break
+		}
+		kind, items, derr := fdecodel(strings.TrimSuffix(line, "\n"))
+		if derr != nil {
+			// a whole line is never torn, so it is not dropped
+			return nil, 0, 0, fmt.Errorf("%w: bad record at offset %d", ErrFormat, off)
+		}
+		rec := krecord{off: off, n: int64(len(line))}
+		off += rec.n
+		switch {
+		case kind == "c" && len(items) == 0:
+			for i, op := range pending {
+				live += kapply(index, op, records[i])
+			}
+			pending, records = pending[:0], records[:0]
+			size = off
+		case kind == "p" && len(items) >= 2:
+			pending = append(pending, kop{fname: items[0], key: items[1]})
+			records = append(records, rec)
+		case kind == "d" && len(items) == 2:
+			pending = append(pending, kop{del: true, fname: items[0], key: items[1]})
+			records = append(records, rec)
+		default:
+			return nil, 0, 0, fmt.Errorf("%w: bad record at offset %d", ErrFormat, rec.off)
+		}
+	}
+	return index, size, live, nil
+}
+
+// kapply - enter a committed record in the index
+// returns the change in the size of the latest records
+func kapply(index map[string]map[string]krecord, op kop, rec krecord) int64 {
+	keys := index[op.fname]
+	old, found := keys[op.key]
+	n := int64(0)
+	if found {
+		n -= old.n
+	}
+	if op.del {
+		if found {
+			delete(keys, op.key)
+			if len(keys) == 0 {
+				delete(index, op.fname)
+			}
+		}
+		return n
+	}
+	if keys == nil {
+		keys = make(map[string]krecord)
+		index[op.fname] = keys
+	}
+	keys[op.key] = rec
+	return n + rec.n
+}
+
+// write - append a list of records and a commit to the log
+// requires that s.mu is held
+func (s *KVStorage) write(ops []kop) error {
+	if s.fh == nil {
+		return ioerror(os.ErrClosed)
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	records := make([]krecord, len(ops))
+	for i, op := range ops {
+		var line string
+		if op.del {
+			line = fencodel("d", []string{op.fname, op.key})
+		} else {
+			line = fencodel("p", append([]string{op.fname, op.key}, op.value...))
+		}
+		records[i] = krecord{off: s.size + int64(b.Len()), n: int64(len(line))}
+		b.WriteString(line)
+	}
+	b.WriteString(fencodel("c", nil))
+	_, err := s.fh.WriteAt(b.Bytes(), s.size)
+	if err == nil && !s.nosync {
+		err = s.fh.Sync()
+	}
+	if err != nil {
+		s.fh.Truncate(s.size)
+		return ioerror(err)
+	}
+	s.size += int64(b.Len())
+	for i, op := range ops {
+		s.live += kapply(s.index, op, records[i])
+	}
+	if dead := s.size - s.live; dead > lcompact && dead > s.live {
+		// the records are committed, so a failed compaction loses nothing
+		s.compact()
+	}
+	return nil
+}
+
+// read - read the values of a record
+// requires that s.mu is held
+func (s *KVStorage) read(rec krecord) ([]string, error) {
+	b := make([]byte, rec.n)
+	if _, err := s.fh.ReadAt(b, rec.off); err != nil {
+		return nil, ioerror(err)
+	}
+	kind, items, err := fdecodel(strings.TrimSuffix(string(b), "\n"))
+	if err != nil || kind != "p" || len(items) < 2 {
+		return nil, fmt.Errorf("%w: bad record at offset %d", ErrFormat, rec.off)
+	}
+	return items[2:], nil
+}
+
+// load - read a frame
+// requires that s.mu is held
+func (s *KVStorage) load(fname string) (Frame, error) {
+	if s.fh == nil {
+		return nil, ioerror(os.ErrClosed)
+	}
+	keys, ok := s.index[fname]
+	if !ok {
+		return nil, ErrFrameNotFound
+	}
+	x := Frame{fname + ",slots": {}}
+	for k, rec := range keys {
+		value, err := s.read(rec)
+		if err != nil {
+			return nil, err
+		}
+		x[k] = value
+	}
+	return x, nil
+}
+
+// store - records replacing a stored frame
+func (s *KVStorage) store(fname string, x Frame) []kop {
+	ops := []kop{}
+	for k := range s.index[fname] {
+		if _, ok := x[k]; !ok {
+			ops = append(ops, kop{del: true, fname: fname, key: k})
+		}
+	}
+	for k, v := range x {
+		ops = append(ops, kop{fname: fname, key: k, value: v})
+	}
+	return ops
+}
+
+// remove - records removing a stored frame
+func (s *KVStorage) remove(fname string) []kop {
+	ops := []kop{}
+	for k := range s.index[fname] {
+		ops = append(ops, kop{del: true, fname: fname, key: k})
+	}
+	return ops
+}
+
+// Compact - copy the latest records to a new log and replace the old one
+func (s *KVStorage) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+// compact - copy the latest records to a new log
+// requires that s.mu is held
+func (s *KVStorage) compact() error {
+	if s.fh == nil {
+		return ioerror(os.ErrClosed)
+	}
+	index := make(map[string]map[string]krecord, len(s.index))
+	var size, live int64
+	tmp, err := ftemp(s.path, func(w io.Writer) error {
+		writer := bufio.NewWriter(w)
+		header := lheader + strconv.Itoa(FormatVersion) + "\n"
+		writer.WriteString(header)
+		size = int64(len(header))
+		fnames := make([]string, 0, len(s.index))
+		for k := range s.index {
+			fnames = append(fnames, k)
+		}
+		sort.Strings(fnames)
+		for _, fname := range fnames {
+			keys := make(map[string]krecord, len(s.index[fname]))
+			for k, rec := range s.index[fname] {
+				b := make([]byte, rec.n)
+				if _, err := s.fh.ReadAt(b, rec.off); err != nil {
+					return err
+				}
+				writer.Write(b)
+				keys[k] = krecord{off: size, n: rec.n}
+				size += rec.n
+				live += rec.n
+			}
+			index[fname] = keys
+		}
+		commit := fencodel("c", nil)
+		writer.WriteString(commit)
+		size += int64(len(commit))
+		return writer.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return ioerror(err)
+	}
+	fsyncd(s.path)
+	fh, err := os.OpenFile(s.path, os.O_RDWR, 0644)
+	if err != nil {
+		// the old file is gone, so the log can not go on
+		s.fh.Close()
+		s.fh = nil
+		return ioerror(err)
+	}
+	s.fh.Close()
+	s.fh, s.index, s.size, s.live = fh, index, size, live
+	return nil
+}
+
+// Load - read a frame
+func (s *KVStorage) Load(fname string) (Frame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(fname)
+}
+
+// LoadAll - read a list of frames, failing if any of them fails
+func (s *KVStorage) LoadAll(fnames []string) ([]Frame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	frames := []Frame{}
+	for _, i := range fnames {
+		x, err := s.load(i)
+		if err != nil {
+			return nil, ferror("load", i, "", err)
+		}
+		frames = append(frames, x)
+	}
+	return frames, nil
+}
+
+// Store - write a frame
+func (s *KVStorage) Store(fname string, x Frame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(s.store(fname, x))
+}
+
+// StoreAll - write a list of frames, either all of them or none
+func (s *KVStorage) StoreAll(fnames []string, frames []Frame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops := []kop{}
+	for i, fname := range fnames {
+		ops = append(ops, s.store(fname, frames[i])...)
+	}
+	return s.write(ops)
+}
+
+// Delete - remove a stored frame
+func (s *KVStorage) Delete(fname string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index[fname]; !ok {
+		return ErrFrameNotFound
+	}
+	return s.write(s.remove(fname))
+}
+
+// DeleteAll - remove a list of stored frames, as many as can be
+func (s *KVStorage) DeleteAll(fnames []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := []error{}
+	ops := []kop{}
+	for _, i := range fnames {
+		if _, ok := s.index[i]; !ok {
+			errs = append(errs, ferror("delete", i, "", ErrFrameNotFound))
+		}
+		ops = append(ops, s.remove(i)...)
+	}
+	return errors.Join(append(errs, s.write(ops))...)
+}
+
+// List - names of the stored frames, in sorted order
+func (s *KVStorage) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	frames := []string{}
+	for k := range s.index {
+		frames = append(frames, k)
+	}
+	sort.Strings(frames)
+	return frames, nil
+}
+
+// Apply - write the changes made to stored frames in memory
+// a whole frame replaced or removed has every stored element removed
+func (s *KVStorage) Apply(changes []Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops := []kop{}
+	cleared := map[string]bool{}
+	for _, c := range changes {
+		if _, ok := s.index[c.Frame]; !ok {
+			continue
+		}
+		if c.Key == "" {
+			ops = append(ops, s.remove(c.Frame)...)
+			cleared[c.Frame] = true
+		} else if c.New == nil && cleared[c.Frame] {
+			// removed with the frame already
+			continue
+		} else {
+			ops = append(ops, kop{del: c.New == nil, fname: c.Frame, key: c.Key, value: c.New})
+		}
+	}
+	return s.write(ops)
+}
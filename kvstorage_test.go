@@ -0,0 +1,176 @@
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openkv - open a log for a test, closed when the test ends
+func openkv(t *testing.T, path string) *KVStorage {
+	s, err := OpenKVStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestKVStorageBackend(t *testing.T) {
+	fstorage(t, openkv(t, filepath.Join(t.TempDir(), "kb.log")), nil)
+}
+
+func TestKVStorageChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kb.log")
+	s := openkv(t, path)
+	kb := NewKnowledgeBase()
+	kb.SetStorage(s)
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatev("a", "s")
+	if err := kb.FstorefE("a"); err != nil {
+		t.Fatal(err)
+	}
+	kb.Fputv("a", "s", "hello, world")
+	kb.Fcreates("a", "t")
+	kb.Fcreatef("b")
+	kb.Fcreates("b", "x")
+	s.Close()
+	kb2 := NewKnowledgeBase()
+	kb2.SetStorage(openkv(t, path))
+	if err := kb2.FloadfE("a"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(kb.copyf("a"), kb2.copyf("a")) {
+		t.Errorf("got %v, want %v", kb2.copyf("a"), kb.copyf("a"))
+	}
+	if err := kb2.FloadfE("b"); !errors.Is(err, ErrFrameNotFound) {
+		t.Errorf("frame not stored: got %v, want %v", err, ErrFrameNotFound)
+	}
+}
+
+func TestKVStorageWholeFrames(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(kb *KnowledgeBase) error
+		want Frame // nil if dst is no longer stored
+	}{
+		{"fcopyf over", func(kb *KnowledgeBase) error {
+			return kb.FcopyfE("src", "dst")
+		}, Frame{"dst,slots": {"p"}, "p,facets": {"value"}, "p,value": {"1"}}},
+		{"fremovef", func(kb *KnowledgeBase) error {
+			return kb.FremovefE("dst")
+		}, nil},
+		{"fremovef and fcreatef", func(kb *KnowledgeBase) error {
+			if err := kb.FremovefE("dst"); err != nil {
+				return err
+			}
+			return kb.FcreatefE("dst")
+		}, nil},
+		{"fremovefs and fcreatefs", func(kb *KnowledgeBase) error {
+			if err := kb.FremovefsE("dst"); err != nil {
+				return err
+			}
+			return kb.FcreatefsE("dst")
+		}, nil},
+		{"transaction", func(kb *KnowledgeBase) error {
+			tx := kb.Begin()
+			tx.Fremovef("dst")
+			tx.Fcopyf("src", "dst")
+			return tx.Commit()
+		}, Frame{"dst,slots": {"p"}, "p,facets": {"value"}, "p,value": {"1"}}},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "kb.log")
+		kb := NewKnowledgeBase()
+		kb.SetStorage(openkv(t, path))
+		kb.Fcreatef("src")
+		kb.Fcreates("src", "p")
+		kb.Fcreatev("src", "p")
+		kb.Fputv("src", "p", "1")
+		kb.Fcreatef("dst")
+		kb.Fcreates("dst", "q")
+		kb.Fcreatev("dst", "q")
+		kb.Fputv("dst", "q", "1")
+		kb.Fstoref("dst")
+		if err := tt.fn(kb); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		kb.GetStorage().(*KVStorage).Close()
+		x, err := openkv(t, path).Load("dst")
+		if tt.want == nil {
+			if !errors.Is(err, ErrFrameNotFound) {
+				t.Errorf("%s: got %v, %v, want no frame", tt.name, x, err)
+			}
+		} else if !reflect.DeepEqual(x, tt.want) {
+			t.Errorf("%s: got %v, %v, want %v", tt.name, x, err, tt.want)
+		}
+	}
+}
+
+func TestKVStorageImportStored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kb.log")
+	kb := NewKnowledgeBase()
+	kb.SetStorage(openkv(t, path))
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "old")
+	kb.Fstoref("a")
+	kb2 := NewKnowledgeBase()
+	kb2.SetStorage(kb.GetStorage())
+	if err := kb2.Fimportf([]byte(`{"name":"a","slots":{"new":{}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	x, _ := kb.GetStorage().Load("a")
+	if want := (Frame{"a,slots": {"new"}, "new,facets": {}}); !reflect.DeepEqual(x, want) {
+		t.Errorf("got %v, want %v", x, want)
+	}
+}
+
+func TestKVStorageLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kb.log")
+	s := openkv(t, path)
+	s.Store("a", fvframe("a", "hello"))
+	s.Close()
+	// a torn tail is dropped
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString("\"p\" \"a\" \"s,value\" \"bad\"\n\"p\" \"a\"")
+	f.Close()
+	s = openkv(t, path)
+	if x, _ := s.Load("a"); Getval(x["s,value"]) != "hello" {
+		t.Errorf("torn tail: got %v", x)
+	}
+	// a bad line followed by more is not a torn tail
+	s.Close()
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, append(data, "\"p\" \"a\" \"s,value\" bad\n\"c\"\n"...), 0644)
+	if _, err := OpenKVStorage(path); !errors.Is(err, ErrFormat) {
+		t.Errorf("bad line: got %v, want %v", err, ErrFormat)
+	}
+	if got, _ := os.ReadFile(path); len(got) <= len(data) {
+		t.Error("bad line: log truncated")
+	}
+	os.WriteFile(path, data, 0644)
+	s = openkv(t, path)
+	// replaced records are compacted away
+	s.SetSync(false)
+	kb := NewKnowledgeBase()
+	kb.SetStorage(s)
+	kb.Floadf("a")
+	big := strings.Repeat("x", 1000)
+	for i := 0; i < 3000; i++ {
+		kb.Fputv("a", "s", fmt.Sprint(i, big))
+	}
+	if st, _ := os.Stat(path); st.Size() > 3<<20 {
+		t.Errorf("log not compacted: %d bytes", st.Size())
+	}
+	if x, _ := s.Load("a"); Getval(x["s,value"]) != fmt.Sprint(2999, big) {
+		t.Error("compaction lost the latest value")
+	}
+	if err := s.Compact(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,209 @@
+/**********************************************************************
+ *
+ * file name:    lifecycle.go
+ * description:  demons of frames
+ *
+ * Besides the demons of its slots, a frame may hold demons of its own,
+ * which are called when the frame itself changes:
+ *
+ *	fframes[<fname>][<fname>,demons,<dname>]	frame demon
+ *
+ * The word demons keeps the demons of a frame apart from the facets of
+ * a slot named as the frame.
+ *
+ * ifcreatef				after the frame is created by Fcreatef or Fcopyf
+ * ifremovef				before the frame is removed by Fremovef
+ * ifcreates				after a slot is created by Fcreates
+ * ifremoves				before a slot is removed by Fremoves
+ * ifincludef				after the frame is included in a frameset
+ * ifexcludef				before the frame is excluded from a frameset
+ *
+ * Each also has a before and an after demon, such as beforecreatef and
+ * aftercreatef, called before the frame changes and after it has, as
+ * for the demons of slots (see phases.go). A before demon failing stops
+ * the change; frame demons get no values to rewrite. The afterremovef
+ * demons of a frame are looked up before it is removed.
+ *
+ * The demons of a frameset are called for every member as well, after
+ * those of the member, so a frameset can watch all of its frames. A
+ * frame created by Fcreatef has no demons yet, so only the framesets
+ * listing it see it created, while a frame created by Fcopyf gets the
+ * demons of the frame it is a copy of. ifincludef and ifexcludef are
+ * called for the frame and the frameset it joins or leaves, with the
+ * name of the frameset as argument; the others are called with the
+ * frame and, for a slot, the slot. A demon called before a change
+ * failing stops the change, as with the demons of slots.
+ *
+ *							Functions
+ *
+ * Fgetdf					get a demon of a frame
+ * Fputdf					put a demon into a frame
+ * Fremovedf				remove a demon from a frame
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"fmt"
+	"sort"
+)
+
+// fdemonsf - list of frame demon types, with their before and after
+// demons
+var fdemonsf = []string{
+	"ifcreatef", "ifremovef", "ifcreates", "ifremoves", "ifincludef", "ifexcludef",
+	"beforecreatef", "beforeremovef", "beforecreates", "beforeremoves", "beforeincludef", "beforeexcludef",
+	"aftercreatef", "afterremovef", "aftercreates", "afterremoves", "afterincludef", "afterexcludef",
+}
+
+// fdemonk - the element of a frame holding a frame demon
+func fdemonk(fname, dname string) string {
+	return fname + ",demons," + dname
+}
+
+// fdemonf - determine if an element of a frame is a frame demon
+func fdemonf(fname, ename string) bool {
+	for _, i := range fdemonsf {
+		if ename == fdemonk(fname, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// fframek - determine if an element of a frame belongs to the frame
+// itself rather than to a slot, which may be named as the frame
+func fframek(fname, ename string) bool {
+	return ename == fname+",slots" || ename == fname+",set" || fdemonf(fname, ename)
+}
+
+// markdf - note that frame demons exist if a frame holds any
+func (kb *KnowledgeBase) markdf(fname string, frame Frame) {
+	for _, i := range fdemonsf {
+		if _, ok := frame[fdemonk(fname, i)]; ok {
+			kb.fdemons.Store(true)
+			return
+		}
+	}
+}
+
+// setsdf - framesets listing a frame and holding a demon, by name
+func (kb *KnowledgeBase) setsdf(fname, dname string) []string {
+	sets := []string{}
+	if !kb.fdemons.Load() {
+		return sets
+	}
+	for _, i := range kb.Flistf() {
+		kb.readf(i, func(f Frame) {
+			if len(fmethodl(f[fdemonk(i, dname)])) > 0 && Fmember(f[i+",set"], fname) {
+				sets = append(sets, i)
+			}
+		})
+	}
+	sort.Strings(sets)
+	return sets
+}
+
+// firef - call the frame demons of a frame and then those of framesets
+// the framesets listing the frame are looked up if sets is nil
+// stops at the first demon failing
+func (kb *KnowledgeBase) firef(dname, fname, sname string, sets []string, args ...any) error {
+	return kb.callf(kb.demonsf(dname, fname, sets), dname, fname, sname, args...)
+}
+
+// demonsf - methods of the frame demons of a frame and then of framesets
+// the framesets listing the frame are looked up if sets is nil
+func (kb *KnowledgeBase) demonsf(dname, fname string, sets []string) []string {
+	mnames := []string{}
+	kb.readf(fname, func(f Frame) {
+		mnames = append(mnames, fmethodl(f[fdemonk(fname, dname)])...)
+	})
+	if sets == nil {
+		sets = kb.setsdf(fname, dname)
+	}
+	for _, i := range sets {
+		kb.readf(i, func(f Frame) {
+			mnames = append(mnames, fmethodl(f[fdemonk(i, dname)])...)
+		})
+	}
+	return mnames
+}
+
+// callf - call the methods of frame demons
+// stops at the first demon failing
+func (kb *KnowledgeBase) callf(mnames []string, dname, fname, sname string, args ...any) error {
+	if len(mnames) == 0 {
+		return nil
+	}
+	defer kb.group()()
+	for _, mname := range mnames {
+		if _, err := kb.invoke(mname, ErrDemonMissing, &MethodContext{KB: kb, Frame: fname, Slot: sname, Op: dname, Args: args}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fputdf - put a demon into a frame
+// requires that fframes[fname] exists
+// modifies fframes[fname][fname,dname]
+func (kb *KnowledgeBase) Fputdf(fname, dname, mname string) bool {
+	return kb.FputdfE(fname, dname, mname) == nil
+}
+
+// fputdfe - put a demon into a frame, returning an error
+func (kb *KnowledgeBase) FputdfE(fname, dname, mname string) error {
+	if !Fmember(fdemonsf, dname) {
+		return ferror("fputdf", fname, "", fmt.Errorf("%w: no frame demon %q", ErrFacetNotFound, dname))
+	}
+	kb.fdemons.Store(true)
+	err := kb.writef(fname, func(f Frame) error {
+		f[fdemonk(fname, dname)] = []string{mname}
+		return nil
+	})
+	return ferror("fputdf", fname, "", err)
+}
+
+// fgetdf - get a demon of a frame, "" if it has none
+// requires that fframes[fname][fname,dname] exists
+func (kb *KnowledgeBase) Fgetdf(fname, dname string) string {
+	mname, _ := kb.FgetdfE(fname, dname)
+	return mname
+}
+
+// fgetdfe - get a demon of a frame, returning an error
+func (kb *KnowledgeBase) FgetdfE(fname, dname string) (string, error) {
+	mname := ""
+	found := false
+	if !kb.readf(fname, func(f Frame) {
+		var x []string
+		x, found = f[fdemonk(fname, dname)]
+		mname = Getval(fmethodl(x))
+	}) {
+		return "", ferror("fgetdf", fname, "", ErrFrameNotFound)
+	}
+	if !found || !Fmember(fdemonsf, dname) {
+		return "", ferror("fgetdf", fname, "", ErrFacetNotFound)
+	}
+	return mname, nil
+}
+
+// fremovedf - remove a demon from a frame
+// requires that fframes[fname][fname,dname] exists
+// modifies fframes[fname][fname,dname]
+func (kb *KnowledgeBase) Fremovedf(fname, dname string) bool {
+	return kb.FremovedfE(fname, dname) == nil
+}
+
+// fremovedfe - remove a demon from a frame, returning an error
+func (kb *KnowledgeBase) FremovedfE(fname, dname string) error {
+	err := kb.writef(fname, func(f Frame) error {
+		if _, ok := f[fdemonk(fname, dname)]; !ok || !Fmember(fdemonsf, dname) {
+			return ErrFacetNotFound
+		}
+		delete(f, fdemonk(fname, dname))
+		return nil
+	})
+	return ferror("fremovedf", fname, "", err)
+}
@@ -0,0 +1,202 @@
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// lkb - a knowledge base with a method rec noting each demon it is
+// called for in log, and a method veto failing
+func lkb(log *[]string) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatex("rec")
+	kb.Fputxm("rec", func(c *MethodContext) (any, error) {
+		s := c.Op + ":" + c.Frame + ":" + c.Slot
+		if len(c.Args) > 0 {
+			s += ":" + fmt.Sprint(c.Args[0])
+		}
+		*log = append(*log, s)
+		return nil, nil
+	})
+	kb.Fcreatex("veto")
+	kb.Fputxm("veto", func(c *MethodContext) (any, error) {
+		return nil, errors.New("veto")
+	})
+	return kb
+}
+
+func TestFrameDemons(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(kb *KnowledgeBase)
+		change func(kb *KnowledgeBase)
+		want   []string
+	}{
+		{"slots", func(kb *KnowledgeBase) {
+			kb.Fputdf("a", "ifcreates", "rec")
+			kb.Fputdf("a", "ifremoves", "rec")
+		}, func(kb *KnowledgeBase) {
+			kb.Fcreates("a", "x")
+			kb.Fremoves("a", "x")
+		}, []string{"ifcreates:a:x", "ifremoves:a:x"}},
+		{"frameset", func(kb *KnowledgeBase) {
+			kb.Fputdf("a", "ifincludef", "rec")
+			kb.Fputdf("a", "ifexcludef", "rec")
+			kb.Fputdf("a", "ifcreates", "rec")
+			kb.Fputdf("set", "ifincludef", "rec")
+			kb.Fputdf("set", "ifcreates", "rec")
+		}, func(kb *KnowledgeBase) {
+			kb.Fsincludef("set", "a")
+			kb.Fcreates("a", "y")
+			kb.Fsexcludef("set", "a")
+		}, []string{"ifincludef:a::set", "ifincludef:a::set", "ifcreates:a:y", "ifcreates:a:y", "ifexcludef:a::set"}},
+		{"fcopyf", func(kb *KnowledgeBase) {
+			kb.Fputdf("a", "ifcreatef", "rec")
+		}, func(kb *KnowledgeBase) {
+			kb.Fcopyf("a", "b")
+		}, []string{"ifcreatef:b:"}},
+		{"frameset members", func(kb *KnowledgeBase) {
+			kb.Fsincludef("set", "a")
+			kb.Fputdf("set", "ifcreatef", "rec")
+			kb.Fputdf("set", "ifremovef", "rec")
+		}, func(kb *KnowledgeBase) {
+			kb.Fremovef("a")
+			kb.Fcreatef("a")
+		}, []string{"ifremovef:a:", "ifcreatef:a:"}},
+	}
+	for _, tt := range tests {
+		log := []string{}
+		kb := lkb(&log)
+		kb.Fcreatef("a")
+		kb.Fcreatefs("set")
+		tt.setup(kb)
+		tt.change(kb)
+		if !reflect.DeepEqual(log, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, log, tt.want)
+		}
+	}
+}
+
+func TestFrameDemonErrors(t *testing.T) {
+	log := []string{}
+	kb := lkb(&log)
+	kb.Fcreatef("a")
+	kb.Fputdf("a", "ifremovef", "veto")
+	kb.Fputdf("a", "ifcreates", "nope")
+	tests := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{"unknown demon", func() error { return kb.FputdfE("a", "bogus", "rec") }, ErrFacetNotFound},
+		{"missing method", func() error { return kb.FcreatesE("a", "z") }, ErrDemonMissing},
+		{"missing demon", func() error { return errv(kb.FgetdfE("a", "ifexcludef")) }, ErrFacetNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if err := kb.FremovefE("a"); err == nil || !kb.Fexistf("a") {
+		t.Errorf("veto: got %v", err)
+	}
+	kb.Fremovedf("a", "ifremovef")
+	if err := kb.FremovefE("a"); err != nil {
+		t.Errorf("after fremovedf: %v", err)
+	}
+}
+
+func TestIncludeMissingSet(t *testing.T) {
+	log := []string{}
+	kb := lkb(&log)
+	kb.Fcreatef("a")
+	kb.Fputdf("a", "beforeincludef", "rec")
+	kb.Fputdf("a", "ifincludef", "rec")
+	var fe *FrameError
+	if err := kb.FsincludefE("set", "a"); !errors.Is(err, ErrFrameNotFound) || !errors.As(err, &fe) || fe.Frame != "set" {
+		t.Errorf("fsincludef: got %v, want %v for set", err, ErrFrameNotFound)
+	}
+	if len(log) != 0 {
+		t.Errorf("demons called: %q", log)
+	}
+}
+
+// TestFrameDemonSlot checks that the demons of a frame are kept apart
+// from a slot named as the frame
+func TestFrameDemonSlot(t *testing.T) {
+	log := []string{}
+	kb := lkb(&log)
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "a")
+	kb.Fcreatev("a", "a")
+	kb.Fputv("a", "a", "1")
+	kb.Fputdf("a", "ifcreatef", "rec")
+	kb.Fcopyf("a", "b")
+	kb.Fcreatef("c")
+	kb.Fmergef("a", "c")
+	kb.Fcopys("a", "a", "c")
+	kb.Fremoves("a", "a")
+	if got := kb.Fgetdf("a", "ifcreatef"); got != "rec" {
+		t.Errorf("demon after fremoves: got %q, want rec", got)
+	}
+	if got := kb.Flists("a"); len(got) != 0 {
+		t.Errorf("slots after fremoves: got %q", got)
+	}
+	if got := kb.Fgetdf("b", "ifcreatef"); got != "rec" {
+		t.Errorf("demon of the copy: got %q, want rec", got)
+	}
+	if got := kb.Fgetv("b", "a"); got != "1" {
+		t.Errorf("slot of the copy: got %q, want 1", got)
+	}
+	if got := kb.copyf("b")[fdemonk("a", "ifcreatef")]; got != nil {
+		t.Errorf("demon of the copy kept under the old name: %q", got)
+	}
+	if got := kb.Fgetdf("c", "ifcreatef"); got != "" {
+		t.Errorf("demon merged: got %q", got)
+	}
+	if got := kb.Fgetv("c", "a"); got != "1" {
+		t.Errorf("slot merged: got %q, want 1", got)
+	}
+	if want := []string{"ifcreatef:b:"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("demons called: got %q, want %q", log, want)
+	}
+}
+
+func TestFrameDemonJSON(t *testing.T) {
+	log := []string{}
+	kb := lkb(&log)
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "a")
+	kb.Fcreatev("a", "a")
+	kb.Fputdf("a", "ifcreates", "rec")
+	data, _ := kb.Fexportf("a")
+	kb2 := NewKnowledgeBase()
+	if err := kb2.Fimportf(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(kbdump(kb2), kbdump(kb)) {
+		t.Errorf("got %v from %s", kbdump(kb2), data)
+	}
+}
+
+func TestFrameDemonUndo(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.SetHistory(10)
+	kb.Fcreatex("mk")
+	kb.Fputxm("mk", func(c *MethodContext) (any, error) {
+		return nil, c.KB.FcreatesE(c.Frame, "auto")
+	})
+	kb.Fcreatefs("s")
+	kb.Fcreatef("m")
+	kb.Fputdf("s", "ifincludef", "mk")
+	kb.Fsincludef("s", "m")
+	if !kb.Fexists("m", "auto") {
+		t.Fatal("demon not called")
+	}
+	kb.Undo()
+	if kb.Fexists("m", "auto") || len(kb.Fslistf("s")) != 0 {
+		t.Error("undo left the changes of the demon")
+	}
+}
@@ -0,0 +1,141 @@
+/**********************************************************************
+ *
+ * file name:    limits.go
+ * description:  limits on nested demons and chains of references
+ *
+ * A demon putting into its own slot calls itself again, and slots
+ * referring to each other have no end, so both are limited. A demon or
+ * method called while too many others are still running, counting
+ * those called by commands they call, fails with ErrDepth instead of
+ * being called. The count is carried in the MethodContext, each demon
+ * or method one deeper than the one whose commands called it, and in
+ * the knowledge base it is handed. It is also kept for each goroutine,
+ * so a method calling commands through a knowledge base it captured is
+ * counted too, and the greater of the two is taken; commands running
+ * side by side in other goroutines do not add up. Following the
+ * references of a slot, every command stops with ErrCycle on coming
+ * back to a frame it has passed, and with ErrDepth after too many
+ * frames.
+ *
+ * Both limits are shared by the transactions and snapshots of a
+ * knowledge base, like its methods, and a limit of 0 or less puts back
+ * the default.
+ *
+ *							Functions
+ *
+ * GetDemonDepth			get the limit on nested demons and methods
+ * GetRefDepth				get the limit on references followed
+ * SetDemonDepth			set the limit on nested demons and methods
+ * SetRefDepth				set the limit on references followed
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+const (
+	DefaultDemonDepth = 64
+	DefaultRefDepth   = 64
+)
+
+// flimits - the limits of a knowledge base
+// maxd and maxr are the limits set, 0 for the defaults, and running
+// counts the demons and methods running in each goroutine
+type flimits struct {
+	lmu     sync.Mutex
+	maxd    int
+	maxr    int
+	running map[uint64]int
+}
+
+// fgoid - id of the calling goroutine
+func fgoid() uint64 {
+	var b [64]byte
+	x := bytes.TrimPrefix(b[:runtime.Stack(b[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(x, ' '); i >= 0 {
+		x = x[:i]
+	}
+	id, _ := strconv.ParseUint(string(x), 10, 64)
+	return id
+}
+
+// SetDemonDepth - set the limit on demons and methods nested
+func (kb *KnowledgeBase) SetDemonDepth(n int) {
+	kb.lmu.Lock()
+	defer kb.lmu.Unlock()
+	kb.maxd = n
+}
+
+// GetDemonDepth - get the limit on demons and methods nested
+func (kb *KnowledgeBase) GetDemonDepth() int {
+	kb.lmu.Lock()
+	defer kb.lmu.Unlock()
+	if kb.maxd <= 0 {
+		return DefaultDemonDepth
+	}
+	return kb.maxd
+}
+
+// SetRefDepth - set the limit on references followed from a slot
+func (kb *KnowledgeBase) SetRefDepth(n int) {
+	kb.lmu.Lock()
+	defer kb.lmu.Unlock()
+	kb.maxr = n
+}
+
+// GetRefDepth - get the limit on references followed from a slot
+func (kb *KnowledgeBase) GetRefDepth() int {
+	kb.lmu.Lock()
+	defer kb.lmu.Unlock()
+	if kb.maxr <= 0 {
+		return DefaultRefDepth
+	}
+	return kb.maxr
+}
+
+// enter - count a demon or method about to run in a goroutine
+// returns its depth, the greater of the count and the depth of kb, one
+// deeper, and the function counting it done
+func (kb *KnowledgeBase) enter() (int, func()) {
+	id := fgoid()
+	kb.lmu.Lock()
+	defer kb.lmu.Unlock()
+	if kb.running == nil {
+		kb.running = make(map[uint64]int)
+	}
+	kb.running[id]++
+	return max(kb.running[id], kb.depth+1), func() {
+		kb.lmu.Lock()
+		defer kb.lmu.Unlock()
+		if kb.running[id]--; kb.running[id] == 0 {
+			delete(kb.running, id)
+		}
+	}
+}
+
+// fdeeper - check the depth of a demon or method about to run
+func (kb *KnowledgeBase) fdeeper(c *MethodContext) error {
+	if max := kb.GetDemonDepth(); c.Depth > max {
+		return fmt.Errorf("%w: more than %d demons and methods nested at %s", ErrDepth, max, c.Op)
+	}
+	return nil
+}
+
+// fpassed - check the frames a chain of references has passed
+// fname is the frame about to be passed
+func (kb *KnowledgeBase) fpassed(plist []string, fname, sname string) error {
+	if Fmember(plist, fname) {
+		return fmt.Errorf("%w: references of %q come back to %q", ErrCycle, sname, fname)
+	}
+	if max := kb.GetRefDepth(); len(plist) >= max {
+		return fmt.Errorf("%w: more than %d references followed from %q", ErrDepth, max, sname)
+	}
+	return nil
+}
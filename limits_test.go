@@ -0,0 +1,258 @@
+package framesets2
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// lpkb - a knowledge base with a demon on a.x putting into its own slot
+func lpkb(calls *int) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatex("loop")
+	kb.FputxmE("loop", func(c *MethodContext) (any, error) {
+		*calls++
+		return nil, c.KB.FputvE(c.Frame, c.Slot, "again")
+	})
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "x")
+	kb.Fcreatev("a", "x")
+	kb.Faddd("a", "x", "ifputv", "loop", 0)
+	return kb
+}
+
+func TestDemonDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		calls int
+		get   int
+	}{
+		{"default", 0, DefaultDemonDepth, DefaultDemonDepth},
+		{"set", 5, 5, 5},
+		{"one", 1, 1, 1},
+		{"negative", -1, DefaultDemonDepth, DefaultDemonDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			kb := lpkb(&calls)
+			kb.SetDemonDepth(tt.limit)
+			if got := kb.GetDemonDepth(); got != tt.get {
+				t.Fatalf("GetDemonDepth = %d, want %d", got, tt.get)
+			}
+			if err := kb.FputvE("a", "x", "v"); !errors.Is(err, ErrDepth) {
+				t.Fatalf("FputvE = %v, want ErrDepth", err)
+			}
+			if calls != tt.calls {
+				t.Fatalf("calls = %d, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestDemonDepthCaptured(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		calls int
+	}{
+		{"default", 0, DefaultDemonDepth},
+		{"set", 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			kb := NewKnowledgeBase()
+			kb.SetDemonDepth(tt.limit)
+			// a func(string) has no MethodContext, so it puts through kb
+			kb.Fcreatex("loop")
+			kb.Fputx("loop", func(fname string) {
+				calls++
+				kb.Fputv(fname, "x", "again")
+			})
+			kb.Fcreatef("a")
+			kb.Fcreates("a", "x")
+			kb.Fcreatev("a", "x")
+			kb.Faddd("a", "x", "ifputv", "loop", 0)
+			kb.Fputv("a", "x", "v")
+			if calls != tt.calls {
+				t.Fatalf("calls = %d, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestDemonDepthContext(t *testing.T) {
+	kb := NewKnowledgeBase()
+	var depths []int
+	kb.Fcreatex("inner")
+	kb.FputxmE("inner", func(c *MethodContext) (any, error) {
+		depths = append(depths, c.Depth)
+		return nil, nil
+	})
+	kb.Fcreatex("outer")
+	kb.FputxmE("outer", func(c *MethodContext) (any, error) {
+		depths = append(depths, c.Depth)
+		// a transaction begun by a method counts on from it
+		tx := c.KB.Begin()
+		defer tx.Rollback()
+		return tx.Fexecma("a", "y")
+	})
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "y")
+	kb.Fcreatem("a", "y")
+	kb.Fputm("a", "y", "inner")
+	kb.Fcreates("a", "x")
+	kb.Fcreatem("a", "x")
+	kb.Fputm("a", "x", "outer")
+	tests := []struct {
+		name  string
+		limit int
+		want  []int
+		err   error
+	}{
+		{"nested", 0, []int{1, 2}, nil},
+		{"limit", 1, []int{1}, ErrDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depths = nil
+			kb.SetDemonDepth(tt.limit)
+			defer kb.SetDemonDepth(0)
+			if _, err := kb.Fexecma("a", "x"); !errors.Is(err, tt.err) {
+				t.Fatalf("Fexecma = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(depths, tt.want) {
+				t.Fatalf("depths = %v, want %v", depths, tt.want)
+			}
+		})
+	}
+}
+
+func TestDemonDepthConcurrent(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.SetDemonDepth(2)
+	kb.Fcreatex("slow")
+	kb.FputxmE("slow", func(c *MethodContext) (any, error) { return nil, nil })
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "y")
+	kb.Fcreatev("a", "y")
+	kb.Faddd("a", "y", "ifputv", "slow", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := kb.FputvE("a", "y", "v"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// rkb - a knowledge base with references p.x to q.x to r.x to s.x
+func rkb() *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	for _, f := range []string{"p", "q", "r"} {
+		kb.Fcreatef(f)
+		kb.Fcreates(f, "x")
+		kb.Fcreater(f, "x")
+	}
+	kb.Fcreatef("s")
+	kb.Fcreates("s", "x")
+	kb.Fcreatev("s", "x")
+	kb.Fputv("s", "x", "end")
+	kb.Fputr("p", "x", "q")
+	kb.Fputr("q", "x", "r")
+	kb.Fputr("r", "x", "s")
+	return kb
+}
+
+func TestRefDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		value string
+		path  []string
+		err   error
+	}{
+		{"default", 0, "end", []string{"p", "q", "r", "s"}, nil},
+		{"enough", 3, "end", []string{"p", "q", "r", "s"}, nil},
+		{"short", 2, "", []string{"p", "q"}, ErrDepth},
+		{"one", 1, "", []string{"p"}, ErrDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := rkb()
+			kb.SetRefDepth(tt.limit)
+			value, err := kb.FgetvE("p", "x")
+			if value != tt.value || !errors.Is(err, tt.err) {
+				t.Fatalf("FgetvE = %q, %v, want %q, %v", value, err, tt.value, tt.err)
+			}
+			path, err := kb.FpathrE("p", "x")
+			if !reflect.DeepEqual(path, tt.path) || !errors.Is(err, tt.err) {
+				t.Fatalf("FpathrE = %v, %v, want %v, %v", path, err, tt.path, tt.err)
+			}
+			if got := kb.Fpathr("p", "x"); !reflect.DeepEqual(got, tt.path) {
+				t.Fatalf("Fpathr = %v, want %v", got, tt.path)
+			}
+		})
+	}
+}
+
+func TestRefCycle(t *testing.T) {
+	kb := rkb()
+	kb.Fputr("r", "x", "p")
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"fgetv", func() error { _, err := kb.FgetvE("p", "x"); return err }},
+		{"fexecma", func() error { _, err := kb.Fexecma("p", "x"); return err }},
+		{"fpathr", func() error { _, err := kb.FpathrE("p", "x"); return err }},
+		{"fcreatev", func() error { return kb.FcreatevE("p", "x") }},
+		{"fremovev", func() error { return kb.FremovevE("p", "x") }},
+		{"fcreatem", func() error { return kb.FcreatemE("p", "x") }},
+		{"fremovem", func() error { return kb.FremovemE("p", "x") }},
+		{"fputm", func() error { return kb.FputmE("p", "x", "m") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, ErrCycle) {
+				t.Fatalf("err = %v, want ErrCycle", err)
+			}
+		})
+	}
+	if kb.Fexistv("p", "x") || kb.Fexistm("p", "x") {
+		t.Fatal("a slot in a cycle exists")
+	}
+	if got := kb.Fpathr("p", "x"); !reflect.DeepEqual(got, []string{"p", "q", "r"}) {
+		t.Fatalf("Fpathr = %v", got)
+	}
+}
+
+func TestPathrErrors(t *testing.T) {
+	kb := rkb()
+	kb.Fputr("r", "x", "z")
+	tests := []struct {
+		name  string
+		fname string
+		path  []string
+		err   error
+	}{
+		{"no frame", "z", []string{}, ErrFrameNotFound},
+		{"no frame at the end", "p", []string{"p", "q", "r"}, ErrFrameNotFound},
+		{"no reference", "s", []string{"s"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := kb.FpathrE(tt.fname, "x")
+			if !reflect.DeepEqual(path, tt.path) || !errors.Is(err, tt.err) {
+				t.Fatalf("FpathrE = %v, %v, want %v, %v", path, err, tt.path, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/**********************************************************************
+ *
+ * file name:    memstorage.go
+ * description:  frames stored in memory
+ *
+ * MemStorage keeps stored frames in a map, which makes it a quick
+ * stand-in for a directory or archive in tests. Frames are copied on
+ * the way in and out, so a stored frame never changes with the frame
+ * in the knowledge base.
+ *
+ *							Functions
+ *
+ * NewMemStorage			create an empty memory storage
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// MemStorage - frames stored in memory
+type MemStorage struct {
+	mu     sync.RWMutex
+	frames map[string]Frame
+}
+
+// NewMemStorage - create an empty memory storage
+func NewMemStorage() *MemStorage {
+	return &MemStorage{frames: make(map[string]Frame)}
+}
+
+// Load - read a frame
+func (m *MemStorage) Load(fname string) (Frame, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if x, ok := m.frames[fname]; ok {
+		return clonef(x), nil
+	}
+	return nil, ErrFrameNotFound
+}
+
+// Store - write a frame
+func (m *MemStorage) Store(fname string, x Frame) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.frames[fname] = clonef(x)
+	return nil
+}
+
+// Delete - remove a stored frame
+func (m *MemStorage) Delete(fname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.frames[fname]; !ok {
+		return ErrFrameNotFound
+	}
+	delete(m.frames, fname)
+	return nil
+}
+
+// List - names of the stored frames, in sorted order
+func (m *MemStorage) List() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	frames := []string{}
+	for k := range m.frames {
+		frames = append(frames, k)
+	}
+	sort.Strings(frames)
+	return frames, nil
+}
+
+// LoadAll - read a list of frames, failing if any of them fails
+func (m *MemStorage) LoadAll(fnames []string) ([]Frame, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	frames := []Frame{}
+	for _, i := range fnames {
+		x, ok := m.frames[i]
+		if !ok {
+			return nil, ferror("load", i, "", ErrFrameNotFound)
+		}
+		frames = append(frames, clonef(x))
+	}
+	return frames, nil
+}
+
+// StoreAll - write a list of frames
+func (m *MemStorage) StoreAll(fnames []string, frames []Frame) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, fname := range fnames {
+		m.frames[fname] = clonef(frames[i])
+	}
+	return nil
+}
+
+// DeleteAll - remove a list of stored frames, as many as can be
+func (m *MemStorage) DeleteAll(fnames []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	errs := []error{}
+	for _, i := range fnames {
+		if _, ok := m.frames[i]; !ok {
+			errs = append(errs, ferror("delete", i, "", ErrFrameNotFound))
+		}
+		delete(m.frames, i)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+/**********************************************************************
+ *
+ * file name:    methods.go
+ * description:  methods taking arguments and returning results
+ *
+ * Every method and demon in fmethods is a Method. It is called with a
+ * MethodContext saying which knowledge base, frame and slot it was
+ * called for, what triggered it (fexecm, fexecd or the demon type) and
+ * any arguments, and returns a result and an error. Commands called
+ * through the knowledge base in the MethodContext count towards the
+ * limit on nested demons and methods. Methods written as
+ * func(string) still work: Fputx wraps them with Adapt.
+ *
+ *							Functions
+ *
+ * Adapt					make a Method from a func(string)
+ * Fexecda					directly execute a demon with arguments
+ * Fexecma					execute a method with arguments
+ * Fgetxm					get a Method from the function map
+ *
+ **********************************************************************/
+
+package framesets2
+
+// MethodContext - what a method or demon is told about its call
+type MethodContext struct {
+	KB    *KnowledgeBase
+	Frame string
+	Slot  string
+	Op    string
+	Args  []any
+	Old   []string // values before the event, for a before or after demon
+	New   []string // values after the event, for a before or after demon
+	Depth int      // demons and methods running, counting this one
+}
+
+// Method - a method or demon
+type Method func(c *MethodContext) (any, error)
+
+// adapt - make a Method from a method which only takes a frame name
+// a nil method gives a nil Method
+func Adapt(method func(string)) Method {
+	if method == nil {
+		return nil
+	}
+	return func(c *MethodContext) (any, error) {
+		method(c.Frame)
+		return nil, nil
+	}
+}
+
+// fgetxm - get a Method from fmethods
+func (kb *KnowledgeBase) Fgetxm(mname string) (Method, bool) {
+	method := kb.method(mname)
+	return method, method != nil
+}
+
+// fexecma - execute a method with arguments and return its result
+// requires that fframes[fname][sname,facets] exists
+// calls ifref and ifexecm demons
+func (kb *KnowledgeBase) Fexecma(fname, sname string, args ...any) (any, error) {
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil {
+		if s.has("method") {
+			if _, err = kb.before(s, "ifexecm", fname, sname, s["method"], s["method"], args...); err != nil {
+				return nil, ferror("fexecm", fname, sname, err)
+			}
+			if err = kb.fire(s, "ifexecm", fname, sname, args...); err == nil {
+				c := &MethodContext{KB: kb, Frame: fname, Slot: sname, Op: "fexecm", Args: args}
+				result, err := kb.call(kb.getval(fname, sname, "method"), c)
+				if err == nil {
+					err = kb.after(s, "ifexecm", fname, sname, s["method"], s["method"], args...)
+				}
+				return result, ferror("fexecm", fname, sname, err)
+			}
+		} else {
+			err = s.missing("method")
+		}
+	}
+	return nil, ferror("fexecm", fname, sname, err)
+}
+
+// fexecda - directly execute the demons of a type with arguments and
+// return the result of the last
+// requires that fframes[fname][sname,dname] exists
+func (kb *KnowledgeBase) Fexecda(fname, sname, dname string, args ...any) (any, error) {
+	s, err := kb.slote(fname, sname)
+	if err == nil {
+		if s.has(dname) {
+			var result any
+			for _, mname := range s.demons(dname) {
+				c := &MethodContext{KB: kb, Frame: fname, Slot: sname, Op: dname, Args: args}
+				if result, err = kb.invoke(mname, ErrDemonMissing, c); err != nil {
+					break
+				}
+			}
+			return result, ferror("fexecd", fname, sname, err)
+		} else {
+			err = ErrFacetNotFound
+		}
+	}
+	return nil, ferror("fexecd", fname, sname, err)
+}
@@ -0,0 +1,97 @@
+package framesets2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMethodArguments(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatem("a", "s")
+	var got *MethodContext
+	kb.Fcreatex("add")
+	kb.Fputxm("add", func(c *MethodContext) (any, error) {
+		got = c
+		return c.Args[0].(int) + c.Args[1].(int), nil
+	})
+	called := ""
+	kb.Fcreatex("old")
+	kb.Fputx("old", func(f string) { called = f })
+	kb.Fcreatex("veto")
+	kb.Fputxm("veto", func(*MethodContext) (any, error) { return "x", errors.New("no") })
+	tests := []struct {
+		name   string
+		mname  string
+		args   []any
+		result any
+		err    bool
+	}{
+		{"method with arguments", "add", []any{2, 3}, 5, false},
+		{"adapted method", "old", nil, nil, false},
+		{"failing method", "veto", nil, "x", true},
+	}
+	for _, tt := range tests {
+		kb.Fputm("a", "s", tt.mname)
+		result, err := kb.Fexecma("a", "s", tt.args...)
+		if result != tt.result || (err != nil) != tt.err {
+			t.Errorf("%s: got %v, %v", tt.name, result, err)
+		}
+	}
+	if got == nil || got.Frame != "a" || got.Slot != "s" || got.Op != "fexecm" {
+		t.Errorf("context: got %+v", got)
+	}
+	if called != "a" {
+		t.Errorf("adapted method called with %q", called)
+	}
+}
+
+func TestDirectDemons(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatem("a", "s")
+	kb.Fcreated("a", "s", "ifgetm")
+	kb.Fputd("a", "s", "ifgetm", "veto")
+	kb.Fcreatex("veto")
+	kb.Fputxm("veto", func(*MethodContext) (any, error) { return "x", errors.New("no") })
+	if _, err := kb.FgetmE("a", "s"); err == nil {
+		t.Error("fgetm: demon failing did not veto")
+	}
+	if result, err := kb.Fexecda("a", "s", "ifgetm", 1); result != "x" || err == nil {
+		t.Errorf("fexecda: got %v, %v", result, err)
+	}
+	kb.Fremovex("veto")
+	if _, err := kb.Fexecda("a", "s", "ifgetm"); !errors.Is(err, ErrDemonMissing) {
+		t.Errorf("fexecda missing: got %v, want %v", err, ErrDemonMissing)
+	}
+	if err := kb.FputxmE("nope", nil); !errors.Is(err, ErrMethodNotFound) {
+		t.Errorf("fputxm missing: got %v, want %v", err, ErrMethodNotFound)
+	}
+}
+
+func TestNilMethod(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatex("m")
+	kb.Fputxm("m", func(*MethodContext) (any, error) { return "kept", nil })
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"fputxm", func() error { return kb.FputxmE("m", nil) }},
+		{"fputx", func() error { return kb.FputxE("m", nil) }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, ErrNilMethod) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, ErrNilMethod)
+		}
+	}
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatem("a", "s")
+	kb.Fputm("a", "s", "m")
+	if result, err := kb.Fexecma("a", "s"); result != "kept" || err != nil {
+		t.Errorf("method after nil puts: got %v, %v", result, err)
+	}
+}
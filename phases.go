@@ -0,0 +1,147 @@
+/**********************************************************************
+ *
+ * file name:    phases.go
+ * description:  before and after demons
+ *
+ * Each demon type if<event> is called at a fixed point of its command,
+ * and can stop it only by failing. Next to it, every event has a
+ * before<event> and an after<event> demon type, such as beforeputv and
+ * afterputv or beforeremovef and afterremovef, held like any other
+ * demon of a slot or a frame.
+ *
+ * A before demon is called before anything is changed or returned. Its
+ * MethodContext holds the values of the slot in Old and, for a put, the
+ * values about to be put in New. Failing vetoes the event, so the
+ * command fails with the error and changes nothing. Returning values
+ * rewrites the event: a put puts them instead of New, a get returns them
+ * instead of the values of the slot, and ifref, whose Old and New hold
+ * the referenced frame, follows the reference to the frame returned.
+ * Returning nil lets the event go ahead as it is. A put whose values
+ * were changed by another command after its before demon saw them is
+ * made on the values at hand if the demon let it go ahead as it was,
+ * and fails with ErrConflict if the demon rewrote it.
+ *
+ * An after demon is called once the event has happened, with the values
+ * before it in Old and after it in New, and what it returns is ignored,
+ * so it suits auditing. Its failing is returned by the command, but the
+ * change stays. For facets created or removed, the side without the
+ * facet is nil; frame demons get no values. The if<event> demon is
+ * called between the two phases, as before.
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"slices"
+	"strings"
+)
+
+// fphase - name of the demon of an event in a phase, before or after
+func fphase(phase, dname string) string {
+	return phase + strings.TrimPrefix(dname, "if")
+}
+
+// fcopyl - copy of a list, nil if the list is nil
+func fcopyl(lista []string) []string {
+	if lista == nil {
+		return nil
+	}
+	return append([]string{}, lista...)
+}
+
+// before - call the before demons of an event on a slot
+// returns the values to go ahead with, new unless the demons rewrote them
+func (kb *KnowledgeBase) before(s slot, dname, fname, sname string, old, new []string, args ...any) ([]string, error) {
+	mnames := s.demons(fphase("before", dname))
+	if len(mnames) == 0 {
+		return new, nil
+	}
+	defer kb.group()()
+	for _, mname := range mnames {
+		c := &MethodContext{KB: kb, Frame: fname, Slot: sname, Op: fphase("before", dname), Args: args, Old: fcopyl(old), New: fcopyl(new)}
+		result, err := kb.invoke(mname, ErrDemonMissing, c)
+		if err != nil {
+			return new, err
+		}
+		if x := fvalues(result); x != nil {
+			new = x
+		}
+	}
+	return new, nil
+}
+
+// after - call the after demons of an event on a slot
+func (kb *KnowledgeBase) after(s slot, dname, fname, sname string, old, new []string, args ...any) error {
+	mnames := s.demons(fphase("after", dname))
+	if len(mnames) == 0 {
+		return nil
+	}
+	defer kb.group()()
+	for _, mname := range mnames {
+		c := &MethodContext{KB: kb, Frame: fname, Slot: sname, Op: fphase("after", dname), Args: args, Old: fcopyl(old), New: fcopyl(new)}
+		if _, err := kb.invoke(mname, ErrDemonMissing, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// phased - determine if a slot has a before demon for an event
+func (s slot) phased(dname string) bool {
+	return len(s.demons(fphase("before", dname))) > 0
+}
+
+// ref - follow the reference in a slot, calling the demons of ifref
+// returns the frame referenced, which a before demon may have changed
+func (kb *KnowledgeBase) ref(s slot, fname, sname string) (string, error) {
+	old := s["ref"]
+	x, err := kb.before(s, "ifref", fname, sname, old, old)
+	if err != nil {
+		return fname, err
+	}
+	if err = kb.fire(s, "ifref", fname, sname); err != nil {
+		return fname, err
+	}
+	if err = kb.after(s, "ifref", fname, sname, old, x); err != nil {
+		return fname, err
+	}
+	return Getval(x), nil
+}
+
+// got - call the demons of a get, once the values are read
+// returns the values to return, which a before demon may have changed
+func (kb *KnowledgeBase) got(s slot, dname, fname, sname string, value []string) ([]string, error) {
+	x, err := kb.before(s, dname, fname, sname, value, value)
+	if err != nil {
+		return []string{}, err
+	}
+	if err = kb.after(s, dname, fname, sname, value, x); err != nil {
+		return []string{}, err
+	}
+	return x, nil
+}
+
+// beforev - call the before demon of a put into a value facet
+// returns the function putting the values the demon agreed to; if the
+// values changed since the demon saw them, a put it let through is made
+// on the values at hand, and one it rewrote fails with ErrConflict
+func (kb *KnowledgeBase) beforev(s slot, fname, sname string, fn func([]string) ([]string, error)) (func([]string) ([]string, error), error) {
+	put, err := fn(append([]string{}, s["value"]...))
+	if err != nil {
+		return fn, err
+	}
+	value, err := kb.before(s, "ifputv", fname, sname, s["value"], put)
+	if err != nil {
+		return fn, err
+	}
+	return func(cur []string) ([]string, error) {
+		switch {
+		case slices.Equal(cur, s["value"]):
+			return append([]string{}, value...), nil
+		case slices.Equal(value, put):
+			return fn(cur)
+		}
+		return nil, ErrConflict
+	}, nil
+}
@@ -0,0 +1,259 @@
+package framesets2
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pkb - a knowledge base with methods rec, noting each demon it is
+// called for with its old and new values in log, upper, returning the
+// new value in upper case, toc, returning c, and veto, failing
+// frame a has a slot x without facets and frames b and c a value slot x
+// holding bv and cv
+func pkb(log *[]string) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatex("rec")
+	kb.Fputxm("rec", func(c *MethodContext) (any, error) {
+		*log = append(*log, c.Op+":"+strings.Join(c.Old, "|")+">"+strings.Join(c.New, "|"))
+		return nil, nil
+	})
+	kb.Fcreatex("upper")
+	kb.Fputxm("upper", func(c *MethodContext) (any, error) {
+		return strings.ToUpper(Getval(c.New)), nil
+	})
+	kb.Fcreatex("toc")
+	kb.Fputxm("toc", func(c *MethodContext) (any, error) {
+		return "c", nil
+	})
+	kb.Fcreatex("veto")
+	kb.Fputxm("veto", func(c *MethodContext) (any, error) {
+		return nil, errors.New("veto")
+	})
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "x")
+	for _, fname := range []string{"b", "c"} {
+		kb.Fcreatef(fname)
+		kb.Fcreates(fname, "x")
+		kb.Fcreatev(fname, "x")
+		kb.Fputv(fname, "x", fname+"v")
+	}
+	return kb
+}
+
+// fputds - put a demon of each type given into a slot
+func fputds(kb *KnowledgeBase, fname, sname, mname string, dnames ...string) {
+	for _, dname := range dnames {
+		kb.Fcreated(fname, sname, dname)
+		kb.Fputd(fname, sname, dname, mname)
+	}
+}
+
+func TestPhases(t *testing.T) {
+	tests := []struct {
+		name   string
+		dnames []string
+		change func(kb *KnowledgeBase)
+		want   []string
+	}{
+		{"create and put", []string{"beforecreatev", "aftercreatev", "afterputv", "ifputv"}, func(kb *KnowledgeBase) {
+			kb.Fcreatev("a", "x")
+			kb.Fputv("a", "x", "red")
+		}, []string{"beforecreatev:>", "aftercreatev:>", "ifputv:>", "afterputv:>red"}},
+		{"put again", []string{"afterputv"}, func(kb *KnowledgeBase) {
+			kb.Fcreatev("a", "x")
+			kb.Fputv("a", "x", "red")
+			kb.Fputv("a", "x", "blue")
+		}, []string{"afterputv:>red", "afterputv:red>blue"}},
+		{"remove", []string{"afterremovev"}, func(kb *KnowledgeBase) {
+			kb.Fcreatev("a", "x")
+			kb.Fputv("a", "x", "pink")
+			kb.Fremovev("a", "x")
+		}, []string{"afterremovev:pink>"}},
+	}
+	for _, tt := range tests {
+		log := []string{}
+		kb := pkb(&log)
+		fputds(kb, "a", "x", "rec", tt.dnames...)
+		tt.change(kb)
+		if !reflect.DeepEqual(log, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, log, tt.want)
+		}
+	}
+}
+
+func TestPhaseRewrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(kb *KnowledgeBase)
+		dname  string
+		mname  string
+		change func(kb *KnowledgeBase)
+		got    func(kb *KnowledgeBase) string
+		want   string
+	}{
+		{"beforeputv", func(kb *KnowledgeBase) { kb.Fcreatev("a", "x") }, "beforeputv", "upper",
+			func(kb *KnowledgeBase) { kb.Fputv("a", "x", "blue") },
+			func(kb *KnowledgeBase) string { return kb.slot("a", "x")["value"][0] }, "BLUE"},
+		{"beforegetv", func(kb *KnowledgeBase) { kb.Fcreatev("a", "x"); kb.Fputv("a", "x", "pink") }, "beforegetv", "upper",
+			func(kb *KnowledgeBase) {},
+			func(kb *KnowledgeBase) string { return kb.Fgetv("a", "x") + " " + kb.slot("a", "x")["value"][0] }, "PINK pink"},
+		{"beforeref", func(kb *KnowledgeBase) { kb.Fcreater("a", "x"); kb.Fputr("a", "x", "b") }, "beforeref", "toc",
+			func(kb *KnowledgeBase) {},
+			func(kb *KnowledgeBase) string { return kb.Fgetv("a", "x") }, "cv"},
+		{"beforeputr", func(kb *KnowledgeBase) { kb.Fcreater("a", "x") }, "beforeputr", "toc",
+			func(kb *KnowledgeBase) { kb.Fputr("a", "x", "b") },
+			func(kb *KnowledgeBase) string { return kb.Fgetr("a", "x") }, "c"},
+		{"beforeputm", func(kb *KnowledgeBase) { kb.Fcreatem("a", "x") }, "beforeputm", "upper",
+			func(kb *KnowledgeBase) { kb.Fputm("a", "x", "go") },
+			func(kb *KnowledgeBase) string { return kb.Fgetm("a", "x") }, "GO"},
+	}
+	for _, tt := range tests {
+		log := []string{}
+		kb := pkb(&log)
+		tt.setup(kb)
+		fputds(kb, "a", "x", tt.mname, tt.dname)
+		tt.change(kb)
+		if got := tt.got(kb); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPhaseVeto(t *testing.T) {
+	tests := []struct {
+		name   string
+		dname  string
+		frame  bool
+		change func(kb *KnowledgeBase) error
+		check  func(kb *KnowledgeBase) bool
+	}{
+		{"beforeputv", "beforeputv", false, func(kb *KnowledgeBase) error { return kb.FputvE("a", "x", "green") },
+			func(kb *KnowledgeBase) bool { return kb.Fgetv("a", "x") == "red" }},
+		{"beforegetv", "beforegetv", false, func(kb *KnowledgeBase) error { return errv(kb.FgetvE("a", "x")) },
+			func(kb *KnowledgeBase) bool { return true }},
+		{"beforeremovev", "beforeremovev", false, func(kb *KnowledgeBase) error { return kb.FremovevE("a", "x") },
+			func(kb *KnowledgeBase) bool { return kb.Fexistv("a", "x") }},
+		{"beforeremovef", "beforeremovef", true, func(kb *KnowledgeBase) error { return kb.FremovefE("a") },
+			func(kb *KnowledgeBase) bool { return kb.Fexistf("a") }},
+		{"beforeincludef", "beforeincludef", true, func(kb *KnowledgeBase) error {
+			kb.Fcreatefs("set")
+			kb.Fputdf("set", "beforeincludef", "veto")
+			return kb.FsincludefE("set", "b")
+		}, func(kb *KnowledgeBase) bool { return !Fmember(kb.Fslistf("set"), "b") }},
+	}
+	for _, tt := range tests {
+		log := []string{}
+		kb := pkb(&log)
+		kb.Fcreatev("a", "x")
+		kb.Fputv("a", "x", "red")
+		if tt.frame {
+			kb.Fputdf("a", tt.dname, "veto")
+		} else {
+			fputds(kb, "a", "x", "veto", tt.dname)
+		}
+		if err := tt.change(kb); err == nil {
+			t.Errorf("%s: not vetoed", tt.name)
+		}
+		if !tt.check(kb) {
+			t.Errorf("%s: changed", tt.name)
+		}
+	}
+}
+
+func TestFramePhases(t *testing.T) {
+	log := []string{}
+	kb := pkb(&log)
+	kb.Fcreatefs("set")
+	kb.Fputdf("set", "beforecreatef", "rec")
+	kb.Fputdf("set", "aftercreatef", "rec")
+	kb.Fputdf("set", "afterremovef", "rec")
+	kb.Fcreatef("z")
+	kb.Fsincludef("set", "z")
+	kb.Fsincludef("set", "a")
+	kb.Fputdf("a", "afterremovef", "rec")
+	tests := []struct {
+		name   string
+		change func()
+		want   []string
+	}{
+		{"remove and create a member", func() {
+			kb.Fremovef("z")
+			kb.Fcreatef("z")
+		}, []string{"afterremovef:>", "beforecreatef:>", "aftercreatef:>"}},
+		{"create another frame", func() { kb.Fcreatef("z2") }, nil},
+		{"remove", func() { kb.Fremovef("a") }, []string{"afterremovef:>", "afterremovef:>"}},
+	}
+	for _, tt := range tests {
+		log = nil
+		tt.change()
+		if !reflect.DeepEqual(log, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, log, tt.want)
+		}
+	}
+}
+
+func TestPhaseChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite bool
+		err     error
+		want    []string
+	}{
+		{"let through", false, nil, []string{"bv", "z", "y"}},
+		{"rewritten", true, ErrConflict, []string{"bv", "z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := []string{}
+			kb := pkb(&log)
+			// the demon changes the values once, after it saw them
+			once := false
+			kb.Fcreatex("bump")
+			kb.Fputxm("bump", func(c *MethodContext) (any, error) {
+				if once {
+					return nil, nil
+				}
+				once = true
+				if err := c.KB.FappendvE(c.Frame, c.Slot, "z"); err != nil {
+					return nil, err
+				}
+				if tt.rewrite {
+					return []string{"w"}, nil
+				}
+				return nil, nil
+			})
+			fputds(kb, "b", "x", "bump", "beforeputv")
+			if err := kb.FappendvE("b", "x", "y"); !errors.Is(err, tt.err) {
+				t.Fatalf("FappendvE = %v, want %v", err, tt.err)
+			}
+			if got := kb.Fgetvl("b", "x"); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("values = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhaseConcurrent(t *testing.T) {
+	log := []string{}
+	kb := pkb(&log)
+	kb.Fcreatex("pass")
+	kb.Fputxm("pass", func(c *MethodContext) (any, error) { return nil, nil })
+	fputds(kb, "b", "x", "pass", "beforeputv")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := kb.FappendvE("b", "x", "y"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := kb.Fgetvl("b", "x"); len(got) != 21 {
+		t.Fatalf("got %d values, want 21", len(got))
+	}
+}
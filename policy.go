@@ -0,0 +1,143 @@
+/**********************************************************************
+ *
+ * file name:    policy.go
+ * description:  failures of methods and demons
+ *
+ * Every method and demon is called through invoke, so a method which
+ * does not exist fails with ErrMethodNotFound, or ErrDemonMissing for a
+ * demon, and a method which panics fails with ErrPanic instead of taking
+ * the process down. What happens next is the policy of the knowledge
+ * base:
+ *
+ * PolicyAbort				the error stops the command, which returns it
+ * PolicyLog				the error is logged and the command goes on
+ * PolicyCollect			the error is kept and the command goes on
+ *
+ * PolicyAbort is the default. With the others the failing method is
+ * taken to have returned nil. The policy is only for a method missing
+ * or panicking: an error a method returns stops the command whatever
+ * the policy, so a before demon still vetoes, and so does ErrDepth.
+ * Errors are logged and kept as a *FrameError naming the demon type or
+ * fexecm, the frame and the slot. Kept errors stay until ClearErrors,
+ * and like the policy and logger are shared by the transactions and
+ * snapshots of a knowledge base.
+ *
+ *							Functions
+ *
+ * ClearErrors				drop the errors kept
+ * GetErrors				get the errors kept
+ * GetPolicy				get the policy for failures
+ * SetLogger				set the logger for failures
+ * SetPolicy				set the policy for failures
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Policy - what a knowledge base does when a method or demon is missing
+// or panics
+type Policy int
+
+const (
+	PolicyAbort Policy = iota
+	PolicyLog
+	PolicyCollect
+)
+
+// fpolicy - the policy of a knowledge base and the errors it kept
+type fpolicy struct {
+	pmu    sync.Mutex
+	policy Policy
+	logger *log.Logger
+	errs   []error
+}
+
+// SetPolicy - set the policy for methods and demons failing
+func (kb *KnowledgeBase) SetPolicy(p Policy) {
+	kb.pmu.Lock()
+	defer kb.pmu.Unlock()
+	kb.policy = p
+}
+
+// GetPolicy - get the policy for methods and demons failing
+func (kb *KnowledgeBase) GetPolicy() Policy {
+	kb.pmu.Lock()
+	defer kb.pmu.Unlock()
+	return kb.policy
+}
+
+// SetLogger - set the logger used by PolicyLog, log.Default() if nil
+func (kb *KnowledgeBase) SetLogger(l *log.Logger) {
+	kb.pmu.Lock()
+	defer kb.pmu.Unlock()
+	kb.logger = l
+}
+
+// GetErrors - get the errors kept by PolicyCollect, oldest first
+func (kb *KnowledgeBase) GetErrors() []error {
+	kb.pmu.Lock()
+	defer kb.pmu.Unlock()
+	return append([]error{}, kb.errs...)
+}
+
+// ClearErrors - drop the errors kept by PolicyCollect
+func (kb *KnowledgeBase) ClearErrors() {
+	kb.pmu.Lock()
+	defer kb.pmu.Unlock()
+	kb.errs = nil
+}
+
+// handle - apply the policy to a method or demon missing or panicking
+// returns the error if the command is to stop, nil if it goes on
+func (kb *KnowledgeBase) handle(c *MethodContext, err error) error {
+	kb.pmu.Lock()
+	defer kb.pmu.Unlock()
+	switch kb.policy {
+	case PolicyLog:
+		logger := kb.logger
+		if logger == nil {
+			logger = log.Default()
+		}
+		logger.Print(ferror(c.Op, c.Frame, c.Slot, err))
+		return nil
+	case PolicyCollect:
+		kb.errs = append(kb.errs, ferror(c.Op, c.Frame, c.Slot, err))
+		return nil
+	}
+	return err
+}
+
+// invoke - call a method or demon by name
+// missing is the error for a method which does not exist
+func (kb *KnowledgeBase) invoke(mname string, missing error, c *MethodContext) (any, error) {
+	method := kb.method(mname)
+	if method == nil {
+		return nil, kb.handle(c, fmt.Errorf("%w: %q", missing, mname))
+	}
+	return kb.run(mname, method, c)
+}
+
+// run - call a method or demon, unless too many are running, turning a
+// panic into an error
+// the method is handed a knowledge base as deep as it is
+func (kb *KnowledgeBase) run(mname string, method Method, c *MethodContext) (result any, err error) {
+	depth, done := kb.enter()
+	defer done()
+	c.Depth = depth
+	if err = kb.fdeeper(c); err != nil {
+		return nil, err
+	}
+	c.KB = &KnowledgeBase{fkb: kb.fkb, depth: c.Depth}
+	defer func() {
+		if x := recover(); x != nil {
+			result, err = nil, kb.handle(c, fmt.Errorf("%w: %q: %v", ErrPanic, mname, x))
+		}
+	}()
+	return method(c)
+}
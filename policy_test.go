@@ -0,0 +1,158 @@
+package framesets2
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+// errNo - the error returned by the method fail
+var errNo = errors.New("no")
+
+// fpkb - a knowledge base with slot a.x, its demon dname calling mname,
+// and the methods boom, which panics, fail, which returns an error, and
+// loop, which puts into its own slot
+func fpkb(dname, mname string) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatex("boom")
+	kb.FputxmE("boom", func(c *MethodContext) (any, error) { panic("bad") })
+	kb.Fcreatex("fail")
+	kb.FputxmE("fail", func(c *MethodContext) (any, error) { return nil, errNo })
+	kb.Fcreatex("loop")
+	kb.FputxmE("loop", func(c *MethodContext) (any, error) {
+		return nil, c.KB.FputvE(c.Frame, c.Slot, "again")
+	})
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "x")
+	kb.Fcreatev("a", "x")
+	kb.Faddd("a", "x", dname, mname, 0)
+	return kb
+}
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		dname  string
+		mname  string
+		err    error // nil if the command goes on
+		value  string
+		kept   error // the error kept by PolicyCollect
+	}{
+		{"abort panic", PolicyAbort, "ifputv", "boom", ErrPanic, "", nil},
+		{"abort missing", PolicyAbort, "ifputv", "ghost", ErrDemonMissing, "", nil},
+		{"abort fail", PolicyAbort, "ifputv", "fail", errNo, "", nil},
+		{"log panic", PolicyLog, "ifputv", "boom", nil, "v", nil},
+		{"log missing", PolicyLog, "ifputv", "ghost", nil, "v", nil},
+		{"log fail", PolicyLog, "ifputv", "fail", errNo, "", nil},
+		{"log veto", PolicyLog, "beforeputv", "fail", errNo, "", nil},
+		{"log depth", PolicyLog, "ifputv", "loop", ErrDepth, "", nil},
+		{"collect panic", PolicyCollect, "ifputv", "boom", nil, "v", ErrPanic},
+		{"collect missing", PolicyCollect, "ifputv", "ghost", nil, "v", ErrDemonMissing},
+		{"collect before missing", PolicyCollect, "beforeputv", "ghost", nil, "v", ErrDemonMissing},
+		{"collect fail", PolicyCollect, "ifputv", "fail", errNo, "", nil},
+		{"collect veto", PolicyCollect, "beforeputv", "fail", errNo, "", nil},
+		{"collect depth", PolicyCollect, "ifputv", "loop", ErrDepth, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := fpkb(tt.dname, tt.mname)
+			var b bytes.Buffer
+			kb.SetLogger(log.New(&b, "", 0))
+			kb.SetPolicy(tt.policy)
+			if got := kb.GetPolicy(); got != tt.policy {
+				t.Fatalf("GetPolicy = %v, want %v", got, tt.policy)
+			}
+			err := kb.FputvE("a", "x", "v")
+			if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("FputvE = %v, want %v", err, tt.err)
+			}
+			if got := kb.Fgetv("a", "x"); got != tt.value {
+				t.Fatalf("Fgetv = %q, want %q", got, tt.value)
+			}
+			logged := tt.policy == PolicyLog && tt.err == nil
+			if logged != (b.Len() > 0) {
+				t.Fatalf("logged %q", b.String())
+			}
+			errs := kb.GetErrors()
+			if tt.kept == nil {
+				if len(errs) != 0 {
+					t.Fatalf("GetErrors = %v, want none", errs)
+				}
+				return
+			}
+			var fe *FrameError
+			if len(errs) != 1 || !errors.Is(errs[0], tt.kept) || !errors.As(errs[0], &fe) {
+				t.Fatalf("GetErrors = %v, want %v", errs, tt.kept)
+			}
+			if fe.Op != tt.dname || fe.Frame != "a" || fe.Slot != "x" {
+				t.Fatalf("kept %+v", fe)
+			}
+			kb.ClearErrors()
+			if errs := kb.GetErrors(); len(errs) != 0 {
+				t.Fatalf("GetErrors after ClearErrors = %v", errs)
+			}
+		})
+	}
+}
+
+func TestPolicyMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		mname  string
+		err    error
+	}{
+		{"abort panic", PolicyAbort, "boom", ErrPanic},
+		{"abort missing", PolicyAbort, "none", ErrMethodNotFound},
+		{"abort fail", PolicyAbort, "fail", errNo},
+		{"collect panic", PolicyCollect, "boom", nil},
+		{"collect missing", PolicyCollect, "none", nil},
+		{"collect fail", PolicyCollect, "fail", errNo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := fpkb("ifputv", "boom")
+			kb.Fcreates("a", "m")
+			kb.Fcreatem("a", "m")
+			kb.Fputm("a", "m", tt.mname)
+			kb.SetPolicy(tt.policy)
+			_, err := kb.Fexecma("a", "m")
+			if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("Fexecma = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPolicyLog(t *testing.T) {
+	kb := fpkb("ifputv", "boom")
+	var b bytes.Buffer
+	kb.SetLogger(log.New(&b, "", 0))
+	kb.SetPolicy(PolicyLog)
+	if err := kb.FputvE("a", "x", "v"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ifputv a.x", "bad"} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("log %q does not contain %q", b.String(), want)
+		}
+	}
+}
+
+func TestPolicyShared(t *testing.T) {
+	kb := fpkb("ifputv", "boom")
+	kb.SetPolicy(PolicyCollect)
+	tx := kb.Begin()
+	if err := tx.FputvE("a", "x", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if errs := kb.GetErrors(); len(errs) != 1 || !errors.Is(errs[0], ErrPanic) {
+		t.Fatalf("GetErrors = %v", errs)
+	}
+}
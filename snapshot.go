@@ -0,0 +1,189 @@
+/**********************************************************************
+ *
+ * file name:    snapshot.go
+ * description:  snapshots and forks of a knowledge base
+ *
+ * Snapshot returns a knowledge base holding every frame as it was when
+ * the snapshot was taken, however the knowledge base changes after
+ * that. Taking a snapshot copies nothing. Instead, the first time a
+ * frame is changed, created or removed after the snapshot, the frame
+ * as it was is kept for the snapshot, so a frame is copied at most once
+ * for each snapshot, and only if it changes. A snapshot can be read
+ * with every command, but anything changing it fails with ErrReadOnly.
+ *
+ * Fork returns a transaction begun on a new snapshot, so it starts out
+ * as the knowledge base was, can be changed without being seen or
+ * disturbed by anyone, and is merged back into the knowledge base with
+ * Commit, or thrown away with Rollback. Merging fails with ErrConflict
+ * if a frame the fork changed was changed in the knowledge base since
+ * the fork was taken.
+ *
+ * Each snapshot adds a little to every change of the knowledge base,
+ * and keeps copies of the frames changed since it was taken, so a
+ * snapshot should be released when no longer needed. A fork releases
+ * its snapshot when committed or rolled back.
+ *
+ *							Functions
+ *
+ * Fork						fork a knowledge base
+ * Release					release a snapshot
+ * Snapshot					take a snapshot of a knowledge base
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"slices"
+	"sync"
+)
+
+// fsnap - a snapshot of a knowledge base
+// kept holds every frame changed since the snapshot was taken, as it
+// was then, with a nil frame for one which did not exist
+type fsnap struct {
+	mu       sync.Mutex
+	live     *KnowledgeBase
+	kept     map[string]Frame
+	released bool
+}
+
+// Snapshot - take a snapshot of a knowledge base
+func (kb *KnowledgeBase) Snapshot() *KnowledgeBase {
+	s := &fsnap{live: kb, kept: make(map[string]Frame)}
+	kb.mu.Lock()
+	kb.snaps = append(kb.snaps, s)
+	kb.mu.Unlock()
+	return &KnowledgeBase{fkb: &fkb{
+		fframes: make(map[string]*fentry),
+		fxtable: kb.fxtable,
+		snap:    s,
+	}, depth: kb.depth}
+}
+
+// Fork - begin a transaction on a snapshot of a knowledge base, which
+// Commit merges back into it
+func (kb *KnowledgeBase) Fork() *KnowledgeBase {
+	fork := kb.Snapshot().Begin()
+	fork.tx.fork = true
+	return fork
+}
+
+// Release - release a snapshot
+// the snapshot must not be used after it is released
+func (kb *KnowledgeBase) Release() {
+	s := kb.snap
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.released {
+		s.mu.Unlock()
+		return
+	}
+	s.released = true
+	s.kept = nil
+	s.mu.Unlock()
+	live := s.live
+	live.mu.Lock()
+	live.snaps = slices.DeleteFunc(live.snaps, func(i *fsnap) bool {
+		return i == s
+	})
+	live.mu.Unlock()
+}
+
+// fkeep - keep a frame for every snapshot which has not kept it yet
+// x is called for the frame as it is, nil if it does not exist
+// requires that kb.mu is held and the frame cannot change
+func fkeep(snaps []*fsnap, fname string, x func() Frame) {
+	for _, s := range snaps {
+		s.mu.Lock()
+		if _, ok := s.kept[fname]; !ok && !s.released {
+			s.kept[fname] = x()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// thaw - copy a frame of a snapshot into it
+// returns nil if the frame did not exist when the snapshot was taken
+func (kb *KnowledgeBase) thaw(fname string) *fentry {
+	x := kb.snap.frame(fname)
+	if x == nil {
+		return nil
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if e := kb.fframes[fname]; e != nil {
+		return e
+	}
+	e := &fentry{frame: x}
+	kb.fframes[fname] = e
+	return e
+}
+
+// frame - copy of a frame as it was when a snapshot was taken
+func (s *fsnap) frame(fname string) Frame {
+	live := s.live
+	if live.tx != nil || live.snap != nil {
+		live.entry(fname)
+	}
+	// the entry is locked without holding mu, which may wait for the
+	// frame to be handed on; one replaced or removed meanwhile was kept
+	// for the snapshot first, so it is looked up again
+	live.mu.RLock()
+	e := live.fframes[fname]
+	live.mu.RUnlock()
+	for e != nil {
+		e.mu.RLock()
+		if !e.gone {
+			defer e.mu.RUnlock()
+			break
+		}
+		e.mu.RUnlock()
+		live.mu.RLock()
+		e = live.fframes[fname]
+		live.mu.RUnlock()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return nil
+	}
+	if x, ok := s.kept[fname]; ok {
+		if x == nil {
+			return nil
+		}
+		return clonef(x)
+	}
+	if e == nil {
+		return nil
+	}
+	return clonef(e.frame)
+}
+
+// lists - names of the frames of a snapshot
+// frames already copied into the snapshot are left out
+func (kb *KnowledgeBase) lists() []string {
+	s := kb.snap
+	live := map[string]bool{}
+	for _, i := range s.live.Flistf() {
+		live[i] = true
+	}
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	frames := []string{}
+	for k := range live {
+		if x, ok := s.kept[k]; (!ok || x != nil) && kb.fframes[k] == nil {
+			frames = append(frames, k)
+		}
+	}
+	for k, x := range s.kept {
+		if x != nil && kb.fframes[k] == nil && !live[k] {
+			frames = append(frames, k)
+		}
+	}
+	return frames
+}
@@ -0,0 +1,228 @@
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(kb *KnowledgeBase)
+	}{
+		{"fputv", func(kb *KnowledgeBase) { kb.Fputv("a", "s", "2") }},
+		{"fremoves", func(kb *KnowledgeBase) { kb.Fremoves("a", "s") }},
+		{"fcreatef", func(kb *KnowledgeBase) { kb.Fcreatef("n") }},
+		{"fremovef", func(kb *KnowledgeBase) { kb.Fremovef("r") }},
+		{"fcopyf", func(kb *KnowledgeBase) { kb.Fcopyf("a", "r") }},
+		{"transaction", func(kb *KnowledgeBase) {
+			tx := kb.Begin()
+			tx.Fcreatef("t")
+			tx.Fremovef("a")
+			tx.Commit()
+		}},
+	}
+	for _, tt := range tests {
+		kb := fvkb("1")
+		kb.Fcreatef("r")
+		want := kbdump(kb)
+		sn := kb.Snapshot()
+		tt.change(kb)
+		if got := kbdump(sn); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, want)
+		}
+		if got := sn.Flistf(); !slices.Equal(sorted(got), []string{"a", "r"}) {
+			t.Errorf("%s: frames %q", tt.name, got)
+		}
+		if got := kbdump(sn.Snapshot()); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: snapshot of snapshot: got %v", tt.name, got)
+		}
+		sn.Release()
+		sn.Release()
+		if len(kb.snaps) != 0 {
+			t.Errorf("%s: %d snapshots kept", tt.name, len(kb.snaps))
+		}
+	}
+}
+
+// sorted - a sorted copy of a list of names
+func sorted(names []string) []string {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	return names
+}
+
+func TestSnapshotReadOnly(t *testing.T) {
+	sn := fvkb("1").Snapshot()
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"fputv", func() error { return sn.FputvE("a", "s", "x") }},
+		{"fcreatef", func() error { return sn.FcreatefE("z") }},
+		{"fremovef", func() error { return sn.FremovefE("a") }},
+		{"fcreates", func() error { return sn.FcreatesE("a", "t") }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, ErrReadOnly)
+		}
+	}
+	if got := sn.Fgetv("a", "s"); got != "1" || sn.Fexistf("z") {
+		t.Errorf("snapshot changed: got %q", got)
+	}
+}
+
+func TestFork(t *testing.T) {
+	tests := []struct {
+		name    string
+		outside func(kb *KnowledgeBase)
+		err     error
+		value   string
+	}{
+		{"merged", func(kb *KnowledgeBase) { kb.Fcreatef("live") }, nil, "fork"},
+		{"conflict", func(kb *KnowledgeBase) { kb.Fputv("a", "s", "live") }, ErrConflict, "live"},
+	}
+	for _, tt := range tests {
+		kb := fvkb("1")
+		kb.Fcreatef("n")
+		f := kb.Fork()
+		f.Fputv("a", "s", "fork")
+		f.Fcreatef("ff")
+		f.Fremovef("n")
+		tt.outside(kb)
+		if kb.Fgetv("a", "s") == "fork" || f.Fexistf("live") {
+			t.Errorf("%s: fork not apart", tt.name)
+		}
+		err := f.Commit()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+		if err != nil {
+			if f.Fgetv("a", "s") != "fork" {
+				t.Errorf("%s: fork lost its changes", tt.name)
+			}
+			f.Rollback()
+		}
+		if got := kb.Fgetv("a", "s"); got != tt.value {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.value)
+		}
+		if kb.Fexistf("ff") != (err == nil) {
+			t.Errorf("%s: created frame", tt.name)
+		}
+		if len(kb.snaps) != 0 {
+			t.Errorf("%s: %d snapshots kept", tt.name, len(kb.snaps))
+		}
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	kb := NewKnowledgeBase()
+	for i := 0; i < 20; i++ {
+		fname := fmt.Sprint("f", i)
+		kb.Fcreatef(fname)
+		kb.Fcreates(fname, "s")
+		kb.Fcreatev(fname, "s")
+		kb.Fputv(fname, "s", "0")
+	}
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for r := 1; ; r++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// each transaction puts the same value in every frame, so
+			// a snapshot must see the same value in all of them
+			tx := kb.Begin()
+			for i := 0; i < 20; i++ {
+				tx.Fputv(fmt.Sprint("f", i), "s", fmt.Sprint(r))
+			}
+			tx.Commit()
+		}
+	}()
+	for k := 0; k < 200; k++ {
+		sn := kb.Snapshot()
+		v := sn.Fgetv("f0", "s")
+		for i := 1; i < 20; i++ {
+			if x := sn.Fgetv(fmt.Sprint("f", i), "s"); x != v {
+				t.Errorf("snapshot torn: f%d is %s, f0 is %s", i, x, v)
+			}
+		}
+		sn.Release()
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// bchanger - a storage whose Changer waits to be released
+type bchanger struct {
+	*MemStorage
+	applying chan bool
+	release  chan bool
+}
+
+func (c bchanger) Apply([]Change) error {
+	c.applying <- true
+	<-c.release
+	return nil
+}
+
+func TestSnapshotWhileApplying(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(kb *KnowledgeBase)
+		seen   func(sn *KnowledgeBase) bool
+	}{
+		{"fputv", func(kb *KnowledgeBase) { kb.Fputv("a", "s", "2") }, nil},
+		{"fcreatef", func(kb *KnowledgeBase) { kb.Fcreatef("c") },
+			func(sn *KnowledgeBase) bool { return sn.Fexistf("c") }},
+		{"fremovef", func(kb *KnowledgeBase) { kb.Fremovef("a") },
+			func(sn *KnowledgeBase) bool { return !sn.Fexistf("a") }},
+		{"transaction", func(kb *KnowledgeBase) {
+			tx := kb.Begin()
+			tx.Fputv("a", "s", "2")
+			tx.Commit()
+		}, func(sn *KnowledgeBase) bool { return sn.Fgetv("a", "s") == "2" }},
+	}
+	for _, tt := range tests {
+		kb := fvkb("1")
+		kb.Fcreatef("b")
+		kb.OpenJournal(t.TempDir(), 0)
+		kb.GetJournal().SetSync(false)
+		c := bchanger{NewMemStorage(), make(chan bool), make(chan bool)}
+		kb.SetStorage(c)
+		go tt.change(kb)
+		<-c.applying
+		// nothing but the frame being changed waits for the Changer
+		done := make(chan *KnowledgeBase)
+		go func() {
+			sn := kb.Snapshot()
+			sn.Fexistf("b")
+			kb.Fgetv("b", "s")
+			kb.Flistf()
+			kb.Begin().Fexistf("b")
+			done <- sn
+		}()
+		var sn *KnowledgeBase
+		select {
+		case sn = <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s: knowledge base locked while applying", tt.name)
+		}
+		close(c.release)
+		if tt.seen != nil && tt.seen(sn) {
+			t.Errorf("%s: snapshot sees a change made after it", tt.name)
+		}
+		kb.CloseJournal()
+	}
+}
@@ -0,0 +1,88 @@
+/**********************************************************************
+ *
+ * file name:    storage.go
+ * description:  where frames are stored
+ *
+ * Fstoref, Floadf, Fstorefs, Floadfs and the other storage commands
+ * keep frames in the Storage of the knowledge base. A knowledge base
+ * starts out with a DirStorage in the working directory; SetStorage
+ * replaces it, so two knowledge bases can keep their frames apart or
+ * keep them somewhere else entirely. The package has three kinds:
+ *
+ * DirStorage				a directory holding one file per frame
+ * ArchiveStorage			a single file holding every frame
+ * MemStorage				frames kept in memory, for tests
+ *
+ * A missing frame is reported as ErrFrameNotFound and any other
+ * failure of the underlying files as ErrIO.
+ *
+ *							Functions
+ *
+ * Fstored					get a list of stored frames
+ * Funstoref				remove a stored frame
+ * GetStorage				get the storage of a knowledge base
+ * SetStorage				set the storage of a knowledge base
+ *
+ **********************************************************************/
+
+package framesets2
+
+// Storage - a place to keep frames outside of a knowledge base
+type Storage interface {
+	// Load - read a frame
+	Load(fname string) (Frame, error)
+	// Store - write a frame, replacing any stored frame of the same name
+	Store(fname string, x Frame) error
+	// Delete - remove a stored frame
+	Delete(fname string) error
+	// List - names of the stored frames, in sorted order
+	List() ([]string, error)
+	// LoadAll - read a list of frames, failing if any of them fails
+	LoadAll(fnames []string) ([]Frame, error)
+	// StoreAll - write a list of frames, either all of them or none
+	StoreAll(fnames []string, frames []Frame) error
+	// DeleteAll - remove a list of stored frames, as many as can be
+	DeleteAll(fnames []string) error
+}
+
+// SetStorage - set the storage of a knowledge base
+func (kb *KnowledgeBase) SetStorage(s Storage) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	kb.storage = s
+}
+
+// GetStorage - get the storage of a knowledge base
+// a transaction or a snapshot uses the storage of the knowledge base it
+// was taken of, unless set
+func (kb *KnowledgeBase) GetStorage() Storage {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	switch {
+	case kb.storage != nil:
+		return kb.storage
+	case kb.tx != nil:
+		return kb.tx.parent.GetStorage()
+	case kb.snap != nil:
+		return kb.snap.live.GetStorage()
+	}
+	return NewDirStorage(".")
+}
+
+// fstored - get a list of stored frames
+func (kb *KnowledgeBase) Fstored() ([]string, error) {
+	frames, err := kb.GetStorage().List()
+	return frames, ferror("fstored", "", "", err)
+}
+
+// funstoref - remove a stored frame
+// the frame in memory, if any, is left alone
+// requires that fframes[fname] is stored
+func (kb *KnowledgeBase) Funstoref(fname string) bool {
+	return kb.FunstorefE(fname) == nil
+}
+
+// funstorefe - remove a stored frame, returning an error
+func (kb *KnowledgeBase) FunstorefE(fname string) error {
+	return ferror("funstoref", fname, "", kb.GetStorage().Delete(fname))
+}
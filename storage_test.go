@@ -0,0 +1,66 @@
+package framesets2
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// fstorage - store a frameset in store, load it back from the storage
+// returned by reopen and delete it
+func fstorage(t *testing.T, store Storage, reopen func() Storage) {
+	t.Helper()
+	kb := NewKnowledgeBase()
+	kb.SetStorage(store)
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatev("a", "s")
+	kb.Fputvl("a", "s", []string{"x,y", "z"})
+	kb.Fcreatef("b")
+	kb.Fcreatefs("set")
+	kb.Fsincludef("set", "a")
+	kb.Fsincludef("set", "b")
+	if err := kb.FstorefsE("set"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := kb.Fstored(); !slices.Equal(got, []string{"a", "b", "set"}) {
+		t.Errorf("fstored: got %q", got)
+	}
+	if reopen != nil {
+		store = reopen()
+	}
+	kb2 := NewKnowledgeBase()
+	kb2.SetStorage(store)
+	if err := kb2.FloadfsE("set"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(kb.copyf("a"), kb2.copyf("a")) {
+		t.Errorf("got %v, want %v", kb2.copyf("a"), kb.copyf("a"))
+	}
+	if err := store.Delete("zz"); !errors.Is(err, ErrFrameNotFound) {
+		t.Errorf("delete: got %v, want %v", err, ErrFrameNotFound)
+	}
+	if err := store.DeleteAll([]string{"a", "zz"}); !errors.Is(err, ErrFrameNotFound) {
+		t.Errorf("deleteall: got %v, want %v", err, ErrFrameNotFound)
+	}
+	kb3 := NewKnowledgeBase()
+	kb3.SetStorage(store)
+	if err := kb3.FloadfsE("set"); !errors.Is(err, ErrFrameNotFound) {
+		t.Errorf("floadfs: got %v, want %v", err, ErrFrameNotFound)
+	}
+	if kb3.Fexistf("set") || kb3.Fexistf("b") {
+		t.Error("part of the set was loaded")
+	}
+}
+
+func TestStorageBackends(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "a.fs")
+	t.Run("mem", func(t *testing.T) { fstorage(t, NewMemStorage(), nil) })
+	t.Run("dir", func(t *testing.T) { fstorage(t, NewDirStorage(filepath.Join(dir, "d")), nil) })
+	t.Run("archive", func(t *testing.T) {
+		fstorage(t, NewArchiveStorage(archive), func() Storage { return NewArchiveStorage(archive) })
+	})
+}
@@ -0,0 +1,380 @@
+/**********************************************************************
+ *
+ * file name:    tx.go
+ * description:  transactions over the frames of a knowledge base
+ *
+ * Begin starts a transaction, which is a knowledge base of its own.
+ * Every frame command works on it as on any knowledge base, and demons
+ * fired by its commands are handed the transaction, so whatever they
+ * change is part of it as well. The first time a transaction uses a
+ * frame, the frame is copied into it, and from then on the transaction
+ * only sees its own copy. Nothing it does is seen outside of it until
+ * Commit, which puts every frame it changed, created or removed into
+ * the knowledge base at once, or fails and puts none of them.
+ *
+ * Commit fails with ErrConflict if a frame the transaction changed was
+ * changed in the knowledge base since the transaction copied it, unless
+ * it was changed back. Frames which were only read, or changed and then
+ * changed back, are not checked, so write skew is possible: two
+ * transactions, each reading a frame the other changes, both commit. A
+ * transaction depending on a frame it only reads should change the
+ * frame too, for instance by putting a counter in it. A failed Commit
+ * leaves the transaction open as it was, so its frames can still be
+ * read, and it can be rolled back. Rollback throws the changes of a
+ * transaction away. Once committed or rolled back the transaction is
+ * finished, and Commit or Rollback on it again fails with
+ * ErrNoTransaction.
+ *
+ * A committed transaction is handed to the Changer and the Journal of
+ * the knowledge base as one change, so after a crash either all of it
+ * or none of it is replayed. Methods are shared with the knowledge base
+ * and are not part of a transaction, and neither is storing frames, so
+ * Fstoref in a transaction stores its copy of the frame at once.
+ *
+ * A transaction can be begun on a transaction, in which case Commit
+ * puts its changes into the outer transaction.
+ *
+ *							Functions
+ *
+ * Begin					begin a transaction
+ * Commit					make the changes of a transaction visible
+ * Rollback					throw the changes of a transaction away
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"sort"
+)
+
+// ftx - the state of a transaction
+// base holds every frame the transaction has used, as it was in parent
+// when copied, with a nil frame for one which did not exist
+type ftx struct {
+	parent *KnowledgeBase
+	base   map[string]fbase
+	done   bool
+	fork   bool
+}
+
+// fbase - a frame of the parent of a transaction, as it was copied
+type fbase struct {
+	e     *fentry
+	ver   uint64
+	frame Frame
+}
+
+// fwrite - a frame changed by a transaction, nil if absent
+type fwrite struct {
+	fname string
+	old   Frame
+	new   Frame
+}
+
+// Begin - begin a transaction
+func (kb *KnowledgeBase) Begin() *KnowledgeBase {
+	return &KnowledgeBase{fkb: &fkb{
+		fframes: make(map[string]*fentry),
+		fxtable: kb.fxtable,
+		tx:      &ftx{parent: kb, base: make(map[string]fbase)},
+	}, depth: kb.depth}
+}
+
+// fault - copy a frame from the parent of a transaction into it
+// returns nil if the frame does not exist in the transaction
+func (kb *KnowledgeBase) fault(fname string) *fentry {
+	kb.mu.RLock()
+	_, seen := kb.tx.base[fname]
+	e := kb.fframes[fname]
+	kb.mu.RUnlock()
+	if seen {
+		return e
+	}
+	var x Frame
+	var ver uint64
+	parent := kb.tx.parent
+	pe := parent.entry(fname)
+	for pe != nil && !pe.read(func(f Frame) {
+		x, ver = clonef(f), pe.ver
+	}) {
+		pe = parent.entry(fname)
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if e := kb.fframes[fname]; e != nil {
+		return e
+	}
+	if _, seen := kb.tx.base[fname]; seen {
+		return nil
+	}
+	kb.tx.base[fname] = fbase{e: pe, ver: ver, frame: x}
+	if x == nil {
+		return nil
+	}
+	e = &fentry{frame: clonef(x)}
+	kb.fframes[fname] = e
+	return e
+}
+
+// flistt - frames of the parent of a transaction or the knowledge base
+// of a snapshot which it has not used
+func (kb *KnowledgeBase) flistt() []string {
+	frames := []string{}
+	if kb.snap != nil {
+		return kb.lists()
+	}
+	if kb.tx == nil {
+		return frames
+	}
+	listx := kb.tx.parent.Flistf()
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	for _, i := range listx {
+		if _, seen := kb.tx.base[i]; !seen {
+			frames = append(frames, i)
+		}
+	}
+	return frames
+}
+
+// finish - end a transaction, returning the frames it used
+func (kb *KnowledgeBase) finish() (map[string]fbase, map[string]*fentry, error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if kb.tx == nil || kb.tx.done {
+		return nil, nil, ErrNoTransaction
+	}
+	base, frames := kb.tx.base, kb.fframes
+	kb.tx.base, kb.fframes = make(map[string]fbase), make(map[string]*fentry)
+	kb.tx.done = true
+	return base, frames, nil
+}
+
+// Rollback - throw the changes of a transaction away
+func (kb *KnowledgeBase) Rollback() error {
+	_, frames, err := kb.finish()
+	if err != nil {
+		return ferror("rollback", "", "", err)
+	}
+	if kb.tx.fork {
+		kb.tx.parent.Release()
+	}
+	for _, e := range frames {
+		e.drop()
+	}
+	return nil
+}
+
+// Commit - make the changes of a transaction visible in the knowledge
+// base it was begun on, all of them or none
+// if it fails, with ErrConflict or otherwise, the transaction stays open
+// as it was, to be rolled back or its frames read
+func (kb *KnowledgeBase) Commit() error {
+	// mu is held so no command changes the transaction while it commits
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if kb.tx == nil || kb.tx.done {
+		return ferror("commit", "", "", ErrNoTransaction)
+	}
+	writes := []fwrite{}
+	for fname, b := range kb.tx.base {
+		var x Frame
+		if e := kb.fframes[fname]; e != nil {
+			e.mu.Lock()
+			x = e.frame
+			e.mu.Unlock()
+		}
+		if x == nil && b.frame == nil {
+			continue
+		}
+		if x != nil && b.frame != nil && len(fdiff(fname, b.frame, x)) == 0 {
+			continue
+		}
+		writes = append(writes, fwrite{fname: fname, old: b.frame, new: x})
+	}
+	sort.Slice(writes, func(i, j int) bool {
+		return writes[i].fname < writes[j].fname
+	})
+	parent := kb.tx.parent
+	if kb.tx.fork {
+		parent = parent.snap.live
+	}
+	if err := parent.commit(kb.tx.base, writes); err != nil {
+		return ferror("commit", "", "", err)
+	}
+	// the frames now belong to the knowledge base
+	for _, e := range kb.fframes {
+		e.drop()
+	}
+	kb.tx.base, kb.fframes = make(map[string]fbase), make(map[string]*fentry)
+	kb.tx.done = true
+	if kb.tx.fork {
+		kb.tx.parent.Release()
+	}
+	return nil
+}
+
+// commit - put the frames changed by a transaction into fframes
+// a frame conflicts unless it is the one the transaction copied and has
+// not been written since, or is the same as it
+// only the frames written are checked, so two transactions each reading
+// a frame the other writes both commit: this is write skew, and a
+// transaction relying on a frame it only read must write it as well
+func (kb *KnowledgeBase) commit(base map[string]fbase, writes []fwrite) error {
+	if len(writes) == 0 {
+		return nil
+	}
+	if kb.tx != nil {
+		// copy the frames into an outer transaction first
+		for _, w := range writes {
+			kb.entry(w.fname)
+		}
+	}
+	changer, journal := kb.sinks()
+	if changer != nil || journal != nil {
+		kb.smu.Lock()
+		defer kb.checkpointed(journal)
+		defer kb.smu.Unlock()
+		// no other command changes the frames while smu is held, so they
+		// are checked and handed on before mu is taken to put them in
+		kb.mu.RLock()
+		locked, err := kb.lockw(base, writes)
+		funlock(locked)
+		kb.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if err := fcommitted(changer, journal, writes); err != nil {
+			return err
+		}
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	locked, err := kb.lockw(base, writes)
+	defer funlock(locked)
+	if err != nil {
+		return err
+	}
+	if h := kb.history; h != nil {
+		h.begin()
+		for _, w := range writes {
+			r := hrecord{fname: w.fname, whole: true, old: w.old}
+			if w.new != nil {
+				r.new = clonef(w.new)
+			}
+			h.record(r)
+		}
+		h.end()
+	}
+	for _, w := range writes {
+		e := kb.fframes[w.fname]
+		fkeep(kb.snaps, w.fname, func() Frame {
+			if e == nil {
+				return nil
+			}
+			return clonef(e.frame)
+		})
+		switch {
+		case w.new == nil:
+			delete(kb.fframes, w.fname)
+			e.gone = true
+		case e == nil:
+			kb.fframes[w.fname] = &fentry{frame: w.new}
+		default:
+			for k := range e.frame {
+				delete(e.frame, k)
+			}
+			for k, v := range w.new {
+				e.frame[k] = v
+			}
+			e.ver++
+		}
+	}
+	return nil
+}
+
+// lockw - lock the frames changed by a transaction, returning ErrConflict
+// if one was changed since the transaction copied it
+// requires that mu is held
+func (kb *KnowledgeBase) lockw(base map[string]fbase, writes []fwrite) ([]*fentry, error) {
+	locked := []*fentry{}
+	for _, w := range writes {
+		b, e := base[w.fname], kb.fframes[w.fname]
+		if e == nil {
+			if b.frame != nil {
+				return locked, ferror("commit", w.fname, "", ErrConflict)
+			}
+			continue
+		}
+		e.mu.Lock()
+		locked = append(locked, e)
+		if e == b.e && e.ver == b.ver {
+			continue
+		}
+		if b.frame == nil || len(fdiff(w.fname, b.frame, e.frame)) > 0 {
+			return locked, ferror("commit", w.fname, "", ErrConflict)
+		}
+	}
+	return locked, nil
+}
+
+// funlock - unlock the frames locked by lockw
+func funlock(locked []*fentry) {
+	for _, e := range locked {
+		e.mu.Unlock()
+	}
+}
+
+// fcommitted - hand the frames changed by a transaction to a Changer and
+// a Journal as one change
+// as in fchanged, the journal is told if the Changer fails
+func fcommitted(changer Changer, journal *Journal, writes []fwrite) error {
+	changes := []Change{}
+	for _, w := range writes {
+		if w.old != nil && w.new != nil {
+			changes = append(changes, fdiff(w.fname, w.old, w.new)...)
+		} else {
+			changes = append(changes, fwhole(w.fname, w.old, w.new)...)
+		}
+	}
+	if journal != nil {
+		if err := journal.write(jwrites(writes)); err != nil {
+			return err
+		}
+	}
+	if changer != nil && len(changes) > 0 {
+		if err := changer.Apply(changes); err != nil {
+			if journal != nil {
+				journal.write(jwrites(fundo(writes)))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// jwrites - journal records of the frames changed by a transaction
+func jwrites(writes []fwrite) []string {
+	lines := []string{}
+	for _, w := range writes {
+		switch {
+		case w.new == nil:
+			lines = append(lines, fencodel("r", []string{w.fname}))
+		case w.old == nil:
+			lines = append(lines, jframe(w.fname, w.new)...)
+		default:
+			lines = append(lines, jchanges(fdiff(w.fname, w.old, w.new))...)
+		}
+	}
+	return lines
+}
+
+// fundo - the writes undoing a list of writes
+func fundo(writes []fwrite) []fwrite {
+	undo := make([]fwrite, len(writes))
+	for i, w := range writes {
+		undo[len(writes)-1-i] = fwrite{fname: w.fname, old: w.new, new: w.old}
+	}
+	return undo
+}
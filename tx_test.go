@@ -0,0 +1,248 @@
+package framesets2
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// fvkb - a knowledge base holding a frame a with a value s
+func fvkb(value string) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatev("a", "s")
+	kb.Fputv("a", "s", value)
+	return kb
+}
+
+func TestTransactionCommit(t *testing.T) {
+	kb := fvkb("1")
+	kb.Fcreates("a", "log")
+	kb.Fcreatev("a", "log")
+	kb.Fcreatex("d")
+	kb.Fputxm("d", func(c *MethodContext) (any, error) {
+		return nil, c.KB.FputvE("a", "log", "saw "+c.KB.Fgetv("a", "s"))
+	})
+	kb.Fcreated("a", "s", "ifputv")
+	kb.Fputd("a", "s", "ifputv", "d")
+	tx := kb.Begin()
+	if err := tx.FputvE("a", "s", "2"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Fcreatef("b")
+	tx.Fcreatefs("set")
+	tx.Fsincludef("set", "b")
+	tx.Fscreates("set", "z")
+	// the transaction is isolated until it commits, demons run inside it
+	if got := kb.Fgetv("a", "s"); got != "1" {
+		t.Errorf("value outside: got %q, want 1", got)
+	}
+	if kb.Fexistf("b") {
+		t.Error("frame created inside exists outside")
+	}
+	if got := kb.Fgetv("a", "log"); got != "" {
+		t.Errorf("demon outside: got %q", got)
+	}
+	if got := tx.Fgetv("a", "log"); got != "saw 1" {
+		t.Errorf("demon inside: got %q, want saw 1", got)
+	}
+	if got := tx.Flistf(); len(got) != 3 {
+		t.Errorf("frames inside: got %q", got)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if kb.Fgetv("a", "s") != "2" || !kb.Fexists("b", "z") || kb.Fgetv("a", "log") != "saw 1" {
+		t.Error("commit: changes missing")
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("commit again: got %v, want %v", err, ErrNoTransaction)
+	}
+	if err := kb.Commit(); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("commit without transaction: got %v, want %v", err, ErrNoTransaction)
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		inside  func(tx *KnowledgeBase)
+		outside func(kb *KnowledgeBase)
+		err     error
+	}{
+		{"value changed", func(tx *KnowledgeBase) { tx.Fputv("a", "s", "tx") },
+			func(kb *KnowledgeBase) { kb.Fputv("a", "s", "kb") }, ErrConflict},
+		{"frame removed", func(tx *KnowledgeBase) { tx.Fputv("a", "s", "tx") },
+			func(kb *KnowledgeBase) { kb.Fremovef("a") }, ErrConflict},
+		{"frame created again", func(tx *KnowledgeBase) { tx.Fremovef("a") },
+			func(kb *KnowledgeBase) { kb.Fremovef("a"); kb.Fcreatef("a") }, ErrConflict},
+		{"changed back", func(tx *KnowledgeBase) { tx.Fputv("a", "s", "tx") },
+			func(kb *KnowledgeBase) { kb.Fputv("a", "s", "kb"); kb.Fputv("a", "s", "1") }, nil},
+		{"write skew", func(tx *KnowledgeBase) { tx.Fgetv("a", "s"); tx.Fcreatef("b") },
+			func(kb *KnowledgeBase) { kb.Fputv("a", "s", "kb") }, nil},
+	}
+	for _, tt := range tests {
+		kb := fvkb("1")
+		tx := kb.Begin()
+		tt.inside(tx)
+		want := kbdump(tx)
+		tt.outside(kb)
+		err := tx.Commit()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+		if err == nil {
+			continue
+		}
+		// a transaction which failed to commit stays open as it was
+		if got := kbdump(tx); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: transaction changed to %v", tt.name, got)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Errorf("%s: rollback: %v", tt.name, err)
+		}
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	kb := fvkb("1")
+	kb.Fcreatef("b")
+	tx := kb.Begin()
+	tx.Fremovef("b")
+	tx.Fputv("a", "s", "3")
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if !kb.Fexistf("b") || kb.Fgetv("a", "s") != "1" {
+		t.Error("rollback: changes made")
+	}
+	if err := tx.Rollback(); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("rollback again: got %v, want %v", err, ErrNoTransaction)
+	}
+}
+
+func TestTransactionNested(t *testing.T) {
+	kb := NewKnowledgeBase()
+	tx := kb.Begin()
+	in := tx.Begin()
+	in.Fcreatef("n")
+	if err := in.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if kb.Fexistf("n") || !tx.Fexistf("n") {
+		t.Error("inner commit: not in outer transaction only")
+	}
+	tx.Commit()
+	if !kb.Fexistf("n") {
+		t.Error("outer commit: frame missing")
+	}
+}
+
+func TestTransactionJournal(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "j")
+	kb := NewKnowledgeBase()
+	kb.OpenJournal(dir, 0)
+	kb.GetJournal().SetSync(false)
+	kv := openkv(t, filepath.Join(t.TempDir(), "kv"))
+	kv.SetSync(false)
+	kb.SetStorage(kv)
+	kb.Fcreatef("a")
+	kb.Fcreates("a", "s")
+	kb.Fcreatev("a", "s")
+	kb.Fstoref("a")
+	kb.Fcreatef("r")
+	kb.Fstoref("r")
+	tx := kb.Begin()
+	tx.Fputv("a", "s", "v")
+	tx.Fcreatef("c")
+	tx.Fremovef("r")
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := kv.Load("a"); Getval(x["s,value"]) != "v" {
+		t.Errorf("changer: got %v", x)
+	}
+	if stored, _ := kv.List(); !slices.Equal(stored, []string{"a"}) {
+		t.Errorf("changer: stored %q", stored)
+	}
+	kb2 := NewKnowledgeBase()
+	kb2.OpenJournal(dir, 0)
+	if got, want := kbdump(kb2), kbdump(kb); !reflect.DeepEqual(got, want) {
+		t.Errorf("replay: got %v, want %v", got, want)
+	}
+}
+
+func TestTransactionConcurrent(t *testing.T) {
+	kb := fvkb("0")
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				for {
+					tx := kb.Begin()
+					var n int
+					fmt.Sscan(tx.Fgetv("a", "s"), &n)
+					tx.Fputv("a", "s", fmt.Sprint(n+1))
+					if tx.Commit() == nil {
+						break
+					}
+					tx.Rollback()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := kb.Fgetv("a", "s"); got != "400" {
+		t.Errorf("got %s, want 400", got)
+	}
+}
+
+func TestInverseWhole(t *testing.T) {
+	old := Frame{"a,slots": {"s"}, "s,facets": {"value"}, "s,value": {"1"}}
+	tests := []struct {
+		name string
+		old  Frame
+		new  Frame
+	}{
+		{"replaced", old, Frame{"a,slots": {"t"}, "t,facets": {}}},
+		{"removed", old, nil},
+		{"created", nil, old},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		kb := NewKnowledgeBase()
+		kb.OpenJournal(dir, 0)
+		if tt.old != nil {
+			kb.Fcreatef("a")
+			kb.Fcreates("a", "s")
+			kb.Fcreatev("a", "s")
+			kb.Fputv("a", "s", "1")
+		}
+		kb.Fcreatef("b")
+		want := kbdump(kb)
+		changes := fwhole("a", tt.old, tt.new)
+		// a change of another frame is undone apart from the frame
+		changes = append(changes, Change{Frame: "b", Key: "b,slots", Old: []string{}, New: []string{"x"}})
+		j := kb.GetJournal()
+		j.changes(changes)
+		kb2 := NewKnowledgeBase()
+		kb2.OpenJournal(dir, 0)
+		if got := kb2.copyf("a"); !reflect.DeepEqual(got, tt.new) {
+			t.Errorf("%s: changes: got %v, want %v", tt.name, got, tt.new)
+		}
+		kb2.CloseJournal()
+		j.changes(finverse(changes))
+		kb3 := NewKnowledgeBase()
+		kb3.OpenJournal(dir, 0)
+		if got := kbdump(kb3); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: inverse: got %v, want %v", tt.name, got, want)
+		}
+	}
+}
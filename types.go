@@ -0,0 +1,452 @@
+/**********************************************************************
+ *
+ * file name:    types.go
+ * description:  typed values
+ *
+ * A slot may declare the type of its values in a type facet,
+ * fframes[<fname>][<sname>,type]. Every put into the value facet of
+ * such a slot is checked against the type and stored in a canonical
+ * form, so a value reads back exactly as it was put, also after
+ * Fstoref and Floadf. A slot without a type facet takes any value,
+ * and its type can be inferred from the value it holds.
+ *
+ *							Types
+ *
+ * string					any value
+ * int						64 bit integer
+ * float					64 bit floating point number
+ * bool						true or false
+ * time						time in RFC 3339 format
+ * ref						name of an existing frame
+ * list						any values, read as a list with Fgetvl
+ *
+ *							Functions
+ *
+ * Fgettype					get the type of a slot
+ * Fgetvb					get a value facet as a bool
+ * Fgetvf					get a value facet as a float
+ * Fgetvi					get a value facet as an int
+ * Fgetvr					get a value facet as a frame name
+ * Fgetvt					get a value facet as a time
+ * Finfert					infer the type of a value
+ * Fputvb					put a bool in a value facet
+ * Fputvf					put a float in a value facet
+ * Fputvi					put an int in a value facet
+ * Fputvr					put a frame name in a value facet
+ * Fputvt					put a time in a value facet
+ * Fputtype					declare the type of a slot
+ * Fremovetype				remove the type of a slot
+ * Ftypev					get the declared or inferred type of a value facet
+ *
+ **********************************************************************/
+
+package framesets2
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeTime   = "time"
+	TypeRef    = "ref"
+	TypeList   = "list"
+)
+
+// ftypes - list of known types
+var ftypes = []string{TypeString, TypeInt, TypeFloat, TypeBool, TypeTime, TypeRef, TypeList}
+
+// fnormt - check a value against a type and return its canonical form
+func fnormt(vtype, value string) (string, error) {
+	switch vtype {
+	case "", TypeString, TypeList:
+		return value, nil
+	case TypeInt:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10), nil
+		}
+	case TypeFloat:
+		if x, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(x, 'g', -1, 64), nil
+		}
+	case TypeBool:
+		if value == "true" || value == "false" {
+			return value, nil
+		}
+	case TypeTime:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t.Format(time.RFC3339Nano), nil
+		}
+	case TypeRef:
+		if value != "" {
+			return value, nil
+		}
+	default:
+		return "", fmt.Errorf("%w: unknown type %q", ErrType, vtype)
+	}
+	return "", fmt.Errorf("%w: %q is not %s", ErrType, value, vtype)
+}
+
+// fnormv - check a list of values against a type
+func fnormv(vtype string, value []string) ([]string, error) {
+	if vtype == "" {
+		return value, nil
+	}
+	listx := make([]string, len(value))
+	for i, v := range value {
+		x, err := fnormt(vtype, v)
+		if err != nil {
+			return nil, err
+		}
+		listx[i] = x
+	}
+	return listx, nil
+}
+
+// fequalt - determine if two values of a type are equal
+// values without a type, or which do not match it, are compared as strings
+func fequalt(vtype, a, b string) bool {
+	x, errx := fnormt(vtype, a)
+	y, erry := fnormt(vtype, b)
+	if errx != nil || erry != nil {
+		return a == b
+	}
+	switch vtype {
+	case TypeFloat:
+		fx, _ := strconv.ParseFloat(x, 64)
+		fy, _ := strconv.ParseFloat(y, 64)
+		return fx == fy
+	case TypeTime:
+		tx, _ := time.Parse(time.RFC3339Nano, x)
+		ty, _ := time.Parse(time.RFC3339Nano, y)
+		return tx.Equal(ty)
+	}
+	return x == y
+}
+
+// fcheckt - check the values of every typed slot in a frame
+// used on frames read from disk
+func fcheckt(f Frame, fname string) error {
+	for _, i := range f[fname+",slots"] {
+		if vtype := Getval(f[i+",type"]); vtype != "" {
+			if _, err := fnormv(vtype, f[i+",value"]); err != nil {
+				return ferror("floadf", fname, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkr - check that the frames named by a put into a ref typed slot exist
+// s is a copy of the slot and fn the change made by the put
+func (kb *KnowledgeBase) checkr(s slot, fn func([]string) ([]string, error)) error {
+	if Getval(s["type"]) != TypeRef {
+		return nil
+	}
+	value, err := fn(append([]string{}, s["value"]...))
+	if err != nil {
+		return err
+	}
+	for _, i := range value {
+		if !kb.Fexistf(i) {
+			return fmt.Errorf("%w: no frame %q", ErrType, i)
+		}
+	}
+	return nil
+}
+
+// finfert - infer the type of a value
+func Finfert(value string) string {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return TypeInt
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return TypeFloat
+	}
+	if value == "true" || value == "false" {
+		return TypeBool
+	}
+	if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return TypeTime
+	}
+	return TypeString
+}
+
+// fputtype - declare the type of a slot
+// values and defaults already in the slot must match the type
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,type],
+//          fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+func (kb *KnowledgeBase) Fputtype(fname, sname, vtype string) bool {
+	return kb.FputtypeE(fname, sname, vtype) == nil
+}
+
+// fputtypee - declare the type of a slot, returning an error
+func (kb *KnowledgeBase) FputtypeE(fname, sname, vtype string) error {
+	if !Fmember(ftypes, vtype) {
+		return ferror("fputtype", fname, sname, fmt.Errorf("%w: unknown type %q", ErrType, vtype))
+	}
+	fname, s, err := kb.follow(fname, sname)
+	if err == nil && vtype == TypeRef {
+		err = kb.checkr(s, func(value []string) ([]string, error) {
+			return value, nil
+		})
+	}
+	if err == nil {
+		err = kb.writef(fname, func(f Frame) error {
+			value, err := fnormv(vtype, f[sname+",value"])
+			if err != nil {
+				return err
+			}
+			x, err := fnormv(vtype, f[sname+",default"])
+			if err != nil {
+				return err
+			}
+			if !Fmember(f[sname+",facets"], "type") {
+				if err := fcreatet(f, fname, sname, "type"); err != nil {
+					return err
+				}
+			}
+			fputt(f, sname, "type", vtype)
+			if Fmember(f[sname+",facets"], "value") {
+				f[sname+",value"] = value
+			}
+			if Fmember(f[sname+",facets"], "default") {
+				f[sname+",default"] = x
+			}
+			return nil
+		})
+	}
+	return ferror("fputtype", fname, sname, err)
+}
+
+// fgettype - get the declared type of a slot, "" if it has none
+// requires that fframes[fname][sname,facets] exists
+func (kb *KnowledgeBase) Fgettype(fname, sname string) string {
+	_, s, err := kb.follow(fname, sname)
+	if err != nil {
+		return ""
+	}
+	return Getval(s["type"])
+}
+
+// fremovetype - remove the type of a slot
+// requires that fframes[fname][sname,type] exists
+// modifies fframes[fname][sname,facets], fframes[fname][sname,type]
+//          where fname is the original or referenced frame
+func (kb *KnowledgeBase) Fremovetype(fname, sname string) bool {
+	return kb.FremovetypeE(fname, sname) == nil
+}
+
+// fremovetypee - remove the type of a slot, returning an error
+func (kb *KnowledgeBase) FremovetypeE(fname, sname string) error {
+	fname, _, err := kb.follow(fname, sname)
+	if err == nil {
+		err = kb.writef(fname, func(f Frame) error {
+			return fremovet(f, sname, "type")
+		})
+	}
+	return ferror("fremovetype", fname, sname, err)
+}
+
+// ftypev - get the type of a value facet, declared or else inferred
+// requires that fframes[fname][sname,value] exists
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Ftypev(fname, sname string) string {
+	if vtype := kb.Fgettype(fname, sname); vtype != "" {
+		return vtype
+	}
+	value, err := kb.getvl("ftypev", fname, sname)
+	if err != nil {
+		return ""
+	}
+	if len(value) > 1 {
+		return TypeList
+	}
+	return Finfert(Getval(value))
+}
+
+// getvt - get a value facet and check it against a type
+func (kb *KnowledgeBase) getvt(op, fname, sname, vtype string) (string, error) {
+	value, err := kb.getvl(op, fname, sname)
+	if err != nil {
+		return "", err
+	}
+	x, err := fnormt(vtype, Getval(value))
+	return x, ferror(op, fname, sname, err)
+}
+
+// fgetvi - get a value facet as an int
+// requires that fframes[fname][sname,value] holds an int
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fgetvi(fname, sname string) int64 {
+	i, _ := kb.FgetviE(fname, sname)
+	return i
+}
+
+// fgetvie - get a value facet as an int, returning an error
+func (kb *KnowledgeBase) FgetviE(fname, sname string) (int64, error) {
+	x, err := kb.getvt("fgetvi", fname, sname, TypeInt)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(x, 10, 64)
+}
+
+// fgetvf - get a value facet as a float
+// requires that fframes[fname][sname,value] holds a float
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fgetvf(fname, sname string) float64 {
+	x, _ := kb.FgetvfE(fname, sname)
+	return x
+}
+
+// fgetvfe - get a value facet as a float, returning an error
+func (kb *KnowledgeBase) FgetvfE(fname, sname string) (float64, error) {
+	x, err := kb.getvt("fgetvf", fname, sname, TypeFloat)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(x, 64)
+}
+
+// fgetvb - get a value facet as a bool
+// requires that fframes[fname][sname,value] holds a bool
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fgetvb(fname, sname string) bool {
+	b, _ := kb.FgetvbE(fname, sname)
+	return b
+}
+
+// fgetvbe - get a value facet as a bool, returning an error
+func (kb *KnowledgeBase) FgetvbE(fname, sname string) (bool, error) {
+	x, err := kb.getvt("fgetvb", fname, sname, TypeBool)
+	if err != nil {
+		return false, err
+	}
+	return x == "true", nil
+}
+
+// fgetvt - get a value facet as a time
+// requires that fframes[fname][sname,value] holds a time
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fgetvt(fname, sname string) time.Time {
+	t, _ := kb.FgetvtE(fname, sname)
+	return t
+}
+
+// fgetvte - get a value facet as a time, returning an error
+func (kb *KnowledgeBase) FgetvtE(fname, sname string) (time.Time, error) {
+	x, err := kb.getvt("fgetvt", fname, sname, TypeTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, x)
+}
+
+// fgetvr - get a value facet as the name of an existing frame
+// requires that fframes[fname][sname,value] holds the name of a frame
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fgetvr(fname, sname string) string {
+	x, _ := kb.FgetvrE(fname, sname)
+	return x
+}
+
+// fgetvre - get a value facet as the name of an existing frame,
+// returning an error
+func (kb *KnowledgeBase) FgetvrE(fname, sname string) (string, error) {
+	x, err := kb.getvt("fgetvr", fname, sname, TypeRef)
+	if err == nil && !kb.Fexistf(x) {
+		err = ferror("fgetvr", fname, sname, fmt.Errorf("%w: no frame %q", ErrType, x))
+	}
+	return x, err
+}
+
+// putvt - put a value of a type in a value facet
+func (kb *KnowledgeBase) putvt(op, fname, sname, value string) error {
+	return kb.putv(op, fname, sname, func(vector []string) ([]string, error) {
+		Putval(&vector, value)
+		return vector, nil
+	})
+}
+
+// fputvi - put an int in a value facet
+// requires that fframes[fname][sname,value] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fputvi(fname, sname string, i int64) bool {
+	return kb.FputviE(fname, sname, i) == nil
+}
+
+// fputvie - put an int in a value facet, returning an error
+func (kb *KnowledgeBase) FputviE(fname, sname string, i int64) error {
+	return kb.putvt("fputvi", fname, sname, strconv.FormatInt(i, 10))
+}
+
+// fputvf - put a float in a value facet
+// requires that fframes[fname][sname,value] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fputvf(fname, sname string, x float64) bool {
+	return kb.FputvfE(fname, sname, x) == nil
+}
+
+// fputvfe - put a float in a value facet, returning an error
+func (kb *KnowledgeBase) FputvfE(fname, sname string, x float64) error {
+	return kb.putvt("fputvf", fname, sname, strconv.FormatFloat(x, 'g', -1, 64))
+}
+
+// fputvb - put a bool in a value facet
+// requires that fframes[fname][sname,value] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fputvb(fname, sname string, b bool) bool {
+	return kb.FputvbE(fname, sname, b) == nil
+}
+
+// fputvbe - put a bool in a value facet, returning an error
+func (kb *KnowledgeBase) FputvbE(fname, sname string, b bool) error {
+	return kb.putvt("fputvb", fname, sname, strconv.FormatBool(b))
+}
+
+// fputvt - put a time in a value facet
+// requires that fframes[fname][sname,value] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fputvt(fname, sname string, t time.Time) bool {
+	return kb.FputvtE(fname, sname, t) == nil
+}
+
+// fputvte - put a time in a value facet, returning an error
+func (kb *KnowledgeBase) FputvtE(fname, sname string, t time.Time) error {
+	return kb.putvt("fputvt", fname, sname, t.Format(time.RFC3339Nano))
+}
+
+// fputvr - put the name of an existing frame in a value facet
+// requires that fframes[fname][sname,value] exists and fframes[fname2]
+//          exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fputvr(fname, sname, fname2 string) bool {
+	return kb.FputvrE(fname, sname, fname2) == nil
+}
+
+// fputvre - put the name of an existing frame in a value facet,
+// returning an error
+func (kb *KnowledgeBase) FputvrE(fname, sname, fname2 string) error {
+	if !kb.Fexistf(fname2) {
+		return ferror("fputvr", fname, sname, fmt.Errorf("%w: no frame %q", ErrType, fname2))
+	}
+	return kb.putvt("fputvr", fname, sname, fname2)
+}
@@ -0,0 +1,131 @@
+package framesets2
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// tkb - a knowledge base with a frame p holding a value slot of each type
+func tkb(t *testing.T) *KnowledgeBase {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("p")
+	for _, i := range []struct{ sname, vtype string }{
+		{"age", TypeInt}, {"w", TypeFloat}, {"ok", TypeBool}, {"t", TypeTime}, {"r", TypeRef},
+	} {
+		kb.Fcreates("p", i.sname)
+		kb.Fcreatev("p", i.sname)
+		if err := kb.FputtypeE("p", i.sname, i.vtype); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return kb
+}
+
+func TestTypedValues(t *testing.T) {
+	kb := tkb(t)
+	tests := []struct {
+		name string
+		put  func() error
+		err  error
+	}{
+		{"not an int", func() error { return kb.FputvE("p", "age", "banana") }, ErrType},
+		{"not a bool", func() error { return kb.FputvE("p", "ok", "1") }, ErrType},
+		{"bool in upper case", func() error { return kb.FputvE("p", "ok", "T") }, ErrType},
+		{"no such frame", func() error { return kb.FputvE("p", "r", "nobody") }, ErrType},
+		{"no such frame typed", func() error { return kb.FputvrE("p", "r", "nobody") }, ErrType},
+		{"int", func() error { return kb.FputvE("p", "age", "007") }, nil},
+		{"float", func() error { return kb.FputvfE("p", "w", 0.1+0.2) }, nil},
+		{"bool", func() error { return kb.FputvbE("p", "ok", true) }, nil},
+		{"frame", func() error { return kb.FputvrE("p", "r", "p") }, nil},
+	}
+	for _, tt := range tests {
+		if err := tt.put(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if got := kb.Fgetv("p", "age"); got != "7" {
+		t.Errorf("canonical int: got %q, want 7", got)
+	}
+	if _, err := kb.FgetviE("p", "w"); !errors.Is(err, ErrType) {
+		t.Errorf("float as int: got %v, want %v", err, ErrType)
+	}
+	if !kb.Fputvi("p", "age", 8) || kb.Fgetvi("p", "age") != 8 {
+		t.Errorf("fgetvi: got %d, want 8", kb.Fgetvi("p", "age"))
+	}
+	if !kb.Fgetvb("p", "ok") {
+		t.Error("fgetvb: got false")
+	}
+	if got := kb.Fgetvr("p", "r"); got != "p" {
+		t.Errorf("fgetvr: got %q, want p", got)
+	}
+	if got := kb.Ftypev("p", "age"); got != TypeInt {
+		t.Errorf("ftypev: got %q", got)
+	}
+}
+
+func TestTypedExact(t *testing.T) {
+	kb := tkb(t)
+	x, now := 0.1+0.2, time.Now()
+	kb.Fputvf("p", "w", x)
+	kb.Fputvt("p", "t", now)
+	// the values are kept as strings, which must give them back exactly
+	kb2 := NewKnowledgeBase()
+	kb2.Fcreatef("p")
+	for _, sname := range []string{"w", "t"} {
+		kb2.Fcreates("p", sname)
+		kb2.Fcreatev("p", sname)
+		kb2.Fputv("p", sname, kb.Fgetv("p", sname))
+	}
+	kb2.Fputtype("p", "w", TypeFloat)
+	kb2.Fputtype("p", "t", TypeTime)
+	if got := kb2.Fgetvf("p", "w"); got != x {
+		t.Errorf("float: got %v, want %v", got, x)
+	}
+	if got := kb2.Fgetvt("p", "t"); !got.Equal(now) {
+		t.Errorf("time: got %v, want %v", got, now)
+	}
+}
+
+func TestFindTyped(t *testing.T) {
+	kb := NewKnowledgeBase()
+	for _, i := range []struct{ fname, vtype, value string }{
+		{"a", TypeInt, "7"},
+		{"b", "", "1.50"},
+		{"c", "", "7"},
+		{"d", "", "2026-10-18T10:00:00+02:00"},
+		{"e", "", "red"},
+	} {
+		kb.Fcreatef(i.fname)
+		kb.Fcreates(i.fname, "s")
+		kb.Fcreatev(i.fname, "s")
+		if i.vtype != "" {
+			kb.Fputtype(i.fname, "s", i.vtype)
+		}
+		kb.Fputv(i.fname, "s", i.value)
+	}
+	tests := []struct {
+		value string
+		eq    []string
+	}{
+		{"+7", []string{"a"}},
+		{"7", []string{"a", "c"}},
+		{"7.0", []string{}},
+		{"1.5", []string{}},
+		{"1.50", []string{"b"}},
+		{"2026-10-18T08:00:00Z", []string{}},
+		{"red", []string{"e"}},
+		{"Red", []string{}},
+	}
+	for _, tt := range tests {
+		got := kb.Ffindeq("s", tt.value)
+		slices.Sort(got)
+		if !slices.Equal(got, tt.eq) {
+			t.Errorf("ffindeq %q: got %q, want %q", tt.value, got, tt.eq)
+		}
+		if n := len(kb.Ffindne("s", tt.value)); n != 5-len(tt.eq) {
+			t.Errorf("ffindne %q: got %d frames, want %d", tt.value, n, 5-len(tt.eq))
+		}
+	}
+}
@@ -0,0 +1,184 @@
+/**********************************************************************
+ *
+ * file name:    values.go
+ * description:  value facets holding a list of values
+ *
+ * A value facet is a list of strings. Fgetv and Fputv only use its
+ * first element; the functions here work on the whole list. Like Fputv
+ * and Fgetv they follow references, and call the ifref demon and then
+ * the ifputv demon (when changing the list) or the ifgetv demon (when
+ * reading it).
+ *
+ *							Functions
+ *
+ * Fappendv					append a value to a value facet
+ * Fcountv					get the number of values in a value facet
+ * Fdeletev					delete a value from a value facet
+ * Fdifferencev				remove a list of values from a value facet
+ * Ffindin					find all frames having a given value among the values of a value facet
+ * Fgetvl					get all values of a value facet
+ * Finsertv					insert a value into a value facet
+ * Fmemberv					determine if a value is in a value facet
+ * Fputvl					put a list of values into a value facet
+ * Funionv					add a list of values to a value facet
+ *
+ **********************************************************************/
+
+package framesets2
+
+// fgetvl - get all values of a value facet
+// requires that fframes[fname][sname,facets] exists
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fgetvl(fname, sname string) []string {
+	value, _ := kb.FgetvlE(fname, sname)
+	return value
+}
+
+// fgetvle - get all values of a value facet, returning an error
+func (kb *KnowledgeBase) FgetvlE(fname, sname string) ([]string, error) {
+	return kb.getvl("fgetvl", fname, sname)
+}
+
+// fputvl - put a list of values into a value facet
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fputvl(fname, sname string, lista []string) bool {
+	return kb.FputvlE(fname, sname, lista) == nil
+}
+
+// fputvle - put a list of values into a value facet, returning an error
+func (kb *KnowledgeBase) FputvlE(fname, sname string, lista []string) error {
+	return kb.putv("fputvl", fname, sname, func([]string) ([]string, error) {
+		return append([]string{}, lista...), nil
+	})
+}
+
+// fcountv - get the number of values in a value facet
+// requires that fframes[fname][sname,facets] exists
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fcountv(fname, sname string) int {
+	n, _ := kb.FcountvE(fname, sname)
+	return n
+}
+
+// fcountve - get the number of values in a value facet, returning an error
+func (kb *KnowledgeBase) FcountvE(fname, sname string) (int, error) {
+	value, err := kb.getvl("fcountv", fname, sname)
+	return len(value), err
+}
+
+// fmemberv - determine if a value is in a value facet
+// requires that fframes[fname][sname,facets] exists
+// calls ifref and ifgetv demons
+func (kb *KnowledgeBase) Fmemberv(fname, sname, args string) bool {
+	value, _ := kb.getvl("fmemberv", fname, sname)
+	return Fmember(value, args)
+}
+
+// fappendv - append a value to a value facet
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fappendv(fname, sname, args string) bool {
+	return kb.FappendvE(fname, sname, args) == nil
+}
+
+// fappendve - append a value to a value facet, returning an error
+func (kb *KnowledgeBase) FappendvE(fname, sname, args string) error {
+	return kb.putv("fappendv", fname, sname, func(value []string) ([]string, error) {
+		return append(append([]string{}, value...), args), nil
+	})
+}
+
+// finsertv - insert a value into a value facet at position i
+// requires that fframes[fname][sname,facets] exists and that
+//          0 <= i <= number of values
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Finsertv(fname, sname string, i int, args string) bool {
+	return kb.FinsertvE(fname, sname, i, args) == nil
+}
+
+// finsertve - insert a value into a value facet, returning an error
+func (kb *KnowledgeBase) FinsertvE(fname, sname string, i int, args string) error {
+	return kb.putv("finsertv", fname, sname, func(value []string) ([]string, error) {
+		if i < 0 || i > len(value) {
+			return nil, ErrIndexRange
+		}
+		listx := append([]string{}, value[:i]...)
+		listx = append(listx, args)
+		return append(listx, value[i:]...), nil
+	})
+}
+
+// fdeletev - delete all occurances of a value from a value facet
+// requires that the value is in fframes[fname][sname,value]
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fdeletev(fname, sname, args string) bool {
+	return kb.FdeletevE(fname, sname, args) == nil
+}
+
+// fdeleteve - delete a value from a value facet, returning an error
+func (kb *KnowledgeBase) FdeletevE(fname, sname, args string) error {
+	return kb.putv("fdeletev", fname, sname, func(value []string) ([]string, error) {
+		if !Fmember(value, args) {
+			return nil, ErrValueNotFound
+		}
+		listx := append([]string{}, value...)
+		Fremove(&listx, args)
+		return listx, nil
+	})
+}
+
+// funionv - add a list of values to a value facet
+// the values are left ordered and without duplicates, as with funion
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Funionv(fname, sname string, lista []string) bool {
+	return kb.FunionvE(fname, sname, lista) == nil
+}
+
+// funionve - add a list of values to a value facet, returning an error
+func (kb *KnowledgeBase) FunionvE(fname, sname string, lista []string) error {
+	return kb.putv("funionv", fname, sname, func(value []string) ([]string, error) {
+		return Funion(append([]string{}, value...), lista), nil
+	})
+}
+
+// fdifferencev - remove a list of values from a value facet
+// the values are left ordered and without duplicates, as with fdifference
+// requires that fframes[fname][sname,facets] exists
+// modifies fframes[fname][sname,value] where fname is the original or
+//          referenced frame
+// calls ifref and ifputv demons
+func (kb *KnowledgeBase) Fdifferencev(fname, sname string, lista []string) bool {
+	return kb.FdifferencevE(fname, sname, lista) == nil
+}
+
+// fdifferenceve - remove a list of values from a value facet, returning an error
+func (kb *KnowledgeBase) FdifferencevE(fname, sname string, lista []string) error {
+	return kb.putv("fdifferencev", fname, sname, func(value []string) ([]string, error) {
+		return Fdifference(append([]string{}, value...), append([]string{}, lista...)), nil
+	})
+}
+
+// ffindin - find all frames having a given value among the values of a value facet
+func (kb *KnowledgeBase) Ffindin(sname string, args string) []string {
+	listx := []string{}
+	for _, i := range kb.Flistf() {
+		if kb.Fexistv(i, sname) {
+			if Fmember(kb.Fgetvl(i, sname), args) {
+				listx = append(listx, i)
+			}
+		}
+	}
+	return listx
+}
@@ -0,0 +1,55 @@
+package framesets2
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestListValues(t *testing.T) {
+	kb := NewKnowledgeBase()
+	kb.Fcreatef("p")
+	kb.Fcreates("p", "c")
+	kb.Fcreatev("p", "c")
+	n := 0
+	kb.Fcreatex("d")
+	kb.Fputx("d", func(string) { n++ })
+	kb.Fcreated("p", "c", "ifputv")
+	kb.Fputd("p", "c", "ifputv", "d")
+	tests := []struct {
+		name string
+		op   func() error
+		want []string
+		err  error
+	}{
+		{"append", func() error { return kb.FappendvE("p", "c", "b") }, []string{"b"}, nil},
+		{"append again", func() error { return kb.FappendvE("p", "c", "a") }, []string{"b", "a"}, nil},
+		{"insert first", func() error { return kb.FinsertvE("p", "c", 0, "c") }, []string{"c", "b", "a"}, nil},
+		{"delete", func() error { return kb.FdeletevE("p", "c", "b") }, []string{"c", "a"}, nil},
+		{"union", func() error { return kb.FunionvE("p", "c", []string{"z", "a"}) }, []string{"a", "c", "z"}, nil},
+		{"difference", func() error { return kb.FdifferencevE("p", "c", []string{"c"}) }, []string{"a", "z"}, nil},
+	}
+	for _, tt := range tests {
+		if err := tt.op(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+		if got := kb.Fgetvl("p", "c"); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if n != 6 {
+		t.Errorf("ifputv called %d times, want 6", n)
+	}
+	if err := kb.FinsertvE("p", "c", 9, "x"); !errors.Is(err, ErrIndexRange) {
+		t.Errorf("insert out of range: got %v, want %v", err, ErrIndexRange)
+	}
+	if got := kb.Fcountv("p", "c"); got != 2 {
+		t.Errorf("fcountv: got %d, want 2", got)
+	}
+	if got := kb.Fgetv("p", "c"); got != "a" {
+		t.Errorf("fgetv: got %q, want first value", got)
+	}
+	if got := kb.Ffindin("c", "z"); !slices.Equal(got, []string{"p"}) {
+		t.Errorf("ffindin: got %q", got)
+	}
+}